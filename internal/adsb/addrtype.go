@@ -0,0 +1,114 @@
+package adsb
+
+import "fmt"
+
+// AddrType describes where an aircraft's 24-bit address came from
+type AddrType int
+
+// Address types, following the DF18 control field
+const (
+	AddrModeS         AddrType = iota // Mode S reply or unknown source
+	AddrADSBICAO                      // DF17 ADS-B with ICAO address
+	AddrADSBICAONT                    // DF18 non-transponder ADS-B with ICAO address
+	AddrADSBOther                     // DF18 ADS-B with anonymous or ground vehicle address
+	AddrTISBICAO                      // TIS-B target with ICAO address
+	AddrTISBTrackfile                 // TIS-B target keyed by a ground station track file number
+	AddrTISBOther                     // TIS-B target with an anonymous address
+	AddrADSRICAO                      // ADS-R rebroadcast with ICAO address
+	AddrADSROther                     // ADS-R rebroadcast with an anonymous address
+)
+
+// TrackFileFlag marks aircraft map keys holding a TIS-B track file number
+// rather than an ICAO address. It lies above the 24 address bits, so track
+// files never collide with real aircraft.
+const TrackFileFlag = 1 << 24
+
+// FormatAddress formats an aircraft map key as hex, prefixing track file
+// numbers with "~" as dump1090 does for non-ICAO addresses
+func FormatAddress(key uint32) string {
+	if key&TrackFileFlag != 0 {
+		return fmt.Sprintf("~%06X", key&^TrackFileFlag)
+	}
+	return fmt.Sprintf("%06X", key)
+}
+
+// String returns a short name for the address type
+func (t AddrType) String() string {
+	switch t {
+	case AddrADSBICAO:
+		return "adsb"
+	case AddrADSBICAONT:
+		return "adsb nt"
+	case AddrADSBOther:
+		return "adsb anon"
+	case AddrTISBICAO:
+		return "tisb"
+	case AddrTISBTrackfile:
+		return "tisb track"
+	case AddrTISBOther:
+		return "tisb anon"
+	case AddrADSRICAO:
+		return "adsr"
+	case AddrADSROther:
+		return "adsr anon"
+	default:
+		return "mode s"
+	}
+}
+
+// Anonymous reports whether the address is not a real ICAO address, so it
+// may be reused for other targets or change over time
+func (t AddrType) Anonymous() bool {
+	switch t {
+	case AddrADSBOther, AddrTISBTrackfile, AddrTISBOther, AddrADSROther:
+		return true
+	}
+	return false
+}
+
+// DecodeAddrType returns the address type of a DF17/18 extended squitter. For
+// DF18 the control field selects the source, and fine TIS-B and ADS-R
+// airborne positions carry an IMF bit flagging a non-ICAO address.
+func DecodeAddrType(data []byte) AddrType {
+	if len(data) < 5 {
+		return AddrModeS
+	}
+
+	df := data[0] >> 3
+	if df == DF17 {
+		return AddrADSBICAO
+	}
+	if df != DF18 {
+		return AddrModeS
+	}
+
+	tc := data[4] >> 3
+	airbornePos := (tc >= 9 && tc <= 18) || (tc >= 20 && tc <= 22)
+	imf := airbornePos && data[4]&0x01 != 0 // ME bit 8
+
+	switch data[0] & 0x07 {
+	case 0:
+		return AddrADSBICAONT
+	case 1:
+		return AddrADSBOther
+	case 2:
+		if imf {
+			return AddrTISBTrackfile
+		}
+		return AddrTISBICAO
+	case 3:
+		// Coarse TIS-B flags a non-ICAO address in ME bit 1
+		if data[4]&0x80 != 0 {
+			return AddrTISBTrackfile
+		}
+		return AddrTISBICAO
+	case 5:
+		return AddrTISBOther
+	case 6:
+		if imf {
+			return AddrADSROther
+		}
+		return AddrADSRICAO
+	}
+	return AddrModeS
+}
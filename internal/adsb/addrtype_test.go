@@ -0,0 +1,78 @@
+package adsb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecodeAddrType(t *testing.T) {
+	// DF18 airborne positions (TC 11) for each control field, with the IMF
+	// bit clear and set
+	tests := []struct {
+		cf         byte
+		imf0, imf1 AddrType
+	}{
+		{0, AddrADSBICAONT, AddrADSBICAONT},
+		{1, AddrADSBOther, AddrADSBOther},
+		{2, AddrTISBICAO, AddrTISBTrackfile},
+		{3, AddrTISBICAO, AddrTISBICAO}, // Coarse TIS-B has no IMF bit
+		{4, AddrModeS, AddrModeS},       // Management message
+		{5, AddrTISBOther, AddrTISBOther},
+		{6, AddrADSRICAO, AddrADSROther},
+		{7, AddrModeS, AddrModeS}, // Reserved
+	}
+
+	for _, tt := range tests {
+		for imf, want := range []AddrType{tt.imf0, tt.imf1} {
+			data := make([]byte, 14)
+			data[0] = DF18<<3 | tt.cf
+			data[4] = 11<<3 | byte(imf)
+			if got := DecodeAddrType(data); got != want {
+				t.Errorf("CF %d IMF %d: got %v, want %v", tt.cf, imf, got, want)
+			}
+		}
+	}
+
+	// DF17 is always an ICAO address, whatever the ME bits say
+	data := make([]byte, 14)
+	data[0] = DF17 << 3
+	data[4] = 11<<3 | 1
+	if got := DecodeAddrType(data); got != AddrADSBICAO {
+		t.Errorf("DF17: got %v, want %v", got, AddrADSBICAO)
+	}
+
+	// IMF only applies to airborne positions, not identification
+	data[0] = DF18<<3 | 2
+	data[4] = 4<<3 | 1
+	if got := DecodeAddrType(data); got != AddrTISBICAO {
+		t.Errorf("CF 2 identification: got %v, want %v", got, AddrTISBICAO)
+	}
+
+	// Coarse TIS-B flags a track file in ME bit 1
+	data[0] = DF18<<3 | 3
+	data[4] = 0x80 | 11<<3
+	if got := DecodeAddrType(data); got != AddrTISBTrackfile {
+		t.Errorf("CF 3 with ME bit 1: got %v, want %v", got, AddrTISBTrackfile)
+	}
+}
+
+func TestRemoveStaleExpiresAnonymousFirst(t *testing.T) {
+	am := NewAircraftMap(0)
+
+	normal := am.GetOrCreate(0x000001)
+	normal.AddrType = AddrADSBICAO
+	anon := am.GetOrCreate(0x000002)
+	anon.AddrType = AddrTISBOther
+
+	// Both last heard from 30 seconds ago
+	normal.Seen = time.Now().Add(-30 * time.Second)
+	anon.Seen = normal.Seen
+
+	am.RemoveStale(60*time.Second, 60*time.Second, 20*time.Second)
+	if am.Get(0x000002) != nil {
+		t.Error("anonymous target kept past its 20s TTL")
+	}
+	if am.Get(0x000001) == nil {
+		t.Error("ICAO target removed before its 60s TTL")
+	}
+}
@@ -0,0 +1,275 @@
+package adsb
+
+import "math"
+
+// Comm-B (DF20/21) replies carry a 56-bit MB field holding one of many BDS
+// registers. The register isn't identified in the reply, so each decoder
+// checks the reserved bits and value ranges and rejects implausible data.
+
+// mbField returns the 56-bit MB field of a Comm-B reply
+func mbField(data []byte) (uint64, bool) {
+	if len(data) < 14 {
+		return 0, false
+	}
+
+	var mb uint64
+	for _, b := range data[4:11] {
+		mb = mb<<8 | uint64(b)
+	}
+	return mb, true
+}
+
+// mbBits extracts MB bits first through last, numbered 1-56 as in ICAO Doc 9871
+func mbBits(mb uint64, first, last int) int {
+	width := last - first + 1
+	return int(mb>>(56-last)) & (1<<width - 1)
+}
+
+// BDS40 holds the selected vertical intention (BDS 4.0)
+type BDS40 struct {
+	MCPAltitudeValid bool
+	MCPAltitude      int // MCP/FCU selected altitude in feet
+	FMSAltitudeValid bool
+	FMSAltitude      int // FMS selected altitude in feet
+	BaroValid        bool
+	Baro             float64 // Barometric pressure setting in hPa
+}
+
+// DecodeBDS40 decodes a Comm-B reply as a selected vertical intention report,
+// returning ok false if the MB field is not plausibly BDS 4.0
+func DecodeBDS40(data []byte) (sel BDS40, ok bool) {
+	mb, ok := mbField(data)
+	if !ok || mb == 0 {
+		return sel, false
+	}
+
+	// Reserved bits must be zero
+	if mbBits(mb, 40, 47) != 0 || mbBits(mb, 52, 53) != 0 {
+		return sel, false
+	}
+
+	sel.MCPAltitudeValid = mbBits(mb, 1, 1) == 1
+	sel.MCPAltitude = mbBits(mb, 2, 13) * 16
+	if !sel.MCPAltitudeValid && sel.MCPAltitude != 0 {
+		return sel, false
+	}
+
+	sel.FMSAltitudeValid = mbBits(mb, 14, 14) == 1
+	sel.FMSAltitude = mbBits(mb, 15, 26) * 16
+	if !sel.FMSAltitudeValid && sel.FMSAltitude != 0 {
+		return sel, false
+	}
+
+	sel.BaroValid = mbBits(mb, 27, 27) == 1
+	baro := mbBits(mb, 28, 39)
+	if !sel.BaroValid && baro != 0 {
+		return sel, false
+	}
+	sel.Baro = 800 + float64(baro)*0.1
+
+	// Mode and target source bits are each gated by a status bit
+	if mbBits(mb, 48, 48) == 0 && mbBits(mb, 49, 51) != 0 {
+		return sel, false
+	}
+	if mbBits(mb, 54, 54) == 0 && mbBits(mb, 55, 56) != 0 {
+		return sel, false
+	}
+
+	return sel, true
+}
+
+// BDS44 holds a meteorological routine air report (BDS 4.4)
+type BDS44 struct {
+	WindValid     bool
+	WindSpeed     int     // Knots
+	WindDirection float64 // Degrees true, direction the wind blows from
+	Temperature   float64 // Static air temperature in °C
+	PressureValid bool
+	Pressure      int // Static pressure in hPa
+	HumidityValid bool
+	Humidity      float64 // Percent
+}
+
+// DecodeBDS44 decodes a Comm-B reply as a meteorological routine air report,
+// returning ok false if the MB field is not plausibly BDS 4.4
+func DecodeBDS44(data []byte) (met BDS44, ok bool) {
+	mb, ok := mbField(data)
+	if !ok || mb == 0 {
+		return met, false
+	}
+
+	// Figure of merit / source, values above 4 are reserved
+	if mbBits(mb, 1, 4) > 4 {
+		return met, false
+	}
+
+	met.WindValid = mbBits(mb, 5, 5) == 1
+	met.WindSpeed = mbBits(mb, 6, 14)
+	met.WindDirection = float64(mbBits(mb, 15, 23)) * 180.0 / 256.0
+	if !met.WindValid && (met.WindSpeed != 0 || met.WindDirection != 0) {
+		return met, false
+	}
+	if met.WindSpeed > 250 {
+		return met, false
+	}
+
+	// Static air temperature, sign bit 24 then 10 bits at 0.25°C
+	temp := mbBits(mb, 25, 34)
+	if mbBits(mb, 24, 24) == 1 {
+		temp -= 1024
+	}
+	met.Temperature = float64(temp) * 0.25
+	if met.Temperature < -80 || met.Temperature > 60 {
+		return met, false
+	}
+
+	met.PressureValid = mbBits(mb, 35, 35) == 1
+	met.Pressure = mbBits(mb, 36, 46)
+	if !met.PressureValid && met.Pressure != 0 {
+		return met, false
+	}
+
+	// Turbulence is not decoded but its reserved bits must be clear when invalid
+	if mbBits(mb, 47, 47) == 0 && mbBits(mb, 48, 49) != 0 {
+		return met, false
+	}
+
+	met.HumidityValid = mbBits(mb, 50, 50) == 1
+	met.Humidity = float64(mbBits(mb, 51, 56)) * 100.0 / 64.0
+	if !met.HumidityValid && met.Humidity != 0 {
+		return met, false
+	}
+
+	return met, true
+}
+
+// mbSigned extracts a sign bit followed by a magnitude field, the two's
+// complement form BDS 5.0/6.0 use for angles and rates
+func mbSigned(mb uint64, signBit, last int) int {
+	v := mbBits(mb, signBit+1, last)
+	if mbBits(mb, signBit, signBit) == 1 {
+		v -= 1 << (last - signBit)
+	}
+	return v
+}
+
+// BDS50 holds a track and turn report (BDS 5.0)
+type BDS50 struct {
+	RollValid        bool
+	Roll             float64 // Degrees, right wing down positive
+	TrackValid       bool
+	Track            float64 // True track angle in degrees
+	GroundSpeedValid bool
+	GroundSpeed      int // Knots
+	TrackRateValid   bool
+	TrackRate        float64 // Degrees per second, turning right positive
+	TASValid         bool
+	TAS              int // True airspeed in knots
+}
+
+// DecodeBDS50 decodes a Comm-B reply as a track and turn report, returning
+// ok false if the MB field is not plausibly BDS 5.0
+func DecodeBDS50(data []byte) (tt BDS50, ok bool) {
+	mb, ok := mbField(data)
+	if !ok || mb == 0 {
+		return tt, false
+	}
+
+	// Each field must be clear when its status bit is
+	fields := [][3]int{{1, 2, 11}, {12, 13, 23}, {24, 25, 34}, {35, 36, 45}, {46, 47, 56}}
+	for _, f := range fields {
+		if mbBits(mb, f[0], f[0]) == 0 && mbBits(mb, f[1], f[2]) != 0 {
+			return tt, false
+		}
+	}
+
+	tt.RollValid = mbBits(mb, 1, 1) == 1
+	tt.Roll = float64(mbSigned(mb, 2, 11)) * 45.0 / 256.0
+	if math.Abs(tt.Roll) > 50 {
+		return tt, false
+	}
+
+	tt.TrackValid = mbBits(mb, 12, 12) == 1
+	tt.Track = float64(mbSigned(mb, 13, 23)) * 90.0 / 512.0
+	if tt.Track < 0 {
+		tt.Track += 360
+	}
+
+	tt.GroundSpeedValid = mbBits(mb, 24, 24) == 1
+	tt.GroundSpeed = mbBits(mb, 25, 34) * 2
+	tt.TrackRateValid = mbBits(mb, 35, 35) == 1
+	tt.TrackRate = float64(mbSigned(mb, 36, 45)) * 8.0 / 256.0
+	tt.TASValid = mbBits(mb, 46, 46) == 1
+	tt.TAS = mbBits(mb, 47, 56) * 2
+
+	if tt.GroundSpeed > 600 || tt.TAS > 500 {
+		return tt, false
+	}
+	if tt.GroundSpeedValid && tt.TASValid && abs(tt.GroundSpeed-tt.TAS) > 200 {
+		return tt, false
+	}
+
+	return tt, true
+}
+
+// BDS60 holds a heading and speed report (BDS 6.0)
+type BDS60 struct {
+	HeadingValid      bool
+	Heading           float64 // Magnetic heading in degrees
+	IASValid          bool
+	IAS               int // Indicated airspeed in knots
+	MachValid         bool
+	Mach              float64
+	BaroVertRateValid bool
+	BaroVertRate      int // Barometric altitude rate in ft/min
+	InertialRateValid bool
+	InertialRate      int // Inertial vertical velocity in ft/min
+}
+
+// DecodeBDS60 decodes a Comm-B reply as a heading and speed report,
+// returning ok false if the MB field is not plausibly BDS 6.0
+func DecodeBDS60(data []byte) (hs BDS60, ok bool) {
+	mb, ok := mbField(data)
+	if !ok || mb == 0 {
+		return hs, false
+	}
+
+	fields := [][3]int{{1, 2, 12}, {13, 14, 23}, {24, 25, 34}, {35, 36, 45}, {46, 47, 56}}
+	for _, f := range fields {
+		if mbBits(mb, f[0], f[0]) == 0 && mbBits(mb, f[1], f[2]) != 0 {
+			return hs, false
+		}
+	}
+
+	hs.HeadingValid = mbBits(mb, 1, 1) == 1
+	hs.Heading = float64(mbSigned(mb, 2, 12)) * 90.0 / 512.0
+	if hs.Heading < 0 {
+		hs.Heading += 360
+	}
+
+	hs.IASValid = mbBits(mb, 13, 13) == 1
+	hs.IAS = mbBits(mb, 14, 23)
+	hs.MachValid = mbBits(mb, 24, 24) == 1
+	hs.Mach = float64(mbBits(mb, 25, 34)) * 2.048 / 512.0
+	hs.BaroVertRateValid = mbBits(mb, 35, 35) == 1
+	hs.BaroVertRate = mbSigned(mb, 36, 45) * 32
+	hs.InertialRateValid = mbBits(mb, 46, 46) == 1
+	hs.InertialRate = mbSigned(mb, 47, 56) * 32
+
+	if hs.IAS > 500 || hs.Mach > 1 {
+		return hs, false
+	}
+	if abs(hs.BaroVertRate) > 6000 || abs(hs.InertialRate) > 6000 {
+		return hs, false
+	}
+
+	return hs, true
+}
+
+// abs returns the absolute value of an int
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
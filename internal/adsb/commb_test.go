@@ -0,0 +1,185 @@
+package adsb
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("bad hex %q: %v", s, err)
+	}
+	return data
+}
+
+func TestModeSChecksum(t *testing.T) {
+	// DF17 identification from KLM1023, parity matches the checksum
+	data := mustDecodeHex(t, "8D4840D6202CC371C32CE0576098")
+	if got, want := ModeSChecksum(data), ParityField(data); got != want {
+		t.Errorf("checksum %06X, want %06X", got, want)
+	}
+
+	// A single flipped bit breaks it
+	data[5] ^= 0x01
+	if ModeSChecksum(data) == ParityField(data) {
+		t.Error("expected checksum mismatch after corrupting the message")
+	}
+}
+
+func TestAddressFromParity(t *testing.T) {
+	// Overlaying an address on a valid DF17 parity field recovers it
+	data := mustDecodeHex(t, "8D4840D6202CC371C32CE0576098")
+	parity := ModeSChecksum(data) ^ 0xABCDEF
+	data[11], data[12], data[13] = byte(parity>>16), byte(parity>>8), byte(parity)
+
+	if got := AddressFromParity(data); got != 0xABCDEF {
+		t.Errorf("address %06X, want ABCDEF", got)
+	}
+}
+
+func TestDecodeBDS44(t *testing.T) {
+	met, ok := DecodeBDS44(mustDecodeHex(t, "A0001692185BD5CF400000DFC696"))
+	if !ok {
+		t.Fatal("expected BDS 4.4 to decode")
+	}
+
+	if !met.WindValid || met.WindSpeed != 22 {
+		t.Errorf("wind speed %d (valid %v), want 22", met.WindSpeed, met.WindValid)
+	}
+	if math.Abs(met.WindDirection-344.5) > 0.1 {
+		t.Errorf("wind direction %v, want 344.5", met.WindDirection)
+	}
+	if met.Temperature != -48.75 {
+		t.Errorf("temperature %v, want -48.75", met.Temperature)
+	}
+	if met.PressureValid || met.HumidityValid {
+		t.Errorf("expected pressure and humidity to be invalid, got %+v", met)
+	}
+}
+
+func TestDecodeBDS44RejectsOtherRegisters(t *testing.T) {
+	tests := []string{
+		"A000000000000000000000000000", // Empty MB field
+		"A0000000F0000000000000000000", // Reserved figure of merit
+		"A00000000FFF0000000000000000", // Wind speed without status bit
+		"A000000000000000000000",       // Too short
+	}
+
+	for _, s := range tests {
+		if met, ok := DecodeBDS44(mustDecodeHex(t, s)); ok {
+			t.Errorf("%s: expected rejection, got %+v", s, met)
+		}
+	}
+}
+
+func TestDecodeBDS40(t *testing.T) {
+	sel, ok := DecodeBDS40(mustDecodeHex(t, "A000029C85E42F313000007047D3"))
+	if !ok {
+		t.Fatal("expected BDS 4.0 to decode")
+	}
+
+	if !sel.MCPAltitudeValid || sel.MCPAltitude != 3008 {
+		t.Errorf("MCP altitude %d (valid %v), want 3008", sel.MCPAltitude, sel.MCPAltitudeValid)
+	}
+	if !sel.FMSAltitudeValid || sel.FMSAltitude != 3008 {
+		t.Errorf("FMS altitude %d (valid %v), want 3008", sel.FMSAltitude, sel.FMSAltitudeValid)
+	}
+	if !sel.BaroValid || math.Abs(sel.Baro-1020) > 0.01 {
+		t.Errorf("baro setting %v (valid %v), want 1020", sel.Baro, sel.BaroValid)
+	}
+}
+
+func TestCommBRegistersAreDistinguished(t *testing.T) {
+	bds40 := mustDecodeHex(t, "A000029C85E42F313000007047D3")
+	bds44 := mustDecodeHex(t, "A0001692185BD5CF400000DFC696")
+
+	if _, ok := DecodeBDS44(bds40); ok {
+		t.Error("BDS 4.0 payload accepted as BDS 4.4")
+	}
+	if _, ok := DecodeBDS40(bds44); ok {
+		t.Error("BDS 4.4 payload accepted as BDS 4.0")
+	}
+
+	for _, s := range []string{"A000139381951536E024D4CCF6B5", "A00004128F39F91A7E27C46ADC21"} {
+		if _, ok := DecodeBDS40(mustDecodeHex(t, s)); ok {
+			t.Errorf("%s: BDS 5.0/6.0 payload accepted as BDS 4.0", s)
+		}
+		if _, ok := DecodeBDS44(mustDecodeHex(t, s)); ok {
+			t.Errorf("%s: BDS 5.0/6.0 payload accepted as BDS 4.4", s)
+		}
+	}
+}
+
+func TestDecodeBDS50(t *testing.T) {
+	tt, ok := DecodeBDS50(mustDecodeHex(t, "A000139381951536E024D4CCF6B5"))
+	if !ok {
+		t.Fatal("expected BDS 5.0 to decode")
+	}
+
+	if !tt.RollValid || math.Abs(tt.Roll-2.1) > 0.05 {
+		t.Errorf("roll %v (valid %v), want 2.1", tt.Roll, tt.RollValid)
+	}
+	if !tt.TrackValid || math.Abs(tt.Track-114.258) > 0.01 {
+		t.Errorf("track %v (valid %v), want 114.258", tt.Track, tt.TrackValid)
+	}
+	if !tt.GroundSpeedValid || tt.GroundSpeed != 438 {
+		t.Errorf("ground speed %d (valid %v), want 438", tt.GroundSpeed, tt.GroundSpeedValid)
+	}
+	if !tt.TrackRateValid || tt.TrackRate != 0.125 {
+		t.Errorf("track rate %v (valid %v), want 0.125", tt.TrackRate, tt.TrackRateValid)
+	}
+	if !tt.TASValid || tt.TAS != 424 {
+		t.Errorf("TAS %d (valid %v), want 424", tt.TAS, tt.TASValid)
+	}
+}
+
+func TestDecodeBDS60(t *testing.T) {
+	hs, ok := DecodeBDS60(mustDecodeHex(t, "A00004128F39F91A7E27C46ADC21"))
+	if !ok {
+		t.Fatal("expected BDS 6.0 to decode")
+	}
+
+	if !hs.HeadingValid || math.Abs(hs.Heading-42.715) > 0.01 {
+		t.Errorf("heading %v (valid %v), want 42.715", hs.Heading, hs.HeadingValid)
+	}
+	if !hs.IASValid || hs.IAS != 252 {
+		t.Errorf("IAS %d (valid %v), want 252", hs.IAS, hs.IASValid)
+	}
+	if !hs.MachValid || math.Abs(hs.Mach-0.42) > 0.001 {
+		t.Errorf("Mach %v (valid %v), want 0.42", hs.Mach, hs.MachValid)
+	}
+	if !hs.BaroVertRateValid || hs.BaroVertRate != -1920 {
+		t.Errorf("baro rate %d (valid %v), want -1920", hs.BaroVertRate, hs.BaroVertRateValid)
+	}
+	if !hs.InertialRateValid || hs.InertialRate != -1920 {
+		t.Errorf("inertial rate %d (valid %v), want -1920", hs.InertialRate, hs.InertialRateValid)
+	}
+}
+
+func TestDecodeBDS50And60RejectOtherRegisters(t *testing.T) {
+	tests := []string{
+		"A000000000000000000000000000", // Empty MB field
+		"A000029C85E42F313000007047D3", // BDS 4.0
+		"A0001692185BD5CF400000DFC696", // BDS 4.4
+	}
+
+	for _, s := range tests {
+		if tt, ok := DecodeBDS50(mustDecodeHex(t, s)); ok {
+			t.Errorf("%s: expected BDS 5.0 rejection, got %+v", s, tt)
+		}
+		if hs, ok := DecodeBDS60(mustDecodeHex(t, s)); ok {
+			t.Errorf("%s: expected BDS 6.0 rejection, got %+v", s, hs)
+		}
+	}
+
+	if _, ok := DecodeBDS60(mustDecodeHex(t, "A000139381951536E024D4CCF6B5")); ok {
+		t.Error("BDS 5.0 payload accepted as BDS 6.0")
+	}
+	if _, ok := DecodeBDS50(mustDecodeHex(t, "A00004128F39F91A7E27C46ADC21")); ok {
+		t.Error("BDS 6.0 payload accepted as BDS 5.0")
+	}
+}
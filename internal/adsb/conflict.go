@@ -0,0 +1,172 @@
+package adsb
+
+import (
+	"math"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/map_system"
+)
+
+// Conflict is a pair of aircraft predicted to lose separation
+type Conflict struct {
+	A, B       uint32        // ICAO addresses of the pair
+	TimeToCPA  time.Duration // Time until closest point of approach
+	Horizontal float64       // Horizontal separation at CPA in NM
+	Vertical   int           // Vertical separation at CPA in feet
+}
+
+// conflictTrack is an aircraft's position and velocity on a local flat plane
+type conflictTrack struct {
+	icao   uint32
+	x, y   float64 // NM east and north of the reference point
+	vx, vy float64 // NM per second
+	alt    float64 // Feet
+	vz     float64 // Feet per second
+	cellX  int
+	cellY  int
+}
+
+// FindConflicts predicts pairs of airborne aircraft that will come within
+// horizNM and vertFt of each other within the given time window, assuming
+// constant velocity. Aircraft are bucketed into a grid sized so that only
+// neighbouring cells can conflict, avoiding an all-pairs comparison.
+func FindConflicts(aircraft map[uint32]*Aircraft, horizNM float64, vertFt int, window time.Duration) []Conflict {
+	var tracks []conflictTrack
+	var refLat, refLon float64
+	maxSpeed := 0.0
+
+	for icao, a := range aircraft {
+		if !a.HasPosition || !a.HasAltitude || a.OnGround {
+			continue
+		}
+		if len(tracks) == 0 {
+			refLat, refLon = a.Lat, a.Lon
+		}
+
+		heading := float64(a.Heading) * math.Pi / 180.0
+		speed := float64(a.Speed) / 3600.0
+		tracks = append(tracks, conflictTrack{
+			icao: icao,
+			x:    map_system.NormalizeLon(a.Lon-refLon) * 60.0 * math.Cos(refLat*math.Pi/180.0),
+			y:    (a.Lat - refLat) * 60.0,
+			vx:   speed * math.Sin(heading),
+			vy:   speed * math.Cos(heading),
+			alt:  float64(a.Altitude),
+			vz:   float64(a.VertRate) / 60.0,
+		})
+		maxSpeed = math.Max(maxSpeed, speed)
+	}
+	if len(tracks) < 2 {
+		return nil
+	}
+
+	// Two aircraft further apart than the separation plus both their travel
+	// can't conflict, so that distance bounds the cell size
+	seconds := window.Seconds()
+	cellSize := math.Max(horizNM+2*maxSpeed*seconds, 1)
+
+	grid := make(map[[2]int][]int)
+	for i := range tracks {
+		t := &tracks[i]
+		t.cellX = int(math.Floor(t.x / cellSize))
+		t.cellY = int(math.Floor(t.y / cellSize))
+		key := [2]int{t.cellX, t.cellY}
+		grid[key] = append(grid[key], i)
+	}
+
+	var conflicts []Conflict
+	for i := range tracks {
+		t1 := &tracks[i]
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for _, j := range grid[[2]int{t1.cellX + dx, t1.cellY + dy}] {
+					if j <= i {
+						continue // Each pair once
+					}
+					if c, ok := predictConflict(t1, &tracks[j], seconds, horizNM, vertFt); ok {
+						conflicts = append(conflicts, c)
+					}
+				}
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// predictConflict reports whether two tracks are predicted to be within
+// horizNM and vertFt of each other at the same moment in the next window
+// seconds. Horizontal and vertical loss of separation each hold over a time
+// interval, so a climb or descent through the other aircraft's level before
+// or after the horizontal closest approach is caught when the intervals
+// overlap. The conflict is reported at the moment of least separation
+// within that overlap, measured against the limits.
+func predictConflict(t1, t2 *conflictTrack, window, horizNM float64, vertFt int) (Conflict, bool) {
+	dx, dy := t2.x-t1.x, t2.y-t1.y
+	dvx, dvy := t2.vx-t1.vx, t2.vy-t1.vy
+	dz, dvz := t2.alt-t1.alt, t2.vz-t1.vz
+
+	// |d + dv*t| < horizNM, a quadratic in t
+	a := dvx*dvx + dvy*dvy
+	b := dx*dvx + dy*dvy
+	hLo, hHi, ok := quadraticBelow(a, b, dx*dx+dy*dy-horizNM*horizNM)
+	if !ok {
+		return Conflict{}, false
+	}
+
+	// |dz + dvz*t| < vertFt, linear in t
+	vLo, vHi := math.Inf(-1), math.Inf(1)
+	v := float64(vertFt)
+	if dvz != 0 {
+		vLo, vHi = (-v-dz)/dvz, (v-dz)/dvz
+		if vLo > vHi {
+			vLo, vHi = vHi, vLo
+		}
+	} else if math.Abs(dz) >= v {
+		return Conflict{}, false
+	}
+
+	lo := math.Max(0, math.Max(hLo, vLo))
+	hi := math.Min(window, math.Min(hHi, vHi))
+	if lo >= hi {
+		return Conflict{}, false
+	}
+
+	// The larger of the two separations as a fraction of its limit is
+	// convex in t, so a ternary search finds its minimum
+	separation := func(t float64) float64 {
+		h := math.Hypot(dx+dvx*t, dy+dvy*t) / horizNM
+		return math.Max(h, math.Abs(dz+dvz*t)/v)
+	}
+	for i := 0; i < 60; i++ {
+		m1, m2 := lo+(hi-lo)/3, hi-(hi-lo)/3
+		if separation(m1) <= separation(m2) {
+			hi = m2
+		} else {
+			lo = m1
+		}
+	}
+	t := (lo + hi) / 2
+
+	return Conflict{
+		A:          t1.icao,
+		B:          t2.icao,
+		TimeToCPA:  time.Duration(t * float64(time.Second)),
+		Horizontal: math.Hypot(dx+dvx*t, dy+dvy*t),
+		Vertical:   int(math.Abs(dz + dvz*t)),
+	}, true
+}
+
+// quadraticBelow returns the open interval of t where a*t^2 + 2*b*t + c < 0,
+// for a >= 0. ok is false when there is none; with a = 0 it is unbounded.
+func quadraticBelow(a, b, c float64) (lo, hi float64, ok bool) {
+	if a == 0 {
+		return math.Inf(-1), math.Inf(1), c < 0
+	}
+	disc := b*b - a*c
+	if disc <= 0 {
+		return 0, 0, false
+	}
+	root := math.Sqrt(disc)
+	return (-b - root) / a, (-b + root) / a, true
+}
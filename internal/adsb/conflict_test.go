@@ -0,0 +1,100 @@
+package adsb
+
+import (
+	"testing"
+	"time"
+)
+
+// conflictAircraft returns an airborne aircraft at a position, altitude and
+// velocity for conflict tests
+func conflictAircraft(lat, lon float64, alt, speed, heading, vertRate int) *Aircraft {
+	return &Aircraft{
+		Lat: lat, Lon: lon, HasPosition: true,
+		Altitude: alt, HasAltitude: true,
+		Speed: speed, Heading: heading, VertRate: vertRate,
+	}
+}
+
+func TestFindConflicts(t *testing.T) {
+	const (
+		horizNM = 3.0
+		vertFt  = 1000
+		window  = 120 * time.Second
+	)
+
+	tests := []struct {
+		name     string
+		a, b     *Aircraft
+		ground   bool
+		conflict bool
+		cpa      time.Duration // Expected time to closest approach, when in conflict
+	}{
+		{
+			// 10 NM apart closing at 600 kts, CPA in 60 s
+			name:     "head-on",
+			a:        conflictAircraft(0, 0, 10000, 300, 90, 0),
+			b:        conflictAircraft(0, 10.0/60, 10000, 300, 270, 0),
+			conflict: true,
+			cpa:      60 * time.Second,
+		},
+		{
+			name: "head-on but vertically separated",
+			a:    conflictAircraft(0, 0, 10000, 300, 90, 0),
+			b:    conflictAircraft(0, 10.0/60, 12000, 300, 270, 0),
+		},
+		{
+			name: "diverging",
+			a:    conflictAircraft(0, 0, 10000, 300, 270, 0),
+			b:    conflictAircraft(0, 5.0/60, 10000, 300, 90, 0),
+		},
+		{
+			// Side by side 1 NM apart, the lower one climbing through the
+			// other's level 20 s from now. The horizontal CPA is now, when
+			// they are still 1000 ft apart.
+			name:     "climb-through",
+			a:        conflictAircraft(0, 0, 10000, 250, 0, 0),
+			b:        conflictAircraft(0, 1.0/60, 9000, 250, 0, 3000),
+			conflict: true,
+		},
+		{
+			// Same climb, but it only reaches the level after the window
+			name: "climb-through after the window",
+			a:    conflictAircraft(0, 0, 10000, 250, 0, 0),
+			b:    conflictAircraft(0, 1.0/60, 4000, 250, 0, 1500),
+		},
+		{
+			// 6 NM apart across the anti-meridian, closing head-on
+			name:     "anti-meridian",
+			a:        conflictAircraft(0, 179.95, 35000, 450, 90, 0),
+			b:        conflictAircraft(0, -179.95, 35000, 450, 270, 0),
+			conflict: true,
+			cpa:      24 * time.Second,
+		},
+		{
+			name:   "on the ground",
+			a:      conflictAircraft(0, 0, 0, 20, 90, 0),
+			b:      conflictAircraft(0, 0.5/60, 0, 20, 270, 0),
+			ground: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt.a.OnGround, tt.b.OnGround = tt.ground, tt.ground
+		conflicts := FindConflicts(map[uint32]*Aircraft{1: tt.a, 2: tt.b}, horizNM, vertFt, window)
+		if got := len(conflicts) == 1; got != tt.conflict {
+			t.Errorf("%s: %d conflicts, want conflict %v", tt.name, len(conflicts), tt.conflict)
+			continue
+		}
+		if !tt.conflict {
+			continue
+		}
+
+		c := conflicts[0]
+		if c.Horizontal >= horizNM || c.Vertical >= vertFt {
+			t.Errorf("%s: reported separation %.2f NM %d ft is not a conflict", tt.name, c.Horizontal, c.Vertical)
+		}
+		if tt.cpa > 0 && (c.TimeToCPA-tt.cpa).Abs() > time.Second {
+			t.Errorf("%s: time to CPA %v, want %v", tt.name, c.TimeToCPA, tt.cpa)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package adsb
+
+// Mode S CRC generator polynomial, without the leading x^24 term
+const crcPolynomial = 0xFFF409
+
+// ModeSChecksum computes the 24-bit Mode S parity over a message, excluding
+// the final three parity bytes. For DF11/17/18 a valid message's checksum
+// equals its parity field.
+func ModeSChecksum(data []byte) uint32 {
+	if len(data) < 4 {
+		return 0
+	}
+
+	var crc uint32
+	for _, b := range data[:len(data)-3] {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			if crc&0x800000 != 0 {
+				crc = (crc << 1) ^ crcPolynomial
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc & 0xFFFFFF
+}
+
+// ParityField returns the trailing 24-bit parity (AP/PI) field of a message
+func ParityField(data []byte) uint32 {
+	n := len(data)
+	if n < 4 {
+		return 0
+	}
+	return uint32(data[n-3])<<16 | uint32(data[n-2])<<8 | uint32(data[n-1])
+}
+
+// AddressFromParity recovers the ICAO address of replies that overlay it on
+// the parity field (DF0/4/5/16/20/21). The result is only trustworthy when it
+// matches an aircraft already known from ADS-B.
+func AddressFromParity(data []byte) uint32 {
+	return ModeSChecksum(data) ^ ParityField(data)
+}
@@ -1,6 +1,7 @@
 package adsb
 
 import (
+	"bytes"
 	"math"
 	"sync"
 	"time"
@@ -19,6 +20,18 @@ const (
 	DF21 = 21 // Comm-B, identity reply
 )
 
+// FrameLength returns the length in bytes of a Mode S reply with the given
+// downlink format, or 0 for formats that aren't defined
+func FrameLength(df int) int {
+	switch {
+	case df == DF0, df == DF4, df == DF5, df == DF11:
+		return 7 // 56-bit short frame
+	case df == DF16, df == DF17, df == DF18, df == 19, df == DF20, df == DF21, df >= 24:
+		return 14 // 112-bit long frame
+	}
+	return 0
+}
+
 // ADS-B Type Codes (Extended Squitter Format Type Codes)
 const (
 	TC_IDENT         = 4  // Aircraft identification
@@ -26,43 +39,119 @@ const (
 	TC_AIRBORNE_POS  = 9  // Airborne position (9-18)
 	TC_AIRBORNE_VEL  = 19 // Airborne velocity
 	TC_AIRBORNE_POS2 = 20 // Airborne position (20-22)
+	TC_STATUS        = 28 // Aircraft status (emergency/priority, TCAS RA)
+	TC_OPSTATUS      = 31 // Aircraft operational status
 )
 
-// TrailLength defines how many historical positions to keep
-const TrailLength = 120
+// Message types seen from an aircraft, combined into Aircraft.SeenTypes
+const (
+	SeenPosition = 1 << iota // Airborne position
+	SeenVelocity             // Airborne velocity
+	SeenIdent                // Identification and category
+	SeenSurface              // Surface position
+)
 
 // Aircraft represents a tracked aircraft with all its information
 type Aircraft struct {
-	ICAO         uint32    // 24-bit ICAO address
-	Flight       string    // Flight number/callsign
-	Altitude     int       // Altitude in feet
-	Speed        int       // Ground speed in knots
-	Heading      int       // Track in degrees
-	VertRate     int       // Vertical rate in ft/min
-	Lat          float64   // Latitude
-	Lon          float64   // Longitude
-	Seen         time.Time // Last time any message was received
-	SeenLatLon   time.Time // Last time position was received
-	X            int       // Screen X coordinate
-	Y            int       // Screen Y coordinate
-	OnGround     bool      // Whether aircraft is on ground
-	SignalLevel  [8]byte   // Signal strength history
-	EvenCPRLat   int       // Even CPR latitude
-	EvenCPRLon   int       // Even CPR longitude
-	OddCPRLat    int       // Odd CPR latitude
-	OddCPRLon    int       // Odd CPR longitude
-	EvenCPRTime  int64     // Time of last even CPR message
-	OddCPRTime   int64     // Time of last odd CPR message
-	Trail        []Position
-	LabelX       float64 // Label X position
-	LabelY       float64 // Label Y position
-	LabelW       float64 // Label width
-	LabelH       float64 // Label height
-	LabelDX      float64 // Label X velocity
-	LabelDY      float64 // Label Y velocity
-	LabelOpacity float64 // Label opacity
-	LabelLevel   float64 // Label detail level (0-2)
-	Messages     int     // Number of messages received
+	ICAO          uint32    // 24-bit ICAO address
+	Flight        string    // Flight number/callsign
+	Altitude      int       // Altitude in feet
+	Speed         int       // Ground speed in knots
+	Heading       int       // Symbol direction in degrees: true track, else corrected magnetic heading
+	VertRate      int       // Vertical rate in ft/min
+	Lat           float64   // Latitude
+	Lon           float64   // Longitude
+	HasPosition   bool      // Whether Lat/Lon hold a decoded position
+	HasAltitude   bool      // Whether Altitude holds a decoded altitude
+	Seen          time.Time // Last time any message was received
+	SeenLatLon    time.Time // Last time position was received
+	X             int       // Screen X coordinate
+	Y             int       // Screen Y coordinate
+	OnGround      bool      // Whether aircraft is on ground
+	SignalLevel   [8]byte   // Signal strength history
+	EvenCPRLat    int       // Even CPR latitude
+	EvenCPRLon    int       // Even CPR longitude
+	OddCPRLat     int       // Odd CPR latitude
+	OddCPRLon     int       // Odd CPR longitude
+	EvenCPRTime   int64     // Time of last even CPR message
+	OddCPRTime    int64     // Time of last odd CPR message
+	CPRStatus     string    // Outcome of the last global CPR decode attempt, for debugging
+	CPRStatusAt   time.Time // When CPRStatus was set
+	Trail         []Position
+	LabelX        float64   // Label X position
+	LabelY        float64   // Label Y position
+	LabelW        float64   // Label width
+	LabelH        float64   // Label height
+	LabelDX       float64   // Label X velocity
+	LabelDY       float64   // Label Y velocity
+	LabelOpacity  float64   // Label opacity
+	LabelLevel    float64   // Label detail level (0-2)
+	Messages      int       // Number of messages received
+	MsgRate       float64   // Recent messages per second, see UpdateMessageRate
+	Accuracy      float64   // Horizontal position accuracy radius in meters (0 if unknown)
+	NACp          int       // Navigation accuracy category from operational status (0 if unknown)
+	SeenTypes     int       // Bitmask of Seen* message types received
+	AddrType      AddrType  // Source of the address, see DecodeAddrType
+	NearAirport   string    // Code of the airport being approached or departed, empty when none
+	Emergency     string    // Emergency/priority status from TC 28, empty when none
+	Squawk        int       // Mode A code with the octal digits written in decimal, e.g. 7700
+	HasSquawk     bool      // Whether a squawk has been reported
+	Registration  string    // From the aircraft database, empty when unknown
+	TypeCode      string    // ICAO type designator from the aircraft database, empty when unknown
+	Operator      string    // From the aircraft database, empty when unknown
+	Alert         bool      // Surveillance status reports a permanent or temporary alert
+	SPI           bool      // Surveillance status reports the ident (SPI) pulse
+	SeenSPI       time.Time // Last time the ident pulse was reported
+	SingleAntenna bool      // Transmitting from a single antenna
+	Stacked       bool      // Drawn under another aircraft's symbol at the same screen spot
+	StackCount    int       // Aircraft drawn under this one's symbol
+
+	// Direction of travel from airborne velocity messages
+	Track         int       // True track over the ground in degrees
+	HasTrack      bool      // Whether a track has been reported
+	SeenTrack     time.Time // Last time a track was received
+	MagHeading    int       // Magnetic heading in degrees
+	HasMagHeading bool      // Whether a magnetic heading has been reported
+
+	// Ground track from the latest surface position, invalid while the
+	// aircraft is stationary or not reporting one
+	GroundTrack    int
+	HasGroundTrack bool
+
+	// Selected vertical intention from Comm-B BDS 4.0
+	SelectedAltitude    int     // MCP/FCU selected altitude in feet
+	HasSelectedAltitude bool    // Whether a selected altitude has been reported
+	BaroSetting         float64 // Barometric pressure setting in hPa
+	HasBaroSetting      bool    // Whether a pressure setting has been reported
+
+	// Meteorological data from Comm-B BDS 4.4
+	WindSpeed      int     // Wind speed in knots
+	WindDirection  float64 // Direction the wind blows from in degrees
+	HasWind        bool    // Whether wind has been reported
+	Temperature    float64 // Static air temperature in °C
+	HasTemperature bool    // Whether temperature has been reported
+
+	// Track and turn from Comm-B BDS 5.0
+	Roll         float64 // Roll angle in degrees, right wing down positive
+	HasRoll      bool    // Whether a roll angle has been reported
+	TrackRate    float64 // Rate of turn in degrees per second, right positive
+	HasTrackRate bool    // Whether a track rate has been reported
+	TAS          int     // True airspeed in knots
+	HasTAS       bool    // Whether a true airspeed has been reported
+
+	// Heading and speed from Comm-B BDS 6.0
+	IAS                 int     // Indicated airspeed in knots
+	HasIAS              bool    // Whether an indicated airspeed has been reported
+	Mach                float64 // Mach number
+	HasMach             bool    // Whether a Mach number has been reported
+	BaroVertRate        int     // Barometric altitude rate in ft/min
+	HasBaroVertRate     bool    // Whether a barometric altitude rate has been reported
+	InertialVertRate    int     // Inertial vertical velocity in ft/min
+	HasInertialVertRate bool    // Whether an inertial vertical velocity has been reported
+
+	maxTrail     int       // Maximum number of trail positions to keep
+	rateMessages int       // Messages at the last rate update
+	rateAt       time.Time // Time of the last rate update, zero before the first
 	mutex        sync.Mutex
 }
 
@@ -103,16 +192,128 @@ type Message struct {
 	Valid       bool      // Message passed CRC check
 }
 
+// cprPairWindow is the longest gap in milliseconds between an even and an
+// odd CPR frame for them to be decoded as a pair
+const cprPairWindow = 10000
+
+// StoreCPR records a CPR frame received at nowMs (Unix milliseconds) in the
+// slot for its parity, replacing the previous frame of that parity, and
+// reports whether it can be globally decoded with the other slot. Frames are
+// only ever paired with the most recent frame of the opposite parity, and
+// only when that arrived within cprPairWindow.
+func (a *Aircraft) StoreCPR(lat, lon int, odd bool, nowMs int64) bool {
+	var other int64
+	if odd {
+		a.OddCPRLat, a.OddCPRLon, a.OddCPRTime = lat, lon, nowMs
+		other = a.EvenCPRTime
+	} else {
+		a.EvenCPRLat, a.EvenCPRLon, a.EvenCPRTime = lat, lon, nowMs
+		other = a.OddCPRTime
+	}
+
+	if other == 0 {
+		return false
+	}
+	if nowMs-other > cprPairWindow {
+		a.CPRStatus, a.CPRStatusAt = "stale pair", time.UnixMilli(nowMs)
+		return false
+	}
+	return true
+}
+
+// msgRateWindow is the time constant the message rate is smoothed over
+const msgRateWindow = 5 * time.Second
+
+// UpdateMessageRate folds the messages received since the last update into
+// MsgRate, an exponential average over about msgRateWindow. The first call
+// only takes a baseline.
+func (a *Aircraft) UpdateMessageRate(now time.Time) {
+	if a.rateAt.IsZero() {
+		a.rateMessages, a.rateAt = a.Messages, now
+		return
+	}
+	dt := now.Sub(a.rateAt).Seconds()
+	if dt <= 0 {
+		return
+	}
+
+	rate := float64(a.Messages-a.rateMessages) / dt
+	a.MsgRate += (1 - math.Exp(-dt/msgRateWindow.Seconds())) * (rate - a.MsgRate)
+	a.rateMessages, a.rateAt = a.Messages, now
+}
+
+// AddTrailPoint appends a position to the trail, dropping the oldest
+// positions once the trail reaches its maximum length
+func (a *Aircraft) AddTrailPoint(pos Position) {
+	if a.maxTrail <= 0 {
+		a.Trail = a.Trail[:0]
+		return
+	}
+
+	if len(a.Trail) >= a.maxTrail {
+		n := copy(a.Trail, a.Trail[len(a.Trail)-a.maxTrail+1:])
+		a.Trail = a.Trail[:n]
+	}
+
+	a.Trail = append(a.Trail, pos)
+}
+
+// TrailPointDue reports whether a new position is far enough, in distance or
+// time, from the last trail point to be worth recording. A zero interval
+// disables the time trigger, and a zero distance records every position.
+func (a *Aircraft) TrailPointDue(pos Position, minDistanceNM float64, minInterval time.Duration) bool {
+	if len(a.Trail) == 0 {
+		return true
+	}
+
+	last := a.Trail[len(a.Trail)-1]
+	if minInterval > 0 && pos.Timestamp.Sub(last.Timestamp) >= minInterval {
+		return true
+	}
+	return DistanceNM(last.Lat, last.Lon, pos.Lat, pos.Lon) >= minDistanceNM
+}
+
+// DistanceNM returns the great circle distance between two points in nautical miles
+func DistanceNM(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusNM = 3440.065
+
+	phi1 := lat1 * math.Pi / 180.0
+	phi2 := lat2 * math.Pi / 180.0
+	dPhi := (lat2 - lat1) * math.Pi / 180.0
+	dLambda := (lon2 - lon1) * math.Pi / 180.0
+
+	h := math.Sin(dPhi/2)*math.Sin(dPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * earthRadiusNM * math.Asin(math.Sqrt(math.Min(1, h)))
+}
+
+// BearingDeg returns the initial great circle bearing from the first point to
+// the second in degrees true, from 0 up to 360
+func BearingDeg(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180.0
+	phi2 := lat2 * math.Pi / 180.0
+	dLambda := (lon2 - lon1) * math.Pi / 180.0
+
+	y := math.Sin(dLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLambda)
+	return math.Mod(math.Atan2(y, x)*180.0/math.Pi+360, 360)
+}
+
 // AircraftMap is a type-safe map for storing aircraft keyed by ICAO address
 type AircraftMap struct {
-	data  map[uint32]*Aircraft
-	mutex sync.RWMutex
+	data        map[uint32]*Aircraft
+	trailLength int
+	limit       int    // Most aircraft tracked at once, 0 for no limit
+	pinned      uint32 // Aircraft never evicted to make room, 0 for none
+	mutex       sync.RWMutex
 }
 
-// NewAircraftMap creates a new, initialized aircraft map
-func NewAircraftMap() *AircraftMap {
+// NewAircraftMap creates a new, initialized aircraft map whose aircraft keep
+// at most trailLength historical positions
+func NewAircraftMap(trailLength int) *AircraftMap {
 	return &AircraftMap{
-		data: make(map[uint32]*Aircraft),
+		data:        make(map[uint32]*Aircraft),
+		trailLength: trailLength,
 	}
 }
 
@@ -123,6 +324,22 @@ func (am *AircraftMap) Get(icao uint32) *Aircraft {
 	return am.data[icao]
 }
 
+// SetLimit caps the number of aircraft tracked, 0 for no limit. Once the
+// cap is reached, creating an aircraft evicts the one seen least recently.
+func (am *AircraftMap) SetLimit(limit int) {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+	am.limit = limit
+}
+
+// Pin protects an aircraft, such as the selected one, from eviction by the
+// limit. Pinning another aircraft releases the previous one; 0 pins none.
+func (am *AircraftMap) Pin(icao uint32) {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+	am.pinned = icao
+}
+
 // GetOrCreate retrieves an aircraft or creates a new one if it doesn't exist
 func (am *AircraftMap) GetOrCreate(icao uint32) *Aircraft {
 	am.mutex.Lock()
@@ -130,12 +347,19 @@ func (am *AircraftMap) GetOrCreate(icao uint32) *Aircraft {
 
 	aircraft, exists := am.data[icao]
 	if !exists {
+		for am.limit > 0 && len(am.data) >= am.limit {
+			if !am.evictOldest() {
+				break
+			}
+		}
+
 		aircraft = &Aircraft{
 			ICAO:         icao,
 			Seen:         time.Now(),
-			Trail:        make([]Position, 0, TrailLength),
+			Trail:        make([]Position, 0, max(am.trailLength, 0)),
 			LabelOpacity: 0,
 			LabelLevel:   0,
+			maxTrail:     am.trailLength,
 		}
 		am.data[icao] = aircraft
 	}
@@ -143,6 +367,27 @@ func (am *AircraftMap) GetOrCreate(icao uint32) *Aircraft {
 	return aircraft
 }
 
+// evictOldest removes the least recently seen aircraft other than the pinned
+// one, reporting whether there was one to remove. The caller must hold the
+// write lock.
+func (am *AircraftMap) evictOldest() bool {
+	var oldest *Aircraft
+	for icao, aircraft := range am.data {
+		if icao == am.pinned {
+			continue
+		}
+		if oldest == nil || aircraft.Seen.Before(oldest.Seen) {
+			oldest = aircraft
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+
+	delete(am.data, oldest.ICAO)
+	return true
+}
+
 // Len returns the number of aircraft in the map
 func (am *AircraftMap) Len() int {
 	am.mutex.RLock()
@@ -160,13 +405,23 @@ func (am *AircraftMap) ForEach(f func(icao uint32, aircraft *Aircraft)) {
 	}
 }
 
-// RemoveStale removes aircraft that haven't been seen for a while
-func (am *AircraftMap) RemoveStale(ttl time.Duration) {
+// RemoveStale removes aircraft that haven't been seen for a while, using
+// separate timeouts for airborne aircraft and those on the ground. Targets
+// with anonymous addresses use anonymousTTL when it is shorter.
+func (am *AircraftMap) RemoveStale(airborneTTL, groundTTL, anonymousTTL time.Duration) {
 	am.mutex.Lock()
 	defer am.mutex.Unlock()
 
 	now := time.Now()
 	for icao, aircraft := range am.data {
+		ttl := airborneTTL
+		if aircraft.OnGround {
+			ttl = groundTTL
+		}
+		if aircraft.AddrType.Anonymous() && anonymousTTL < ttl {
+			ttl = anonymousTTL
+		}
+
 		if now.Sub(aircraft.Seen) > ttl {
 			delete(am.data, icao)
 		}
@@ -188,18 +443,9 @@ func (am *AircraftMap) Copy() map[uint32]*Aircraft {
 
 // CPR (Compact Position Reporting) constants
 const (
-	NL_LOOKUP_TABLE_SIZE = 90
+	CPR_NZ = 15 // Number of latitude zones between the equator and a pole
 )
 
-// NL lookup table for latitudes 0-90 degrees
-var nl_table = [NL_LOOKUP_TABLE_SIZE]int{
-	59, 59, 59, 59, 59, 59, 59, 59, 59, 58, 58, 58, 58, 58, 57, 57,
-	57, 57, 57, 57, 56, 56, 56, 56, 56, 56, 55, 55, 55, 55, 55, 54, 54, 54, 54,
-	54, 53, 53, 53, 53, 52, 52, 52, 52, 51, 51, 51, 51, 50, 50, 50, 49, 49, 49,
-	48, 48, 48, 47, 47, 47, 46, 46, 46, 45, 45, 44, 44, 44, 43, 43, 42, 42, 41,
-	41, 41, 40, 40, 39, 39, 38, 38, 37, 37, 36, 36, 35, 35, 34, 34, 33,
-}
-
 // cprModFunction implements the CPR modulo function
 func cprModFunction(a, b int) int {
 	res := a % b
@@ -209,20 +455,25 @@ func cprModFunction(a, b int) int {
 	return res
 }
 
-// cprNLFunction returns the NL lookup value for CPR calculations
+// cprNLFunction returns the number of longitude zones at a latitude, using
+// the transition latitude formula from the ADS-B specification
 func cprNLFunction(lat float64) int {
-	if lat < 0 {
-		lat = -lat // Table is symmetric about the equator
+	lat = math.Abs(lat) // Symmetric about the equator
+
+	// The last transition is at exactly 87 degrees: NL is 2 below it and 1
+	// from it to the pole
+	if lat == 0 {
+		return 59
+	} else if lat >= 87 {
+		return 1
 	}
 
-	// Round to nearest degree
-	lat = math.Round(lat)
+	a := 1 - math.Cos(math.Pi/(2*CPR_NZ))
+	b := math.Pow(math.Cos(math.Pi/180.0*lat), 2)
+	nl := int(math.Floor(2 * math.Pi / math.Acos(1-a/b)))
 
-	if lat >= NL_LOOKUP_TABLE_SIZE {
-		return 1 // Very high latitudes
-	}
-
-	return nl_table[int(lat)]
+	// Rounding can give 60 just off the equator
+	return min(nl, 59)
 }
 
 // cprNFunction returns the number of longitude zones
@@ -242,8 +493,21 @@ func cprDlonFunction(lat float64, odd bool, surface bool) float64 {
 	return 360.0 / float64(cprNFunction(lat, odd))
 }
 
-// DecodeCPRPosition decodes a pair of CPR positions to get the actual position
-func DecodeCPRPosition(evenLat, evenLon, oddLat, oddLon int, lastOdd bool) (float64, float64, bool) {
+// DecodeCPRFields extracts the raw 17-bit CPR latitude and longitude and the
+// odd/even format flag from an airborne position message
+func DecodeCPRFields(data []byte) (lat, lon int, odd bool) {
+	if len(data) < 11 {
+		return 0, 0, false
+	}
+
+	lat = int(data[6]&0x03)<<15 | int(data[7])<<7 | int(data[8])>>1
+	lon = int(data[8]&0x01)<<16 | int(data[9])<<8 | int(data[10])
+	odd = data[6]&0x04 != 0
+	return lat, lon, odd
+}
+
+// cprGlobalLats returns the latitudes an airborne even/odd CPR pair decodes to
+func cprGlobalLats(evenLat, oddLat int) (lat0, lat1 float64) {
 	// Constants for CPR decoding
 	const airDlat0 = 360.0 / 60.0
 	const airDlat1 = 360.0 / 59.0
@@ -251,15 +515,13 @@ func DecodeCPRPosition(evenLat, evenLon, oddLat, oddLon int, lastOdd bool) (floa
 	// Convert from CPR format (0-131071) to floating point (0-1)
 	rlat0 := float64(evenLat) / 131072.0
 	rlat1 := float64(oddLat) / 131072.0
-	rlon0 := float64(evenLon) / 131072.0
-	rlon1 := float64(oddLon) / 131072.0
 
 	// Compute the latitude index "j"
 	j := int(math.Floor(((59.0*rlat0 - 60.0*rlat1) / 1.0) + 0.5))
 
 	// Calculate global latitudes
-	lat0 := airDlat0 * (float64(cprModFunction(j, 60)) + rlat0)
-	lat1 := airDlat1 * (float64(cprModFunction(j, 59)) + rlat1)
+	lat0 = airDlat0 * (float64(cprModFunction(j, 60)) + rlat0)
+	lat1 = airDlat1 * (float64(cprModFunction(j, 59)) + rlat1)
 
 	// Adjust latitudes to be in the -90 to 90 range
 	if lat0 >= 270 {
@@ -268,6 +530,21 @@ func DecodeCPRPosition(evenLat, evenLon, oddLat, oddLon int, lastOdd bool) (floa
 	if lat1 >= 270 {
 		lat1 -= 360
 	}
+	return lat0, lat1
+}
+
+// CPRZones returns the number of longitude zones (NL) at the latitudes an
+// airborne even/odd CPR pair decodes to. A pair only decodes when they match.
+func CPRZones(evenLat, oddLat int) (nlEven, nlOdd int) {
+	lat0, lat1 := cprGlobalLats(evenLat, oddLat)
+	return cprNLFunction(lat0), cprNLFunction(lat1)
+}
+
+// DecodeCPRPosition decodes a pair of CPR positions to get the actual position
+func DecodeCPRPosition(evenLat, evenLon, oddLat, oddLon int, lastOdd bool) (float64, float64, bool) {
+	lat0, lat1 := cprGlobalLats(evenLat, oddLat)
+	rlon0 := float64(evenLon) / 131072.0
+	rlon1 := float64(oddLon) / 131072.0
 
 	// Check that both are in the same latitude zone
 	if cprNLFunction(lat0) != cprNLFunction(lat1) {
@@ -316,7 +593,9 @@ func DecodeCPRPosition(evenLat, evenLon, oddLat, oddLon int, lastOdd bool) (floa
 	return lat, lon, true
 }
 
-// DecodeCallsign decodes the 8-character callsign from ADS-B data
+// DecodeCallsign decodes the 8-character callsign from ADS-B data. Callsigns
+// holding any of the charset's reserved codes come from corrupt frames and
+// decode as empty.
 func DecodeCallsign(data []byte) string {
 	if len(data) < 6 {
 		return ""
@@ -341,6 +620,11 @@ func DecodeCallsign(data []byte) string {
 	callsign[6] = charset[bits>>6&0x3F]
 	callsign[7] = charset[bits&0x3F]
 
+	// Only A-Z, 0-9 and space are valid
+	if bytes.IndexByte(callsign, '?') >= 0 {
+		return ""
+	}
+
 	// Trim trailing spaces
 	i := 7
 	for i >= 0 && callsign[i] == ' ' {
@@ -350,14 +634,160 @@ func DecodeCallsign(data []byte) string {
 	return string(callsign[:i+1])
 }
 
-// DecodeAltitude decodes the altitude from ADS-B data
-func DecodeAltitude(data []byte) int {
+// Plausible altitude range in feet. Decoded altitudes outside it come from
+// corrupt fields and are rejected rather than shown.
+const (
+	MinAltitude = -2000
+	MaxAltitude = 100000
+)
+
+// ValidAltitude reports whether alt is within the plausible altitude range
+func ValidAltitude(alt int) bool {
+	return alt >= MinAltitude && alt <= MaxAltitude
+}
+
+// DecodeAC13 decodes the 13-bit altitude code (AC) field, bits 20-32, of
+// surveillance and Comm-B altitude replies (DF0/4/16/20). Only 25ft
+// increments are supported; Gillham and metric altitudes return ok false.
+func DecodeAC13(data []byte) (alt int, ok bool) {
+	if len(data) < 4 {
+		return 0, false
+	}
+
+	switch data[0] >> 3 {
+	case DF0, DF4, DF16, DF20:
+	default:
+		return 0, false
+	}
+
+	ac13 := int(data[2]&0x1F)<<8 | int(data[3])
+	if ac13 == 0 {
+		return 0, false // Altitude not available
+	}
+
+	// M bit (bit 26) set means metric units
+	if ac13&0x40 != 0 {
+		return 0, false
+	}
+
+	// Q bit (bit 28) set means 25ft increments, N is the remaining 11 bits
+	if ac13&0x10 == 0 {
+		return 0, false
+	}
+	n := (ac13&0x1F80)>>2 | (ac13&0x0020)>>1 | ac13&0x000F
+	return checkAltitude(n*25 - 1000)
+}
+
+// modeACode converts a 13-bit Mode A identity field, bits ordered
+// C1 A1 C2 A2 C4 A4 X B1 D1 B2 D2 B4 D4, to a squawk whose decimal digits
+// are the four octal code digits, e.g. 7700
+func modeACode(id int) int {
+	bit := func(n int) int { return id >> n & 1 }
+	a := bit(7)<<2 | bit(9)<<1 | bit(11)
+	b := bit(1)<<2 | bit(3)<<1 | bit(5)
+	c := bit(8)<<2 | bit(10)<<1 | bit(12)
+	d := bit(0)<<2 | bit(2)<<1 | bit(4)
+	return a*1000 + b*100 + c*10 + d
+}
+
+// DecodeID13 decodes the 13-bit identity (ID) field, bits 20-32, of
+// surveillance and Comm-B identity replies (DF5/21) into a squawk, see
+// modeACode
+func DecodeID13(data []byte) (squawk int, ok bool) {
+	if len(data) < 4 {
+		return 0, false
+	}
+	if df := data[0] >> 3; df != DF5 && df != DF21 {
+		return 0, false
+	}
+	return modeACode(int(data[2]&0x1F)<<8 | int(data[3])), true
+}
+
+// DecodeStatusSquawk decodes the Mode A code, ME bits 12-24, of an aircraft
+// status message (TC 28 subtype 1) into a squawk, see modeACode
+func DecodeStatusSquawk(data []byte) (squawk int, ok bool) {
+	if len(data) < 7 || data[4]>>3 != TC_STATUS || data[4]&0x07 != 1 {
+		return 0, false
+	}
+	return modeACode(int(data[5]&0x1F)<<8 | int(data[6])), true
+}
+
+// Capability (CA) values of DF11/17 that say whether the aircraft is on the
+// ground. The others (0-3 reserved or level 1, 6 and 7) leave it unknown.
+const (
+	CAGround   = 4 // Level 2+ transponder, on the ground
+	CAAirborne = 5 // Level 2+ transponder, airborne
+)
+
+// DecodeGroundState tells whether an extended squitter comes from an
+// aircraft on the ground. Surface position messages always do; otherwise
+// the DF17 capability field decides, so an aircraft taxiing with an airborne
+// position type code is still put on the ground. Airborne position messages
+// with an undetermined capability are taken as airborne.
+func DecodeGroundState(data []byte) (onGround bool, ok bool) {
+	if len(data) < 5 {
+		return false, false
+	}
+	df := int(data[0] >> 3)
+	if df != DF17 && df != DF18 {
+		return false, false
+	}
+
+	tc := int(data[4] >> 3)
+	if tc >= 5 && tc <= 8 {
+		return true, true
+	}
+
+	// DF18 carries the control field in place of the capability
+	if df == DF17 {
+		switch int(data[0] & 0x07) {
+		case CAGround:
+			return true, true
+		case CAAirborne:
+			return false, true
+		}
+	}
+
+	if (tc >= 9 && tc <= 18) || (tc >= 20 && tc <= 22) {
+		return false, true
+	}
+	return false, false
+}
+
+// Surveillance status of an airborne position message
+const (
+	SSNoCondition    = 0
+	SSPermanentAlert = 1 // Emergency condition
+	SSTemporaryAlert = 2 // Mode A code changed
+	SSIdent          = 3 // Special position identification (SPI) pulse
+)
+
+// DecodeSurveillanceStatus decodes the 2-bit surveillance status, ME bits 6-7,
+// and the single antenna flag, ME bit 8, of an airborne position message
+// (TC 9-18 and 20-22)
+func DecodeSurveillanceStatus(data []byte) (status int, singleAntenna bool, ok bool) {
+	if len(data) < 5 {
+		return 0, false, false
+	}
+	if tc := data[4] >> 3; tc < 9 || tc > 22 || tc == 19 {
+		return 0, false, false
+	}
+	return int(data[4]>>1) & 0x03, data[4]&0x01 != 0, true
+}
+
+// DecodeAltitude decodes the altitude from an ADS-B airborne position
+// message. ok is false when no altitude is available or it can't be decoded,
+// so a valid 0 ft can be told apart from a missing altitude.
+func DecodeAltitude(data []byte) (alt int, ok bool) {
 	if len(data) < 7 {
-		return 0
+		return 0, false
 	}
 
-	// Extract altitude bits
-	ac12Field := ((uint16(data[5]) & 0x1F) << 7) | ((uint16(data[6]) & 0xFE) >> 1)
+	// Extract the 12-bit altitude field, ME bits 9-20
+	ac12Field := (uint16(data[5]) << 4) | (uint16(data[6]) >> 4)
+	if ac12Field == 0 {
+		return 0, false // Altitude not available
+	}
 
 	// Check if the altitude is Gillham coded or not
 	qBit := (ac12Field & 0x10) != 0
@@ -365,26 +795,141 @@ func DecodeAltitude(data []byte) int {
 	if qBit {
 		// Extract the 11-bit altitude value
 		n := ((ac12Field & 0x0FE0) >> 1) | (ac12Field & 0x000F)
-		return (int(n) * 25) - 1000
+		return checkAltitude((int(n) * 25) - 1000)
 	}
 
 	// Gillham coded altitude - would need more complex decoding
-	// For simplicity, return 0
-	return 0
+	return 0, false
+}
+
+// checkAltitude returns alt with ok false when it is out of range
+func checkAltitude(alt int) (int, bool) {
+	if !ValidAltitude(alt) {
+		return 0, false
+	}
+	return alt, true
+}
+
+// emergencyStates names the TC 28 subtype 1 emergency/priority codes
+var emergencyStates = [8]string{
+	"",          // No emergency
+	"general",   // General emergency
+	"lifeguard", // Lifeguard/medical emergency
+	"minfuel",   // Minimum fuel
+	"nordo",     // No communications
+	"unlawful",  // Unlawful interference
+	"downed",    // Downed aircraft
+	"reserved",
+}
+
+// DecodeEmergency decodes the emergency/priority status of an aircraft status
+// message (TC 28 subtype 1). state is empty when there is no emergency, and
+// ok is false for other messages.
+func DecodeEmergency(data []byte) (state string, ok bool) {
+	if len(data) < 6 || data[4]>>3 != TC_STATUS || data[4]&0x07 != 1 {
+		return "", false
+	}
+
+	// Emergency state, ME bits 9-11
+	return emergencyStates[data[5]>>5], true
 }
 
-// DecodeVelocity decodes the velocity from ADS-B data
+// DecodeSurfaceMovement decodes ground speed and track from a surface
+// position message (TC 5-8). valid is false when the movement field holds no
+// speed; trackDeg is -1 when the track status bit is clear.
+func DecodeSurfaceMovement(data []byte) (speedKts int, trackDeg int, valid bool) {
+	if len(data) < 7 {
+		return 0, -1, false
+	}
+	if tc := data[4] >> 3; tc < 5 || tc > 8 {
+		return 0, -1, false
+	}
+
+	// Ground track, ME bit 13 status and bits 14-20 in 360/128 degree steps
+	trackDeg = -1
+	if data[5]&0x08 != 0 {
+		raw := int(data[5]&0x07)<<4 | int(data[6])>>4
+		trackDeg = int(math.Round(float64(raw) * 360.0 / 128.0))
+	}
+
+	// Movement, ME bits 6-12
+	movement := int(data[4]&0x07)<<4 | int(data[5])>>4
+	speed, ok := surfaceSpeed(movement)
+	if !ok {
+		return 0, trackDeg, false
+	}
+	return int(math.Round(speed)), trackDeg, true
+}
+
+// surfaceSpeed converts a surface movement code to knots. The quantization
+// is finer at low speeds, so the slope changes between ranges.
+func surfaceSpeed(movement int) (float64, bool) {
+	switch {
+	case movement == 1:
+		return 0, true // Stopped
+	case movement >= 2 && movement <= 8:
+		return 0.125 * float64(movement-1), true // 0.125 kt steps
+	case movement >= 9 && movement <= 12:
+		return 1 + 0.25*float64(movement-9), true // 0.25 kt steps
+	case movement >= 13 && movement <= 38:
+		return 2 + 0.5*float64(movement-13), true // 0.5 kt steps
+	case movement >= 39 && movement <= 93:
+		return 15 + float64(movement-39), true // 1 kt steps
+	case movement >= 94 && movement <= 108:
+		return 70 + 2*float64(movement-94), true // 2 kt steps
+	case movement >= 109 && movement <= 123:
+		return 100 + 5*float64(movement-109), true // 5 kt steps
+	case movement == 124:
+		return 175, true // 175 kt or more
+	}
+	return 0, false // 0 is no information, 125-127 are reserved
+}
+
+// Velocity is a decoded airborne velocity message (TC 19)
+type Velocity struct {
+	Speed      int     // Ground speed (subtypes 1/2) or airspeed (3/4) in knots
+	Airspeed   bool    // Whether Speed is an airspeed rather than ground speed
+	Track      float64 // True track over the ground in degrees, subtypes 1/2
+	HasTrack   bool    // Whether Track is valid
+	Heading    float64 // Magnetic heading in degrees, subtypes 3/4
+	HasHeading bool    // Whether Heading is valid
+	VertRate   int     // Vertical rate in ft/min
+}
+
+// DecodeVelocity decodes the velocity from ADS-B data. heading is the true
+// track for subtypes 1/2 and the magnetic heading for subtypes 3/4; use
+// DecodeVelocityDetails to tell them apart.
 func DecodeVelocity(data []byte) (speed, heading, vertRate int, ok bool) {
-	if len(data) < 10 {
+	v, ok := DecodeVelocityDetails(data)
+	if !ok {
 		return 0, 0, 0, false
 	}
 
+	switch {
+	case v.HasTrack:
+		heading = int(math.Round(v.Track))
+		if heading == 360 {
+			heading = 0
+		}
+	case v.HasHeading:
+		heading = int(v.Heading)
+	}
+	return v.Speed, heading, v.VertRate, true
+}
+
+// DecodeVelocityDetails decodes an airborne velocity message, keeping the
+// true track of subtypes 1/2 apart from the magnetic heading of subtypes 3/4
+func DecodeVelocityDetails(data []byte) (v Velocity, ok bool) {
+	if len(data) < 10 {
+		return v, false
+	}
+
 	// Get message subtype
 	subtype := data[4] & 0x07
 
 	// Only handle subtypes 1-4
 	if subtype < 1 || subtype > 4 {
-		return 0, 0, 0, false
+		return v, false
 	}
 
 	// Decode vertical rate
@@ -395,7 +940,7 @@ func DecodeVelocity(data []byte) (speed, heading, vertRate int, ok bool) {
 		if vertRateBit {
 			vertRateRaw = -vertRateRaw
 		}
-		vertRate = vertRateRaw * 64
+		v.VertRate = vertRateRaw * 64
 	}
 
 	// Airborne velocity subtypes 1 & 2
@@ -422,38 +967,90 @@ func DecodeVelocity(data []byte) (speed, heading, vertRate int, ok bool) {
 			nsVel *= 4
 		}
 
-		// Calculate speed and heading from components
-		speed = int(math.Sqrt(float64(ewVel*ewVel + nsVel*nsVel)))
-		if speed > 0 {
-			heading = int(math.Round(math.Atan2(float64(ewVel), float64(nsVel)) * 180.0 / math.Pi))
-			if heading < 0 {
-				heading += 360
+		// Calculate speed and track from components
+		v.Speed = int(math.Sqrt(float64(ewVel*ewVel + nsVel*nsVel)))
+		if v.Speed > 0 {
+			v.Track = math.Atan2(float64(ewVel), float64(nsVel)) * 180.0 / math.Pi
+			if v.Track < 0 {
+				v.Track += 360
 			}
+			v.HasTrack = true
 		}
 
-		return speed, heading, vertRate, true
+		return v, true
 	}
 
 	// Airborne velocity subtypes 3 & 4
-	if subtype == 3 || subtype == 4 {
-		// Decode airspeed
-		airspeed := ((int(data[7]) & 0x7F) << 3) | (int(data[8]) >> 5)
-		if airspeed != 0 {
-			airspeed -= 1
-			if subtype == 4 {
-				airspeed *= 4
-			}
-			speed = airspeed
+	// Decode airspeed
+	v.Airspeed = true
+	airspeed := ((int(data[7]) & 0x7F) << 3) | (int(data[8]) >> 5)
+	if airspeed != 0 {
+		airspeed -= 1
+		if subtype == 4 {
+			airspeed *= 4
 		}
+		v.Speed = airspeed
+	}
 
-		// Decode heading if available
-		if (data[5] & 0x04) != 0 {
-			hdgRaw := ((int(data[5]) & 0x03) << 8) | int(data[6])
-			heading = (hdgRaw * 360) / 1024
-		}
+	// Decode magnetic heading if available
+	if (data[5] & 0x04) != 0 {
+		hdgRaw := ((int(data[5]) & 0x03) << 8) | int(data[6])
+		v.Heading = float64(hdgRaw) * 360.0 / 1024.0
+		v.HasHeading = true
+	}
+
+	return v, true
+}
+
+// NICtoRadius returns the horizontal containment radius in meters implied by a
+// position message type code (the NUCp/NIC encoded in the type code). It
+// returns 0 when the type code carries no bound or is not a position message.
+func NICtoRadius(tc int) float64 {
+	switch tc {
+	case 5, 9, 20:
+		return 7.5
+	case 6, 10, 21:
+		return 25
+	case 7, 11:
+		return 185.2 // 0.1 NM
+	case 12:
+		return 370.4 // 0.2 NM
+	case 13:
+		return 926 // 0.5 NM
+	case 14:
+		return 1852 // 1 NM
+	case 15:
+		return 3704 // 2 NM
+	case 16:
+		return 18520 // 10 NM
+	case 17:
+		return 37040 // 20 NM
+	}
+	return 0 // TC 8, 18, 22: unknown
+}
+
+// NACpToRadius returns the estimated position uncertainty in meters for a
+// Navigation Accuracy Category for position, or 0 if unknown
+func NACpToRadius(nacp int) float64 {
+	epu := [...]float64{0, 18520, 7408, 3704, 1852, 926, 555.6, 185.2, 92.6, 30, 10, 3}
+	if nacp < 0 || nacp >= len(epu) {
+		return 0
+	}
+	return epu[nacp]
+}
+
+// DecodeNACp extracts NACp from an aircraft operational status message (TC31).
+// Version 0 messages don't carry NACp, so ok is false for them.
+func DecodeNACp(data []byte) (nacp int, ok bool) {
+	if len(data) < 11 || data[4]>>3 != TC_OPSTATUS {
+		return 0, false
+	}
 
-		return speed, heading, vertRate, true
+	// ADS-B version number is ME bits 41-43, NACp is ME bits 45-48
+	version := data[9] >> 5
+	if version == 0 {
+		return 0, false
 	}
 
-	return 0, 0, 0, false
+	return int(data[9] & 0x0F), true
 }
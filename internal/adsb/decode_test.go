@@ -0,0 +1,709 @@
+package adsb
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTrailNeverExceedsConfiguredLength(t *testing.T) {
+	const trailLength = 5
+
+	am := NewAircraftMap(trailLength)
+	aircraft := am.GetOrCreate(0xABCDEF)
+
+	for i := 0; i < 3*trailLength; i++ {
+		aircraft.AddTrailPoint(Position{Lat: float64(i), Timestamp: time.Now()})
+
+		if len(aircraft.Trail) > trailLength {
+			t.Fatalf("trail length %d exceeds configured maximum %d", len(aircraft.Trail), trailLength)
+		}
+	}
+
+	if len(aircraft.Trail) != trailLength {
+		t.Fatalf("expected trail length %d, got %d", trailLength, len(aircraft.Trail))
+	}
+
+	// The oldest positions should have been dropped
+	first := aircraft.Trail[0].Lat
+	last := aircraft.Trail[trailLength-1].Lat
+	if first != float64(2*trailLength) || last != float64(3*trailLength-1) {
+		t.Errorf("expected trail to span %d..%d, got %v..%v", 2*trailLength, 3*trailLength-1, first, last)
+	}
+}
+
+func TestTrailDisabledWithZeroLength(t *testing.T) {
+	am := NewAircraftMap(0)
+	aircraft := am.GetOrCreate(0xABCDEF)
+
+	aircraft.AddTrailPoint(Position{Lat: 1, Timestamp: time.Now()})
+
+	if len(aircraft.Trail) != 0 {
+		t.Errorf("expected no trail points, got %d", len(aircraft.Trail))
+	}
+}
+
+func TestTrailPointDue(t *testing.T) {
+	start := time.Now()
+	aircraft := NewAircraftMap(10).GetOrCreate(0xABCDEF)
+	aircraft.AddTrailPoint(Position{Lat: 51.0, Lon: 0, Timestamp: start})
+
+	tests := []struct {
+		name        string
+		lat         float64 // 0.01 degrees is 0.6 NM
+		after       time.Duration
+		minNM       float64
+		minInterval time.Duration
+		want        bool
+	}{
+		{"moved far enough", 51.01, time.Second, 0.5, 30 * time.Second, true},
+		{"interval passed", 51.001, 31 * time.Second, 0.5, 30 * time.Second, true},
+		{"neither", 51.001, time.Second, 0.5, 30 * time.Second, false},
+		{"zero interval keeps the distance check", 51.001, time.Hour, 0.5, 0, false},
+		{"zero interval, moved", 51.01, time.Second, 0.5, 0, true},
+		{"zero thresholds record everything", 51.0, 0, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		pos := Position{Lat: tt.lat, Lon: 0, Timestamp: start.Add(tt.after)}
+		if got := aircraft.TrailPointDue(pos, tt.minNM, tt.minInterval); got != tt.want {
+			t.Errorf("%s: TrailPointDue = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNICtoRadius(t *testing.T) {
+	tests := []struct {
+		tc   int
+		want float64
+	}{
+		{5, 7.5},
+		{8, 0},
+		{9, 7.5},
+		{10, 25},
+		{11, 185.2},
+		{12, 370.4},
+		{13, 926},
+		{14, 1852},
+		{15, 3704},
+		{16, 18520},
+		{17, 37040},
+		{18, 0},
+		{20, 7.5},
+		{21, 25},
+		{22, 0},
+		{19, 0},
+	}
+
+	for _, tt := range tests {
+		if got := NICtoRadius(tt.tc); got != tt.want {
+			t.Errorf("NICtoRadius(%d) = %v, want %v", tt.tc, got, tt.want)
+		}
+	}
+}
+
+func TestNACpToRadius(t *testing.T) {
+	tests := []struct {
+		nacp int
+		want float64
+	}{
+		{0, 0},
+		{1, 18520},
+		{4, 1852},
+		{8, 92.6},
+		{11, 3},
+		{12, 0},
+		{-1, 0},
+	}
+
+	for _, tt := range tests {
+		if got := NACpToRadius(tt.nacp); got != tt.want {
+			t.Errorf("NACpToRadius(%d) = %v, want %v", tt.nacp, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeNACp(t *testing.T) {
+	// DF17 operational status, version 2 (ME bits 41-43 = 010), NACp 9
+	data := []byte{0x8D, 0x48, 0x40, 0xD6, 0xF8, 0x00, 0x00, 0x00, 0x00, 0x49, 0x00, 0, 0, 0}
+	nacp, ok := DecodeNACp(data)
+	if !ok || nacp != 9 {
+		t.Errorf("expected NACp 9, got %d (ok=%v)", nacp, ok)
+	}
+
+	// Version 0 messages carry no NACp
+	data[9] = 0x09
+	if _, ok := DecodeNACp(data); ok {
+		t.Error("expected no NACp for version 0")
+	}
+
+	// Not an operational status message
+	data[4] = 0x58
+	if _, ok := DecodeNACp(data); ok {
+		t.Error("expected no NACp for TC11")
+	}
+}
+
+// nlTransitions are the latitudes at which NL drops from 59-i to 58-i, as
+// tabulated in the ADS-B specification
+var nlTransitions = []float64{
+	10.47047130, 14.82817437, 18.18626357, 21.02939493, 23.54504487,
+	25.82924707, 27.93898710, 29.91135686, 31.77209708, 33.53993436,
+	35.22899598, 36.85025108, 38.41241892, 39.92256684, 41.38651832,
+	42.80914012, 44.19454951, 45.54626723, 46.86733252, 48.16039128,
+	49.42776439, 50.67150166, 51.89342469, 53.09516153, 54.27817472,
+	55.44378444, 56.59318756, 57.72747354, 58.84763776, 59.95459277,
+	61.04917774, 62.13216659, 63.20427479, 64.26616523, 65.31845310,
+	66.36171008, 67.39646774, 68.42322022, 69.44242631, 70.45451075,
+	71.45986473, 72.45884545, 73.45177442, 74.43893416, 75.42056257,
+	76.39684391, 77.36789461, 78.33374083, 79.29428225, 80.24923213,
+	81.19801349, 82.13956981, 83.07199445, 83.99173563, 84.89166191,
+	85.75541621, 86.53536998, 87.00000000,
+}
+
+// referenceNL looks up NL from the transition latitude table
+func referenceNL(lat float64) int {
+	lat = math.Abs(lat)
+	for i, t := range nlTransitions {
+		if lat < t {
+			return 59 - i
+		}
+	}
+	return 1
+}
+
+func TestCPRNLMatchesSpecification(t *testing.T) {
+	// Sweep the whole range, stepping around the table's precision
+	for lat := -90.0; lat <= 90.0; lat += 0.001 {
+		if got, want := cprNLFunction(lat), referenceNL(lat); got != want {
+			t.Fatalf("NL(%.3f) = %d, want %d", lat, got, want)
+		}
+	}
+
+	// On the transitions the lower zone count applies
+	for _, lat := range []float64{87, -87, 90} {
+		if got := cprNLFunction(lat); got != 1 {
+			t.Errorf("NL(%v) = %d, want 1", lat, got)
+		}
+	}
+	if got := cprNLFunction(86.999999); got != 2 {
+		t.Errorf("NL(86.999999) = %d, want 2", got)
+	}
+
+	// Either side of each transition
+	for i, tr := range nlTransitions {
+		if got := cprNLFunction(tr - 1e-6); got != 59-i {
+			t.Errorf("NL just below %.8f = %d, want %d", tr, got, 59-i)
+		}
+		if got := cprNLFunction(tr + 1e-6); got != 58-i {
+			t.Errorf("NL just above %.8f = %d, want %d", tr, got, 58-i)
+		}
+	}
+}
+
+func TestDecodeAC13(t *testing.T) {
+	// DF20 reply at 32300ft
+	data := []byte{0xA0, 0x20, 0x14, 0xB4, 0, 0, 0, 0, 0, 0, 0, 0xF9, 0xD5, 0x14}
+	if alt, ok := DecodeAC13(data); !ok || alt != 32300 {
+		t.Errorf("DF20: got %d (ok=%v), want 32300", alt, ok)
+	}
+
+	// The AC field is in the same place for DF0, DF4 and DF16
+	for _, df := range []byte{DF0, DF4, DF16} {
+		msg := append([]byte(nil), data...)
+		msg[0] = df<<3 | msg[0]&0x07
+		if alt, ok := DecodeAC13(msg); !ok || alt != 32300 {
+			t.Errorf("DF%d: got %d (ok=%v), want 32300", df, alt, ok)
+		}
+	}
+
+	// DF0 short reply with bits outside the AC field set
+	short := []byte{DF0 << 3, 0xFF, 0xE0 | 0x14, 0xB4, 0x12, 0x34, 0x56}
+	if alt, ok := DecodeAC13(short); !ok || alt != 32300 {
+		t.Errorf("DF0 short: got %d (ok=%v), want 32300", alt, ok)
+	}
+}
+
+func TestValidAltitude(t *testing.T) {
+	tests := []struct {
+		alt  int
+		want bool
+	}{
+		{MinAltitude - 1, false},
+		{MinAltitude, true},
+		{-1000, true},
+		{0, true},
+		{50175, true},
+		{MaxAltitude, true},
+		{MaxAltitude + 1, false},
+		{126700, false}, // Top of the Gillham range
+	}
+
+	for _, tt := range tests {
+		if got := ValidAltitude(tt.alt); got != tt.want {
+			t.Errorf("ValidAltitude(%d) = %v, want %v", tt.alt, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeAC13Unsupported(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not available", []byte{DF4 << 3, 0, 0, 0}},
+		{"metric", []byte{DF4 << 3, 0, 0x14, 0xF4}},
+		{"gillham", []byte{DF4 << 3, 0, 0x14, 0xA4}},
+		{"extended squitter", []byte{DF17 << 3, 0, 0x14, 0xB4}},
+		{"too short", []byte{DF4 << 3, 0, 0x14}},
+	}
+
+	for _, tt := range tests {
+		if alt, ok := DecodeAC13(tt.data); ok {
+			t.Errorf("%s: expected no altitude, got %d", tt.name, alt)
+		}
+	}
+}
+
+func TestRemoveStaleUsesCategoryTTL(t *testing.T) {
+	am := NewAircraftMap(0)
+
+	airborne := am.GetOrCreate(0x000001)
+	ground := am.GetOrCreate(0x000002)
+	ground.OnGround = true
+
+	// Both last heard from 30 seconds ago
+	airborne.Seen = time.Now().Add(-30 * time.Second)
+	ground.Seen = airborne.Seen
+
+	am.RemoveStale(60*time.Second, 10*time.Second, 60*time.Second)
+	if am.Get(0x000001) == nil {
+		t.Error("airborne aircraft removed before its 60s TTL")
+	}
+	if am.Get(0x000002) != nil {
+		t.Error("ground aircraft kept past its 10s TTL")
+	}
+
+	am.RemoveStale(20*time.Second, 10*time.Second, 60*time.Second)
+	if am.Get(0x000001) != nil {
+		t.Error("airborne aircraft kept past its 20s TTL")
+	}
+}
+
+func TestLimitEvictsLeastRecentlySeen(t *testing.T) {
+	am := NewAircraftMap(0)
+	am.SetLimit(3)
+
+	now := time.Now()
+	for i, age := range []time.Duration{10, 30, 20} {
+		am.GetOrCreate(uint32(i + 1)).Seen = now.Add(-age * time.Second)
+	}
+
+	// The oldest, 000002, makes way for the new aircraft
+	am.GetOrCreate(0x000004)
+	if am.Len() != 3 {
+		t.Fatalf("tracking %d aircraft, want the limit of 3", am.Len())
+	}
+	if am.Get(0x000002) != nil {
+		t.Error("least recently seen aircraft not evicted")
+	}
+
+	// A pinned aircraft is skipped even when it is the oldest
+	am.Pin(0x000003)
+	am.GetOrCreate(0x000005)
+	if am.Get(0x000003) == nil {
+		t.Error("pinned aircraft evicted")
+	}
+	if am.Get(0x000001) != nil {
+		t.Error("oldest unpinned aircraft not evicted")
+	}
+
+	// Existing aircraft never evict anything
+	am.GetOrCreate(0x000004)
+	if am.Len() != 3 {
+		t.Errorf("tracking %d aircraft after a lookup, want 3", am.Len())
+	}
+}
+
+// positionWithAltitude builds an airborne position message carrying the given
+// 12-bit altitude field
+func positionWithAltitude(ac12 uint16) []byte {
+	data := []byte{0x8D, 0x40, 0x62, 0x1D, 0x58, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	data[5] = byte(ac12 >> 4)
+	data[6] = byte(ac12&0x0F) << 4
+	return data
+}
+
+func TestDecodeAltitude(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		alt  int
+		ok   bool
+	}{
+		{"38000ft", []byte{0x8D, 0x40, 0x62, 0x1D, 0x58, 0xC3, 0x82, 0xD6, 0x90, 0xC8, 0xAC, 0x28, 0x63, 0xA7}, 38000, true},
+		{"0ft", positionWithAltitude(0x058), 0, true},   // N = 40
+		{"25ft", positionWithAltitude(0x059), 25, true}, // N = 41
+		{"-1000ft", positionWithAltitude(0x010), -1000, true},
+		{"-975ft", positionWithAltitude(0x011), -975, true},   // N = 1
+		{"50175ft", positionWithAltitude(0xFFF), 50175, true}, // N = 2047, the largest 25ft code
+		{"no data", positionWithAltitude(0x000), 0, false},
+		{"gillham", positionWithAltitude(0x0A5), 0, false},
+		{"too short", []byte{0x8D, 0x40, 0x62, 0x1D, 0x58, 0xC3}, 0, false},
+	}
+
+	for _, tt := range tests {
+		alt, ok := DecodeAltitude(tt.data)
+		if alt != tt.alt || ok != tt.ok {
+			t.Errorf("%s: got %d (ok=%v), want %d (ok=%v)", tt.name, alt, ok, tt.alt, tt.ok)
+		}
+	}
+}
+
+func TestDecodeVelocityMagneticHeading(t *testing.T) {
+	// Subtype 3 airspeed message with magnetic heading 243.98
+	data := []byte{0x8D, 0xA0, 0x5F, 0x21, 0x9B, 0x06, 0xB6, 0xAF, 0x18, 0x94, 0x00, 0xCB, 0xC3, 0x3F}
+
+	v, ok := DecodeVelocityDetails(data)
+	if !ok {
+		t.Fatal("subtype 3 message not decoded")
+	}
+	if !v.HasHeading || v.HasTrack || !v.Airspeed {
+		t.Errorf("got heading=%v track=%v airspeed=%v, want a magnetic heading airspeed",
+			v.HasHeading, v.HasTrack, v.Airspeed)
+	}
+	if math.Abs(v.Heading-243.98) > 0.01 {
+		t.Errorf("heading = %.2f, want 243.98", v.Heading)
+	}
+	if v.Speed != 375 || v.VertRate != -2304 {
+		t.Errorf("speed %d rate %d, want 375 -2304", v.Speed, v.VertRate)
+	}
+
+	// Heading status bit clear means no heading, whatever the value bits hold
+	noHeading := append([]byte(nil), data...)
+	noHeading[5] &^= 0x04
+	if v, ok := DecodeVelocityDetails(noHeading); !ok || v.HasHeading {
+		t.Errorf("heading reported with the status bit clear (ok=%v)", ok)
+	}
+
+	// Heading is in 360/1024 degree steps
+	for _, raw := range []int{0, 256, 512, 1023} {
+		msg := append([]byte(nil), data...)
+		msg[5] = msg[5]&^0x03 | byte(raw>>8)
+		msg[6] = byte(raw)
+		v, _ := DecodeVelocityDetails(msg)
+		if want := float64(raw) * 360 / 1024; v.Heading != want {
+			t.Errorf("raw heading %d decoded as %.3f, want %.3f", raw, v.Heading, want)
+		}
+	}
+
+	// Subtype 4 is the supersonic variant with the same heading
+	supersonic := append([]byte(nil), data...)
+	supersonic[4] = supersonic[4]&^0x07 | 4
+	if v, ok := DecodeVelocityDetails(supersonic); !ok || !v.HasHeading || v.Speed != 1500 {
+		t.Errorf("subtype 4: got %+v (ok=%v)", v, ok)
+	}
+}
+
+func TestDecodeVelocityTrueTrack(t *testing.T) {
+	// Subtype 1 ground speed message with true track 182.88
+	data := []byte{0x8D, 0x48, 0x50, 0x20, 0x99, 0x44, 0x09, 0x94, 0x08, 0x38, 0x17, 0x5B, 0x28, 0x4F}
+
+	v, ok := DecodeVelocityDetails(data)
+	if !ok || !v.HasTrack || v.HasHeading || v.Airspeed {
+		t.Fatalf("got %+v (ok=%v), want a true track ground speed", v, ok)
+	}
+	if math.Abs(v.Track-182.88) > 0.01 {
+		t.Errorf("track = %.2f, want 182.88", v.Track)
+	}
+}
+
+// surfacePosition builds a TC 7 surface position message with the given
+// movement code and, when track is 0-127, track status set and that raw track
+func surfacePosition(movement, track int) []byte {
+	data := []byte{0x8C, 0x48, 0x40, 0xD6, 7 << 3, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	data[4] |= byte(movement >> 4)
+	data[5] = byte(movement&0x0F) << 4
+	if track >= 0 {
+		data[5] |= 0x08 | byte(track>>4)
+		data[6] = byte(track&0x0F) << 4
+	}
+	return data
+}
+
+func TestDecodeSurfaceMovement(t *testing.T) {
+	tests := []struct {
+		movement int
+		speed    int
+		valid    bool
+	}{
+		{0, 0, false}, // No information
+		{1, 0, true},  // Stopped
+		{2, 0, true},  // 0.125 kt
+		{8, 1, true},  // 0.875 kt, last 0.125 kt step
+		{9, 1, true},  // 1 kt, first 0.25 kt step
+		{12, 2, true}, // 1.75 kt
+		{13, 2, true}, // 2 kt, first 0.5 kt step
+		{14, 3, true}, // 2.5 kt rounds up
+		{38, 15, true},
+		{39, 15, true}, // First 1 kt step
+		{93, 69, true},
+		{94, 70, true}, // First 2 kt step
+		{108, 98, true},
+		{109, 100, true}, // First 5 kt step
+		{123, 170, true},
+		{124, 175, true}, // 175 kt or more
+		{125, 0, false},  // Reserved
+		{127, 0, false},
+	}
+
+	for _, tt := range tests {
+		speed, _, valid := DecodeSurfaceMovement(surfacePosition(tt.movement, -1))
+		if speed != tt.speed || valid != tt.valid {
+			t.Errorf("movement %d: got %dkts (valid=%v), want %dkts (valid=%v)",
+				tt.movement, speed, valid, tt.speed, tt.valid)
+		}
+	}
+
+	// Slope doubles at each range boundary
+	for _, m := range []int{2, 9, 13, 39, 94, 109} {
+		lo, _ := surfaceSpeed(m)
+		hi, _ := surfaceSpeed(m + 1)
+		prev, _ := surfaceSpeed(m - 1)
+		if m > 2 && !(hi-lo > lo-prev) {
+			t.Errorf("movement %d: step %.3f not larger than previous step %.3f", m, hi-lo, lo-prev)
+		}
+	}
+}
+
+func TestDecodeSurfaceTrack(t *testing.T) {
+	tests := []struct {
+		raw   int
+		track int
+	}{
+		{-1, -1}, // Status bit clear
+		{0, 0},
+		{32, 90},
+		{64, 180},
+		{127, 357}, // 357.1875
+	}
+
+	for _, tt := range tests {
+		_, track, _ := DecodeSurfaceMovement(surfacePosition(20, tt.raw))
+		if track != tt.track {
+			t.Errorf("raw track %d: got %d, want %d", tt.raw, track, tt.track)
+		}
+	}
+
+	// Not a surface position
+	if _, _, valid := DecodeSurfaceMovement(positionWithAltitude(0x058)); valid {
+		t.Error("airborne position decoded as surface movement")
+	}
+}
+
+func TestDecodeSurveillanceStatus(t *testing.T) {
+	tests := []struct {
+		me0           byte // First ME byte: type code, status and antenna flag
+		status        int
+		singleAntenna bool
+		ok            bool
+	}{
+		{0x58, SSNoCondition, false, true}, // TC 11
+		{0x5A, SSPermanentAlert, false, true},
+		{0x5C, SSTemporaryAlert, false, true},
+		{0x5E, SSIdent, false, true},
+		{0x5F, SSIdent, true, true},
+		{0x91, SSNoCondition, true, true}, // TC 18
+		{0xA6, SSIdent, false, true},      // TC 20, GNSS altitude
+		{0x9E, 0, false, false},           // TC 19 velocity
+		{0x2E, 0, false, false},           // TC 5 surface position
+	}
+
+	for _, tt := range tests {
+		frame := positionWithAltitude(0x058)
+		frame[4] = tt.me0
+		status, singleAntenna, ok := DecodeSurveillanceStatus(frame)
+		if status != tt.status || singleAntenna != tt.singleAntenna || ok != tt.ok {
+			t.Errorf("ME %02X: got %d %v %v, want %d %v %v", tt.me0, status, singleAntenna, ok,
+				tt.status, tt.singleAntenna, tt.ok)
+		}
+	}
+
+	// A recorded position with no condition and both antennas
+	frame, _ := hex.DecodeString("8D40621D58C382D690C8AC2863A7")
+	if status, singleAntenna, ok := DecodeSurveillanceStatus(frame); !ok || status != SSNoCondition || singleAntenna {
+		t.Errorf("recorded frame: got %d %v %v, want no condition from both antennas", status, singleAntenna, ok)
+	}
+}
+
+// aircraftStatus builds a TC 28 message with the given subtype and 3-bit
+// emergency state, and squawk 7700 in the remaining bits
+func aircraftStatus(subtype, state byte) []byte {
+	data := []byte{0x8D, 0x48, 0x40, 0xD6, TC_STATUS<<3 | subtype, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	data[5] = state<<5 | 0x1F
+	data[6] = 0xFF
+	return data
+}
+
+func TestDecodeEmergency(t *testing.T) {
+	want := []string{"", "general", "lifeguard", "minfuel", "nordo", "unlawful", "downed", "reserved"}
+
+	for state, label := range want {
+		got, ok := DecodeEmergency(aircraftStatus(1, byte(state)))
+		if !ok || got != label {
+			t.Errorf("state %d: got %q (ok=%v), want %q", state, got, ok, label)
+		}
+	}
+
+	// TCAS RA broadcasts (subtype 2) and other type codes carry no emergency state
+	if _, ok := DecodeEmergency(aircraftStatus(2, 1)); ok {
+		t.Error("subtype 2 decoded as emergency status")
+	}
+	if _, ok := DecodeEmergency(positionWithAltitude(0x058)); ok {
+		t.Error("airborne position decoded as emergency status")
+	}
+	if _, ok := DecodeEmergency([]byte{0x8D, 0x48, 0x40, 0xD6, TC_STATUS<<3 | 1}); ok {
+		t.Error("truncated message decoded as emergency status")
+	}
+}
+
+func TestBearingDeg(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"north", 51.0, 0.0, 52.0, 0.0, 0},
+		{"south", 51.0, 0.0, 50.0, 0.0, 180},
+		{"east on the equator", 0.0, 10.0, 0.0, 11.0, 90},
+		{"west across the anti-meridian", 0.0, -179.5, 0.0, 179.5, 270},
+		{"LHR to JFK", 51.4700, -0.4543, 40.6413, -73.7781, 287.9},
+	}
+
+	for _, tt := range tests {
+		if got := BearingDeg(tt.lat1, tt.lon1, tt.lat2, tt.lon2); math.Abs(got-tt.want) > 0.1 {
+			t.Errorf("%s: BearingDeg = %.1f, want %.1f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStoreCPRPairsOppositeParity(t *testing.T) {
+	a := &Aircraft{}
+
+	if a.StoreCPR(100, 200, false, 1000) {
+		t.Error("lone even frame ready to decode")
+	}
+	if a.StoreCPR(101, 201, false, 2000) {
+		t.Error("two even frames ready to decode")
+	}
+	if a.EvenCPRLat != 101 || a.EvenCPRTime != 2000 {
+		t.Errorf("even slot holds %d at %d, want the latest frame", a.EvenCPRLat, a.EvenCPRTime)
+	}
+	if !a.StoreCPR(300, 400, true, 3000) {
+		t.Error("odd frame after even not ready to decode")
+	}
+
+	// An opposite frame older than the pair window is not used
+	if a.StoreCPR(102, 202, false, 3000+cprPairWindow+1) {
+		t.Error("even frame paired with a stale odd frame")
+	}
+	if a.CPRStatus != "stale pair" {
+		t.Errorf("CPRStatus = %q, want stale pair", a.CPRStatus)
+	}
+}
+
+func TestDecodeSquawk(t *testing.T) {
+	tests := []struct {
+		frame string
+		want  int
+	}{
+		{"2A00516D492B80", 356},                // DF5 identity reply
+		{"A800292DFFBBA9383FFCEB903D01", 1346}, // DF21 Comm-B identity reply
+	}
+
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.frame)
+		if got, ok := DecodeID13(data); !ok || got != tt.want {
+			t.Errorf("%s: squawk %04d (ok %v), want %04d", tt.frame, got, ok, tt.want)
+		}
+	}
+
+	// Other formats carry altitude in the same bits
+	data, _ := hex.DecodeString("A000029C85E42F313000007047D3")
+	if _, ok := DecodeID13(data); ok {
+		t.Error("DF20 altitude reply decoded as a squawk")
+	}
+
+	// Each code digit comes from its own bits
+	if got := modeACode(0x1FFF &^ 0x40); got != 7777 {
+		t.Errorf("all bits set = %04d, want 7777", got)
+	}
+	if got := modeACode(1<<7 | 1<<9 | 1<<11 | 1<<12 | 1<<10); got != 7030 {
+		t.Errorf("A4 A2 A1 C1 C2 = %04d, want 7030", got)
+	}
+}
+
+func TestDecodeGroundState(t *testing.T) {
+	tests := []struct {
+		name     string
+		frame    string
+		onGround bool
+		ok       bool
+	}{
+		{"airborne position, CA airborne", "8D40621D58C382D690C8AC2863A7", false, true},
+		{"airborne position, CA on ground", "8C40621D58C382D690C8AC2863A7", true, true},
+		{"airborne position, CA either", "8E40621D58C382D690C8AC2863A7", false, true},
+		{"identification, CA on ground", "8C4840D6202CC371C32CE0576098", true, true},
+		{"identification, CA airborne", "8D4840D6202CC371C32CE0576098", false, true},
+		{"identification, CA either", "8E4840D6202CC371C32CE0576098", false, false},
+		{"identification, CA downlink request", "8F4840D6202CC371C32CE0576098", false, false},
+		{"identification, level 1", "884840D6202CC371C32CE0576098", false, false},
+		{"surface position, CA airborne", "8D4841753A9A153237AEF0F275BE", true, true},
+		{"DF18 control field is not a capability", "944840D6202CC371C32CE0576098", false, false},
+		{"DF11 all-call", "5D4840D6A7D4DB", false, false},
+	}
+
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.frame)
+		onGround, ok := DecodeGroundState(data)
+		if onGround != tt.onGround || ok != tt.ok {
+			t.Errorf("%s: got (%v, %v), want (%v, %v)", tt.name, onGround, ok, tt.onGround, tt.ok)
+		}
+	}
+}
+
+func TestUpdateMessageRate(t *testing.T) {
+	var a Aircraft
+	start := time.Unix(1000, 0)
+
+	// The first update only takes a baseline
+	a.Messages = 100
+	a.UpdateMessageRate(start)
+	if a.MsgRate != 0 {
+		t.Fatalf("rate after the first update = %.2f, want 0", a.MsgRate)
+	}
+
+	// A steady 4 msg/s converges on 4
+	for i := 1; i <= 30; i++ {
+		a.Messages += 4
+		a.UpdateMessageRate(start.Add(time.Duration(i) * time.Second))
+	}
+	if math.Abs(a.MsgRate-4) > 0.05 {
+		t.Errorf("steady rate = %.2f, want 4", a.MsgRate)
+	}
+
+	// Silence decays it
+	a.UpdateMessageRate(start.Add(35 * time.Second))
+	if a.MsgRate > 2 {
+		t.Errorf("rate after 5 silent seconds = %.2f, want under half", a.MsgRate)
+	}
+
+	// Updates without time passing are ignored
+	rate := a.MsgRate
+	a.Messages += 50
+	a.UpdateMessageRate(start.Add(35 * time.Second))
+	if a.MsgRate != rate {
+		t.Errorf("rate changed to %.2f without time passing", a.MsgRate)
+	}
+}
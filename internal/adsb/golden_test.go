@@ -0,0 +1,139 @@
+package adsb
+
+import (
+	"math"
+	"testing"
+)
+
+// Known-good frames and decodes from "The 1090 Megahertz Riddle" (Junzi Sun),
+// which follows the worked examples in ICAO Doc 9871 and RTCA DO-260B
+
+// withSubtype returns a copy of a velocity frame with its subtype replaced,
+// used to build supersonic frames from subsonic ones
+func withSubtype(data []byte, subtype byte) []byte {
+	out := append([]byte(nil), data...)
+	out[4] = out[4]&0xF8 | subtype
+	return out
+}
+
+func TestGoldenIdentification(t *testing.T) {
+	tests := []struct {
+		frame    string
+		icao     uint32
+		callsign string
+	}{
+		{"8D4840D6202CC371C32CE0576098", 0x4840D6, "KLM1023"},
+		{"8D406B902015A678D4D220AA4BDA", 0x406B90, "EZY85MH"},
+	}
+
+	for _, tt := range tests {
+		data := mustDecodeHex(t, tt.frame)
+		if len(data) != FrameLength(int(data[0]>>3)) {
+			t.Errorf("%s: length %d does not match DF%d", tt.frame, len(data), data[0]>>3)
+		}
+		if icao := uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3]); icao != tt.icao {
+			t.Errorf("%s: ICAO %06X, want %06X", tt.frame, icao, tt.icao)
+		}
+		if tc := data[4] >> 3; tc < 1 || tc > 4 {
+			t.Errorf("%s: type code %d is not identification", tt.frame, tc)
+		}
+		if got := DecodeCallsign(data[5:11]); got != tt.callsign {
+			t.Errorf("%s: callsign %q, want %q", tt.frame, got, tt.callsign)
+		}
+	}
+}
+
+func TestCorruptIdentificationRejected(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame string
+	}{
+		// KLM1023 with the second character's bits hit, decoding "K?M1023"
+		{"reserved code mid callsign", "8D4840D6202DB371C32CE0576098"},
+		// Last character code 0, decoding "KLM102?"
+		{"reserved code at end", "8D4840D6202CC371C32C00576098"},
+		{"all reserved", "8D4840D620000000000000576098"},
+	}
+
+	for _, tt := range tests {
+		data := mustDecodeHex(t, tt.frame)
+		if got := DecodeCallsign(data[5:11]); got != "" {
+			t.Errorf("%s: callsign %q, want rejected", tt.name, got)
+		}
+	}
+
+	// Embedded spaces are valid characters
+	if got := DecodeCallsign([]byte{0x04, 0x28, 0x31, 0xCA, 0x08, 0x20}); got != "AB 12" {
+		t.Errorf("callsign with a space = %q, want \"AB 12\"", got)
+	}
+}
+
+func TestGoldenAirbornePosition(t *testing.T) {
+	even := mustDecodeHex(t, "8D40621D58C382D690C8AC2863A7")
+	odd := mustDecodeHex(t, "8D40621D58C386435CC412692AD6")
+
+	for _, data := range [][]byte{even, odd} {
+		if alt, ok := DecodeAltitude(data); !ok || alt != 38000 {
+			t.Errorf("%X: altitude %d (ok=%v), want 38000", data, alt, ok)
+		}
+	}
+
+	evenLat, evenLon, evenOdd := DecodeCPRFields(even)
+	oddLat, oddLon, oddOdd := DecodeCPRFields(odd)
+	if evenOdd || !oddOdd {
+		t.Fatalf("CPR format flags: even frame odd=%v, odd frame odd=%v", evenOdd, oddOdd)
+	}
+	if evenLat != 93000 || evenLon != 51372 || oddLat != 74158 || oddLon != 50194 {
+		t.Errorf("CPR fields = %d,%d / %d,%d, want 93000,51372 / 74158,50194",
+			evenLat, evenLon, oddLat, oddLon)
+	}
+
+	tests := []struct {
+		name     string
+		lastOdd  bool
+		lat, lon float64
+	}{
+		{"even newest", false, 52.25720, 3.91937},
+		{"odd newest", true, 52.26578, 3.93891},
+	}
+	for _, tt := range tests {
+		lat, lon, ok := DecodeCPRPosition(evenLat, evenLon, oddLat, oddLon, tt.lastOdd)
+		if !ok {
+			t.Errorf("%s: position not decoded", tt.name)
+			continue
+		}
+		if math.Abs(lat-tt.lat) > 1e-4 || math.Abs(lon-tt.lon) > 1e-4 {
+			t.Errorf("%s: position %.5f,%.5f, want %.5f,%.5f", tt.name, lat, lon, tt.lat, tt.lon)
+		}
+	}
+}
+
+func TestGoldenVelocity(t *testing.T) {
+	groundSpeed := mustDecodeHex(t, "8D485020994409940838175B284F")
+	airspeed := mustDecodeHex(t, "8DA05F219B06B6AF189400CBC33F")
+
+	tests := []struct {
+		name     string
+		data     []byte
+		speed    int
+		heading  int
+		vertRate int
+	}{
+		{"subtype 1 ground speed", groundSpeed, 159, 183, -832},
+		{"subtype 2 supersonic ground speed", withSubtype(groundSpeed, 2), 636, 183, -832},
+		{"subtype 3 airspeed", airspeed, 375, 243, -2304},
+		{"subtype 4 supersonic airspeed", withSubtype(airspeed, 4), 1500, 243, -2304},
+	}
+
+	for _, tt := range tests {
+		speed, heading, vertRate, ok := DecodeVelocity(tt.data)
+		if !ok {
+			t.Errorf("%s: not decoded", tt.name)
+			continue
+		}
+		if speed != tt.speed || heading != tt.heading || vertRate != tt.vertRate {
+			t.Errorf("%s: got %dkts %03d %dfpm, want %dkts %03d %dfpm", tt.name,
+				speed, heading, vertRate, tt.speed, tt.heading, tt.vertRate)
+		}
+	}
+}
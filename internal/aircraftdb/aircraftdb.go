@@ -0,0 +1,194 @@
+// Package aircraftdb looks up registration, type and operator by ICAO
+// address in a CSV aircraft database, such as a BaseStation.sqb export or
+// Mictronics' database converted to CSV
+package aircraftdb
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Entry describes an aircraft from the database
+type Entry struct {
+	Registration string
+	Type         string // ICAO type designator, e.g. "B738"
+	Operator     string
+}
+
+// columns are the indexes of the fields in a CSV row, -1 when absent
+type columns struct {
+	icao, registration, typ, operator int
+}
+
+// headerNames maps known header names, lower case, to the field they hold
+var headerNames = map[string]string{
+	"icao": "icao", "icao24": "icao", "hex": "icao", "modes": "icao",
+	"registration": "registration", "reg": "registration", "r": "registration",
+	"type": "type", "icaotypecode": "type", "typecode": "type", "t": "type",
+	"operator": "operator", "registeredowners": "operator", "owner": "operator", "operatorflagcode": "operator",
+}
+
+// defaultColumns is the layout of files without a header row
+var defaultColumns = columns{icao: 0, registration: 1, typ: 2, operator: 3}
+
+// DB looks aircraft up in a CSV file. Only the offset of each row is kept in
+// memory; rows are read from disk when an aircraft is first looked up and
+// the result cached.
+type DB struct {
+	mu      sync.Mutex
+	file    *os.File
+	cols    columns
+	offsets map[uint32]int64
+	cache   map[uint32]Entry
+}
+
+// Open indexes a CSV aircraft database. The first row is taken as a header
+// when it names an ICAO column, otherwise rows are read as
+// icao,registration,type,operator.
+func Open(path string) (*DB, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open aircraft database: %v", err)
+	}
+
+	db := &DB{
+		file:    file,
+		cols:    defaultColumns,
+		offsets: make(map[uint32]int64),
+		cache:   make(map[uint32]Entry),
+	}
+	if err := db.index(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read aircraft database %s: %v", path, err)
+	}
+	return db, nil
+}
+
+// index records the offset of every row by its ICAO address
+func (db *DB) index() error {
+	reader := bufio.NewReaderSize(db.file, 64*1024)
+	var offset int64
+	first := true
+
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			if first {
+				first = false
+				if cols, ok := parseHeader(line); ok {
+					db.cols = cols
+					offset += int64(len(line))
+					continue
+				}
+			}
+
+			// The address is in the row's leading fields, so only split
+			// as far as needed
+			fields := strings.SplitN(line, ",", db.cols.icao+2)
+			if db.cols.icao < len(fields) {
+				if icao, ok := parseICAO(fields[db.cols.icao]); ok {
+					db.offsets[icao] = offset
+				}
+			}
+			offset += int64(len(line))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseHeader returns the column layout named by a header row, ok false when
+// the row doesn't name an ICAO column
+func parseHeader(line string) (columns, bool) {
+	fields, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil {
+		return columns{}, false
+	}
+
+	cols := columns{icao: -1, registration: -1, typ: -1, operator: -1}
+	for i, name := range fields {
+		// Files saved from spreadsheets may start with a byte order mark
+		name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "\ufeff"))
+		switch headerNames[name] {
+		case "icao":
+			cols.icao = i
+		case "registration":
+			cols.registration = i
+		case "type":
+			cols.typ = i
+		case "operator":
+			if cols.operator < 0 {
+				cols.operator = i
+			}
+		}
+	}
+	return cols, cols.icao >= 0
+}
+
+// parseICAO parses a six digit hex address, possibly quoted
+func parseICAO(s string) (uint32, bool) {
+	s = strings.Trim(strings.TrimSpace(s), `"`)
+	if len(s) != 6 {
+		return 0, false
+	}
+	icao, err := strconv.ParseUint(s, 16, 32)
+	return uint32(icao), err == nil
+}
+
+// Len returns the number of aircraft in the database
+func (db *DB) Len() int {
+	return len(db.offsets)
+}
+
+// Lookup returns the entry for an ICAO address, ok false when the aircraft
+// isn't in the database or its row can't be read. A nil DB finds nothing.
+func (db *DB) Lookup(icao uint32) (Entry, bool) {
+	if db == nil {
+		return Entry{}, false
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if entry, ok := db.cache[icao]; ok {
+		return entry, true
+	}
+	offset, ok := db.offsets[icao]
+	if !ok {
+		return Entry{}, false
+	}
+
+	row, err := csv.NewReader(io.NewSectionReader(db.file, offset, 1<<16)).Read()
+	if err != nil {
+		return Entry{}, false
+	}
+
+	field := func(i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	entry := Entry{
+		Registration: field(db.cols.registration),
+		Type:         field(db.cols.typ),
+		Operator:     field(db.cols.operator),
+	}
+	db.cache[icao] = entry
+	return entry, true
+}
+
+// Close closes the database file
+func (db *DB) Close() error {
+	return db.file.Close()
+}
@@ -0,0 +1,69 @@
+package aircraftdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeDB(t *testing.T, contents string) *DB {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "aircraft.csv")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestLookupBaseStationExport(t *testing.T) {
+	db := writeDB(t, "ModeS,Registration,ICAOTypeCode,RegisteredOwners\r\n"+
+		"4CA87C,EI-DEI,A320,Aer Lingus\r\n"+
+		"40621D,PH-BXA,B738,\"KLM, Royal Dutch Airlines\"\r\n")
+
+	if db.Len() != 2 {
+		t.Errorf("Len = %d, want 2", db.Len())
+	}
+
+	entry, ok := db.Lookup(0x40621D)
+	if !ok {
+		t.Fatal("40621D not found")
+	}
+	want := Entry{Registration: "PH-BXA", Type: "B738", Operator: "KLM, Royal Dutch Airlines"}
+	if entry != want {
+		t.Errorf("entry = %+v, want %+v", entry, want)
+	}
+
+	// A second lookup comes from the cache
+	if again, ok := db.Lookup(0x40621D); !ok || again != want {
+		t.Errorf("cached entry = %+v", again)
+	}
+
+	if _, ok := db.Lookup(0xABCDEF); ok {
+		t.Error("missing aircraft found")
+	}
+}
+
+func TestLookupWithoutHeader(t *testing.T) {
+	db := writeDB(t, "a1b2c3,N12345,C172,\n4ca87c,EI-DEI,A320,Aer Lingus")
+
+	entry, ok := db.Lookup(0x4CA87C)
+	if !ok || entry.Registration != "EI-DEI" || entry.Type != "A320" || entry.Operator != "Aer Lingus" {
+		t.Errorf("entry = %+v, %v", entry, ok)
+	}
+	if entry, ok := db.Lookup(0xA1B2C3); !ok || entry.Operator != "" {
+		t.Errorf("entry without operator = %+v, %v", entry, ok)
+	}
+}
+
+func TestNilDBFindsNothing(t *testing.T) {
+	var db *DB
+	if _, ok := db.Lookup(0x4CA87C); ok {
+		t.Error("nil database found an aircraft")
+	}
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+// Aircraft is the JSON form of a tracked aircraft
+type Aircraft struct {
+	Hex       string   `json:"hex"`
+	Type      string   `json:"type"`
+	Flight    string   `json:"flight,omitempty"`
+	Altitude  *int     `json:"alt_baro,omitempty"`
+	Lat       *float64 `json:"lat,omitempty"`
+	Lon       *float64 `json:"lon,omitempty"`
+	Speed     int      `json:"gs"`
+	Track     int      `json:"track"`
+	VertRate  int      `json:"baro_rate"`
+	OnGround  bool     `json:"on_ground"`
+	Emergency string   `json:"emergency,omitempty"`
+	Messages  int      `json:"messages"`
+	SeenAt    float64  `json:"seen_at"`               // Unix time of the last message
+	SeenPosAt *float64 `json:"seen_pos_at,omitempty"` // Unix time of the last position
+}
+
+// document is a JSON body with its cache validators
+type document struct {
+	body     []byte
+	etag     string
+	modified time.Time
+}
+
+// Server serves the aircraft snapshot taken by the last Update over HTTP
+type Server struct {
+	addr     string
+	listener net.Listener
+	server   *http.Server
+	done     chan struct{} // Closed when Serve returns
+
+	all      document            // GET /aircraft
+	aircraft map[string]document // GET /aircraft/{icao}, keyed by upper case hex
+	mutex    sync.RWMutex
+}
+
+// NewServer creates an API server that will listen on addr
+func NewServer(addr string) *Server {
+	s := &Server{
+		addr:     addr,
+		aircraft: make(map[string]document),
+	}
+	s.all = newDocument([]byte("[]\n"), nil)
+	return s
+}
+
+// Handler returns the HTTP handler for the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /aircraft", s.handleAll)
+	mux.HandleFunc("GET /aircraft/{icao}", s.handleOne)
+	return mux
+}
+
+// Start listens on the configured address and serves in the background until
+// ctx is cancelled or Stop is called
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", s.addr, err)
+	}
+	s.listener = listener
+	s.server = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 5 * time.Second}
+
+	s.done = make(chan struct{})
+	stop := context.AfterFunc(ctx, func() { s.server.Close() })
+	go func() {
+		defer close(s.done)
+		defer stop()
+		s.server.Serve(listener)
+	}()
+	return nil
+}
+
+// Addr returns the address the server is listening on
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return s.addr
+	}
+	return s.listener.Addr().String()
+}
+
+// Stop shuts the server down and waits for it to stop serving
+func (s *Server) Stop() {
+	if s.server == nil {
+		return
+	}
+	s.server.Close()
+	<-s.done
+}
+
+// Update replaces the served snapshot. Encoding happens here so requests
+// never touch the live aircraft map. Times are absolute rather than ages, so
+// the body and its ETag only change when an aircraft does.
+func (s *Server) Update(aircraft map[uint32]*adsb.Aircraft) {
+	list := make([]Aircraft, 0, len(aircraft))
+	for _, a := range aircraft {
+		list = append(list, toJSON(a))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Hex < list[j].Hex })
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	all, _ := json.Marshal(list)
+	s.all = newDocument(append(all, '\n'), &s.all)
+
+	docs := make(map[string]document, len(list))
+	for _, a := range list {
+		body, _ := json.Marshal(a)
+		var prev *document
+		if d, ok := s.aircraft[a.Hex]; ok {
+			prev = &d
+		}
+		docs[a.Hex] = newDocument(append(body, '\n'), prev)
+	}
+	s.aircraft = docs
+}
+
+// toJSON converts an aircraft to its JSON form
+func toJSON(a *adsb.Aircraft) Aircraft {
+	out := Aircraft{
+		Hex:       adsb.FormatAddress(a.ICAO),
+		Type:      a.AddrType.String(),
+		Flight:    a.Flight,
+		Speed:     a.Speed,
+		Track:     a.Heading,
+		VertRate:  a.VertRate,
+		OnGround:  a.OnGround,
+		Emergency: a.Emergency,
+		Messages:  a.Messages,
+		SeenAt:    unixSeconds(a.Seen),
+	}
+	if a.HasAltitude {
+		alt := a.Altitude
+		out.Altitude = &alt
+	}
+	if a.HasPosition {
+		lat, lon := a.Lat, a.Lon
+		seenPos := unixSeconds(a.SeenLatLon)
+		out.Lat, out.Lon, out.SeenPosAt = &lat, &lon, &seenPos
+	}
+	return out
+}
+
+// unixSeconds returns t as Unix time in seconds, to the millisecond
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixMilli()) / 1000
+}
+
+// newDocument wraps body with an ETag, keeping the previous modification
+// time when the body is unchanged
+func newDocument(body []byte, prev *document) document {
+	h := fnv.New64a()
+	h.Write(body)
+	doc := document{
+		body:     body,
+		etag:     `"` + strconv.FormatUint(h.Sum64(), 16) + `"`,
+		modified: time.Now(),
+	}
+	if prev != nil && prev.etag == doc.etag {
+		doc.modified = prev.modified
+	}
+	return doc
+}
+
+// handleAll serves every tracked aircraft
+func (s *Server) handleAll(w http.ResponseWriter, r *http.Request) {
+	s.mutex.RLock()
+	doc := s.all
+	s.mutex.RUnlock()
+
+	serveDocument(w, r, doc)
+}
+
+// handleOne serves a single aircraft by hex address, or by track file number
+// with a "~" prefix
+func (s *Server) handleOne(w http.ResponseWriter, r *http.Request) {
+	hex, trackFile := strings.CutPrefix(r.PathValue("icao"), "~")
+	icao, err := strconv.ParseUint(hex, 16, 24)
+	if err != nil {
+		http.Error(w, "invalid ICAO address", http.StatusBadRequest)
+		return
+	}
+	key := uint32(icao)
+	if trackFile {
+		key |= adsb.TrackFileFlag
+	}
+
+	s.mutex.RLock()
+	doc, ok := s.aircraft[adsb.FormatAddress(key)]
+	s.mutex.RUnlock()
+
+	if !ok {
+		http.Error(w, "aircraft not found", http.StatusNotFound)
+		return
+	}
+	serveDocument(w, r, doc)
+}
+
+// serveDocument writes a JSON document, answering conditional requests with
+// 304 Not Modified
+func serveDocument(w http.ResponseWriter, r *http.Request, doc document) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", doc.etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, "", doc.modified, bytes.NewReader(doc.body))
+}
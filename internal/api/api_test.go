@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+func TestAircraftEndpoints(t *testing.T) {
+	am := adsb.NewAircraftMap(0)
+	a := am.GetOrCreate(0x4CA87C)
+	a.Flight = "RYR123"
+	a.Altitude = 36000
+	a.HasAltitude = true
+
+	s := NewServer("")
+	s.Update(am.Copy())
+	h := s.Handler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/aircraft", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /aircraft: status %d", rec.Code)
+	}
+	var list []Aircraft
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("GET /aircraft: %v", err)
+	}
+	if len(list) != 1 || list[0].Hex != "4CA87C" || list[0].Altitude == nil || *list[0].Altitude != 36000 {
+		t.Errorf("GET /aircraft = %s", rec.Body.String())
+	}
+	if list[0].Lat != nil {
+		t.Error("aircraft without a position reported a latitude")
+	}
+
+	// A matching ETag gets a 304
+	req := httptest.NewRequest("GET", "/aircraft", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("conditional GET /aircraft: status %d, want 304", rec.Code)
+	}
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/aircraft/4ca87c", http.StatusOK},
+		{"/aircraft/000001", http.StatusNotFound},
+		{"/aircraft/zzz", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("GET %s: status %d, want %d", tt.path, rec.Code, tt.code)
+		}
+	}
+}
+
+func TestUnchangedSnapshotNotModified(t *testing.T) {
+	am := adsb.NewAircraftMap(0)
+	a := am.GetOrCreate(0x4CA87C)
+	a.Lat, a.Lon, a.HasPosition = 53.35, -6.26, true
+	a.Seen = time.Now()
+	a.SeenLatLon = a.Seen
+
+	s := NewServer("")
+	h := s.Handler()
+	s.Update(am.Copy())
+
+	etags := map[string]string{}
+	for _, path := range []string{"/aircraft", "/aircraft/4CA87C"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		etags[path] = rec.Header().Get("ETag")
+	}
+
+	// A second later nothing new has been heard from the aircraft
+	time.Sleep(time.Second)
+	s.Update(am.Copy())
+
+	for path, etag := range etags {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("If-None-Match", etag)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("conditional GET %s after an unchanged update: status %d, want 304", path, rec.Code)
+		}
+	}
+
+	// A new message changes the document
+	a.Seen = a.Seen.Add(time.Second)
+	s.Update(am.Copy())
+	req := httptest.NewRequest("GET", "/aircraft", nil)
+	req.Header.Set("If-None-Match", etags["/aircraft"])
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("conditional GET /aircraft after a new message: status %d, want 200", rec.Code)
+	}
+}
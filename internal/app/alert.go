@@ -0,0 +1,98 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
+)
+
+// newAlerter picks the aircraft to announce as new. An aircraft is new when
+// it matches the alert filter after not matching for at least debounce, so a
+// flapping signal or one hovering at the edge of the band alerts only once.
+// With a zero debounce an aircraft alerts once each time it starts matching.
+type newAlerter struct {
+	debounce  time.Duration
+	lastMatch map[uint32]time.Time
+}
+
+// check records the aircraft matching at now and returns those that are new
+func (n *newAlerter) check(now time.Time, matching []uint32) []uint32 {
+	if n.lastMatch == nil {
+		n.lastMatch = make(map[uint32]time.Time)
+	}
+
+	var fresh []uint32
+	for _, icao := range matching {
+		// Without a debounce, aircraft that stopped matching are already forgotten
+		last, ok := n.lastMatch[icao]
+		if !ok || (n.debounce > 0 && now.Sub(last) > n.debounce) {
+			fresh = append(fresh, icao)
+		}
+		n.lastMatch[icao] = now
+	}
+
+	// Forget aircraft gone for longer than the debounce period
+	for icao, last := range n.lastMatch {
+		if now.Sub(last) > n.debounce {
+			delete(n.lastMatch, icao)
+		}
+	}
+	return fresh
+}
+
+// alertMatches reports whether an aircraft passes the new aircraft alert
+// filters. Distance and altitude limits need a position or altitude.
+func (a *App) alertMatches(icao uint32, aircraft *adsb.Aircraft) bool {
+	if a.config.AlertWatchlistOnly {
+		if _, ok := a.watchlist.Match(icao, aircraft.Flight); !ok {
+			return false
+		}
+	}
+	if a.config.AlertMaxNM > 0 {
+		if !aircraft.HasPosition ||
+			adsb.DistanceNM(a.config.InitialLat, a.config.InitialLon, aircraft.Lat, aircraft.Lon) > a.config.AlertMaxNM {
+			return false
+		}
+	}
+	if a.config.AlertMinFeet > 0 || a.config.AlertMaxFeet > 0 {
+		if !aircraft.HasAltitude ||
+			(a.config.AlertMinFeet > 0 && aircraft.Altitude < a.config.AlertMinFeet) ||
+			(a.config.AlertMaxFeet > 0 && aircraft.Altitude > a.config.AlertMaxFeet) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkNewAircraft logs, flashes and rings the bell for newly matching aircraft
+func (a *App) checkNewAircraft() {
+	if !a.config.AlertOnNew {
+		return
+	}
+
+	var matching []uint32
+	flights := make(map[uint32]string)
+	a.aircraft.ForEach(func(icao uint32, aircraft *adsb.Aircraft) {
+		if a.alertMatches(icao, aircraft) {
+			matching = append(matching, icao)
+			flights[icao] = aircraft.Flight
+		}
+	})
+
+	fresh := a.newAlerter.check(time.Now(), matching)
+	if len(fresh) == 0 {
+		return
+	}
+	for _, icao := range fresh {
+		eventlog.Printf("New aircraft: %s %s\n", adsb.FormatAddress(icao), flights[icao])
+	}
+
+	if a.vizRenderer != nil {
+		a.vizRenderer.Flash()
+	}
+	if a.config.AlertBell {
+		fmt.Fprint(eventlog.Output(), "\a")
+	}
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewAlerterDebounce(t *testing.T) {
+	n := newAlerter{debounce: time.Minute}
+	start := time.Now()
+
+	check := func(offset time.Duration, matching ...uint32) []uint32 {
+		return n.check(start.Add(offset), matching)
+	}
+
+	if got := check(0, 0xA); len(got) != 1 || got[0] != 0xA {
+		t.Fatalf("first sighting alerted %v, want [A]", got)
+	}
+	if got := check(time.Second, 0xA, 0xB); len(got) != 1 || got[0] != 0xB {
+		t.Fatalf("second check alerted %v, want [B]", got)
+	}
+
+	// A brief dropout doesn't alert again
+	check(2 * time.Second)
+	if got := check(30*time.Second, 0xA); len(got) != 0 {
+		t.Errorf("flapping aircraft alerted again: %v", got)
+	}
+
+	// Gone for longer than the debounce period, it counts as new
+	if got := check(2*time.Minute, 0xA); len(got) != 1 {
+		t.Errorf("returning aircraft alerted %v, want [A]", got)
+	}
+	if len(n.lastMatch) != 1 {
+		t.Errorf("tracking %d aircraft, want stale ones forgotten", len(n.lastMatch))
+	}
+}
+
+func TestNewAlerterZeroDebounce(t *testing.T) {
+	n := newAlerter{}
+	start := time.Now()
+
+	check := func(offset time.Duration, matching ...uint32) []uint32 {
+		return n.check(start.Add(offset), matching)
+	}
+
+	if got := check(0, 0xA); len(got) != 1 {
+		t.Fatalf("first sighting alerted %v, want [A]", got)
+	}
+
+	// Still matching on later checks, however far apart
+	for i := 1; i <= 3; i++ {
+		if got := check(time.Duration(i)*time.Minute, 0xA); len(got) != 0 {
+			t.Fatalf("check %d alerted %v again", i, got)
+		}
+	}
+
+	// Out of the filter for a single check, then back
+	check(4 * time.Minute)
+	if got := check(5*time.Minute, 0xA); len(got) != 1 {
+		t.Errorf("returning aircraft alerted %v, want [A]", got)
+	}
+}
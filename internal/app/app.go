@@ -1,143 +1,379 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"math"
 	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/OJPARKINSON/viz1090/internal/adsb"
+	"github.com/OJPARKINSON/viz1090/internal/aircraftdb"
+	"github.com/OJPARKINSON/viz1090/internal/api"
 	"github.com/OJPARKINSON/viz1090/internal/beast"
 	"github.com/OJPARKINSON/viz1090/internal/config"
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
+	"github.com/OJPARKINSON/viz1090/internal/map_system"
+	"github.com/OJPARKINSON/viz1090/internal/sim"
 	"github.com/OJPARKINSON/viz1090/internal/viz"
+	"github.com/OJPARKINSON/viz1090/internal/watchlist"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
 // App represents the main application
 type App struct {
-	config       *config.Config
-	aircraft     *adsb.AircraftMap
-	selectedICAO uint32
-	centerLat    float64
-	centerLon    float64
-	maxDistance  float64
+	config        *config.Config
+	aircraft      *adsb.AircraftMap
+	selectedICAO  uint32
+	initialSelect initialSelect // Aircraft to select once it appears
+	clickCycle    clickCycle    // Candidates under the last click, for cycling through stacks
+	measuring     bool          // Clicks place measuring tool points instead of selecting
+	measure       measurement   // Points placed with the measuring tool
+	viewMode      viewMode      // What the map is centered on
+	centerLat     float64
+	centerLon     float64
+	maxDistance   float64
+	projection    map_system.ProjectionKind // Map projection, the renderer warns about unknown names
 
 	vizRenderer *viz.Renderer
-	running     bool
-
-	beastConn               net.Conn
-	isConnected             bool
+	demoServer  *sim.BeastServer
+	apiServer   *api.Server
+	watchlist   *watchlist.List[config.WatchEntry]
+	watchSeen   map[uint32]bool // Watched aircraft already announced
+	jsonl       *jsonlWriter    // Decoded message output, nil when disabled
+	recorder    *recorder       // Raw feed recording, nil when disabled
+	aircraftDB  *aircraftdb.DB  // Registration and type lookup, nil when disabled
+	newAlerter  newAlerter
+
+	// Background goroutines run under ctx and are awaited by shutdown
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	connected               atomic.Bool        // A receiver goroutine owns a live Beast connection
+	connecting              atomic.Bool        // A connectToBeast call is dialing
+	decodeTime              atomic.Int64       // Nanoseconds spent in processModeS since the last frame
+	dropConn                context.CancelFunc // Closes the current connection
+	connectedAt             time.Time
+	connMutex               sync.Mutex // Guards dropConn and connectedAt
+	lastData                time.Time  // Last statistics interval with messages
+	noData                  bool       // No messages within the data timeout
 	connectionRetryInterval time.Duration
 	lastFrameTime           time.Time
+	frameTimer              frameTimer
 	lastCleanup             time.Time
 
 	mutex sync.RWMutex
 
+	// Live pause: messages are buffered instead of applied
+	paused       bool
+	replaying    bool // Buffered messages are being applied after a pause
+	pauseBuffer  []pausedMessage
+	pauseDropped int
+	pauseMutex   sync.Mutex
+
 	// Statistics
 	numVisiblePlanes int
 	numPlanes        int
 	msgRate          float64
 	msgRateAcc       float64
+	shortFrames      int // Frames dropped for not matching their DF's length
 	sigAvg           float64
 	sigAcc           float64
 }
 
 // New creates a new application instance
 func New(cfg *config.Config) *App {
+	ctx, cancel := context.WithCancel(context.Background())
+	projection, _ := map_system.ParseProjection(cfg.Projection)
 	return &App{
+		ctx:                     ctx,
+		cancel:                  cancel,
 		config:                  cfg,
-		aircraft:                adsb.NewAircraftMap(),
+		aircraft:                newAircraftMap(cfg),
 		centerLat:               cfg.InitialLat,
 		centerLon:               cfg.InitialLon,
-		maxDistance:             cfg.InitialZoom,
-		running:                 false,
+		maxDistance:             clampZoom(cfg.InitialZoom, cfg.MinZoom, cfg.MaxZoom),
+		projection:              projection,
+		initialSelect:           newInitialSelect(cfg.InitialSelect),
 		lastCleanup:             time.Now(),
 		lastFrameTime:           time.Now(),
+		lastData:                time.Now(),
+		newAlerter:              newAlerter{debounce: time.Duration(cfg.AlertDebounceSeconds) * time.Second},
 		connectionRetryInterval: 5 * time.Second,
 	}
 }
 
+// newAircraftMap creates the aircraft map with the configured trail length
+// and tracking limit
+func newAircraftMap(cfg *config.Config) *adsb.AircraftMap {
+	am := adsb.NewAircraftMap(cfg.TrailLength)
+	am.SetLimit(cfg.MaxAircraft)
+	return am
+}
+
 // Initialize sets up the application
 func (a *App) Initialize() error {
 	var err error
 
+	// Center on the receiver when it can tell us where it is
+	if a.config.ReceiverURL != "" {
+		if lat, lon, err := loadReceiverLocation(a.config.ReceiverURL); err != nil {
+			eventlog.Printf("Warning: %v, using the configured location\n", err)
+		} else {
+			a.config.InitialLat, a.config.InitialLon = lat, lon
+			a.centerLat, a.centerLon = lat, lon
+		}
+	}
+
+	// Merge file entries into the watchlist before anything matches against it
+	if a.config.WatchlistFile != "" {
+		if entries, err := watchlist.Load(a.config.WatchlistFile); err != nil {
+			eventlog.Printf("Warning: %v\n", err)
+		} else {
+			if a.config.Watchlist == nil {
+				a.config.Watchlist = make(map[string]config.WatchEntry)
+			}
+			for key, entry := range entries {
+				if _, ok := a.config.Watchlist[key]; !ok {
+					a.config.Watchlist[key] = entry
+				}
+			}
+		}
+	}
+	a.watchlist = watchlist.New(a.config.Watchlist)
+	a.watchSeen = make(map[uint32]bool)
+	if a.initialSelect.armed {
+		eventlog.Printf("Waiting for %s to select it\n", a.initialSelect.flight)
+	}
+
+	if a.config.AircraftDB != "" {
+		if a.aircraftDB, err = aircraftdb.Open(a.config.AircraftDB); err != nil {
+			eventlog.Printf("Warning: %v\n", err)
+		} else {
+			eventlog.Printf("Loaded %d aircraft from %s\n", a.aircraftDB.Len(), a.config.AircraftDB)
+		}
+	}
+
+	if a.config.JSONL != "" {
+		if a.jsonl, err = openJSONL(a.config.JSONL); err != nil {
+			return err
+		}
+	}
+	if a.config.RecordFile != "" {
+		if a.recorder, err = openRecorder(a.config.RecordFile); err != nil {
+			return err
+		}
+	}
+
 	// Create visualization renderer
-	a.vizRenderer, err = viz.NewRenderer(a.config.ScreenWidth, a.config.ScreenHeight,
-		a.config.UIScale, a.config.Metric)
+	if !a.config.Headless {
+		a.vizRenderer, err = viz.NewRenderer(a.config)
+		if err != nil {
+			return fmt.Errorf("failed to create renderer: %v", err)
+		}
+	}
+
+	if a.config.Demo {
+		if err := a.startDemo(); err != nil {
+			return fmt.Errorf("failed to start demo: %v", err)
+		}
+	}
+
+	if a.config.APIAddr != "" {
+		a.apiServer = api.NewServer(a.config.APIAddr)
+		if err := a.apiServer.Start(a.ctx); err != nil {
+			return fmt.Errorf("failed to start API server: %v", err)
+		}
+		eventlog.Printf("Serving aircraft API on http://%s/aircraft\n", a.apiServer.Addr())
+	}
+
+	return nil
+}
+
+// startDemo runs the simulator in-process on a loopback port and points the
+// Beast connection at it, so the full decode pipeline runs without a receiver
+func (a *App) startDemo() error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
-		return fmt.Errorf("failed to create renderer: %v", err)
+		return err
 	}
 
+	a.demoServer = sim.NewBeastServer()
+	a.demoServer.AddSampleAircraft(a.config.InitialLat, a.config.InitialLon)
+	a.spawn(func() { a.demoServer.Serve(a.ctx, listener) })
+
+	addr := listener.Addr().(*net.TCPAddr)
+	a.config.ServerAddress = addr.IP.String()
+	a.config.ServerPort = addr.Port
+
+	eventlog.Printf("Demo mode: simulator running on %s\n", addr)
 	return nil
 }
 
-// connectToBeast attempts to connect to a Beast data server
-func (a *App) connectToBeast() {
-	if a.isConnected {
+// spawn runs f in a goroutine that shutdown waits for
+func (a *App) spawn(f func()) {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		f()
+	}()
+}
+
+// shutdown cancels background work and waits for it to finish. It is safe to
+// call more than once.
+func (a *App) shutdown() {
+	a.cancel()
+	a.wg.Wait()
+
+	if a.apiServer != nil {
+		a.apiServer.Stop()
+		a.apiServer = nil
+	}
+	if a.jsonl != nil {
+		a.jsonl.Close()
+		a.jsonl = nil
+	}
+	if a.recorder != nil {
+		a.recorder.Close()
+		a.recorder = nil
+	}
+	if a.aircraftDB != nil {
+		a.aircraftDB.Close()
+		a.aircraftDB = nil
+	}
+}
+
+// connectToBeast attempts to connect to a Beast data server. Only one call
+// dials at a time, and none while a connection is live.
+func (a *App) connectToBeast(ctx context.Context) {
+	if a.connected.Load() || !a.connecting.CompareAndSwap(false, true) {
 		return
 	}
+	// connected is set before this runs on success, so there's no gap for
+	// another call to dial
+	defer a.connecting.Store(false)
 
-	addr := fmt.Sprintf("%s:%d", a.config.ServerAddress, a.config.ServerPort)
-	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	addr := net.JoinHostPort(a.config.ServerAddress, strconv.Itoa(a.config.ServerPort))
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
 	if err != nil {
-		fmt.Printf("Failed to connect to Beast server: %v (retrying in %v)\n",
+		if ctx.Err() != nil {
+			return // Shutting down
+		}
+		eventlog.Printf("Failed to connect to Beast server: %v (retrying in %v)\n",
 			err, a.connectionRetryInterval)
-		a.isConnected = false
 		return
 	}
 
-	a.beastConn = conn
-	a.isConnected = true
+	// Configure the receiver before reading, a failure here isn't fatal as
+	// plain dump1090 outputs binary Beast regardless
+	if a.config.BeastSettings != "" {
+		if _, err := conn.Write(beast.EncodeSettings(a.config.BeastSettings)); err != nil {
+			eventlog.Printf("Warning: Failed to send Beast settings: %v\n", err)
+		}
+	}
+
+	connCtx, cancel := context.WithCancel(ctx)
+	a.connMutex.Lock()
+	a.dropConn = cancel
+	a.connectedAt = time.Now()
+	a.connMutex.Unlock()
+	a.connected.Store(true)
 
 	// Start receiver goroutine
-	go a.receiveBeastData()
-	fmt.Printf("Connected to Beast server at %s\n", addr)
+	a.spawn(func() {
+		a.receiveBeastData(connCtx, conn)
+		cancel()
+	})
+	eventlog.Printf("Connected to Beast server at %s\n", addr)
 }
 
-// receiveBeastData receives and processes Beast protocol data
-func (a *App) receiveBeastData() {
-	decoder := beast.NewDecoder(a.beastConn)
+// receiveBeastData receives and processes Beast protocol data from conn
+// until it fails or ctx is cancelled
+func (a *App) receiveBeastData(ctx context.Context, conn net.Conn) {
+	// Closing the connection unblocks the read on shutdown
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	// Record the stream exactly as received
+	var src io.Reader = conn
+	if a.recorder != nil {
+		src = io.TeeReader(conn, a.recorder)
+	}
+	decoder := beast.NewDecoder(src)
 
-	for a.running {
+	for {
 		// Try to read a message
 		msg, err := decoder.ReadMessage()
 		if err != nil {
-			if a.running {
-				fmt.Printf("Beast protocol error: %v\n", err)
-				a.isConnected = false
-
-				// Close the connection
-				if a.beastConn != nil {
-					a.beastConn.Close()
-					a.beastConn = nil
-				}
-
-				return
+			if ctx.Err() == nil {
+				eventlog.Printf("Beast protocol error: %v\n", err)
 			}
-			break
+			a.connected.Store(false)
+			conn.Close()
+			return
 		}
 
 		// Process the message if it's a Mode S message
-		if msg.Type == beast.ModeLong {
-			a.processModeS(msg.Data, msg.Timestamp)
+		if msg.Type == beast.ModeShort || msg.Type == beast.ModeLong {
+			a.handleMessage(msg)
 		}
 	}
 }
 
-// processModeS decodes and handles a Mode S message
-func (a *App) processModeS(data []byte, timestamp uint64) {
-	// Skip processing if data is too short
-	if len(data) < 4 {
+// processModeS decodes and handles a Mode S message received at the given
+// Beast signal level. Aircraft state is stamped with the receive time, which
+// is in the past for messages held while paused.
+func (a *App) processModeS(data []byte, timestamp uint64, signal byte, received time.Time) {
+	if len(data) == 0 {
 		return
 	}
 
+	start := time.Now()
+	defer func() { a.decodeTime.Add(int64(time.Since(start))) }()
+
 	// Extract downlink format (DF)
 	df := data[0] >> 3
 
+	// Drop truncated or mismatched frames before any fields are read
+	if len(data) != adsb.FrameLength(int(df)) {
+		a.shortFrames++
+		return
+	}
+
+	// Write the message out once processing has filled in what it decoded
+	var rec *jsonlRecord
+	if a.jsonl != nil {
+		rec = describeMessage(data, received)
+		defer a.jsonl.write(rec)
+	}
+
+	// Surveillance and air-air altitude replies only carry altitude
+	if df == adsb.DF0 || df == adsb.DF4 || df == adsb.DF16 {
+		a.processAltitudeReply(data, received)
+		return
+	}
+
+	// Identity replies only carry the squawk
+	if df == adsb.DF5 {
+		a.processIdentityReply(data, received)
+		return
+	}
+
+	// Comm-B replies carry BDS registers for aircraft we already track
+	if df == adsb.DF20 || df == adsb.DF21 {
+		a.processCommB(data, received)
+		return
+	}
+
 	// Only process DF17 and DF18 (ADS-B messages) for simplicity
 	if df != 17 && df != 18 {
 		return
@@ -146,17 +382,50 @@ func (a *App) processModeS(data []byte, timestamp uint64) {
 	// Extract ICAO address from bytes 1-3
 	icao := uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
 
+	// TIS-B track file numbers are not ICAO addresses, so keep them apart
+	// from real aircraft or drop them
+	addrType := adsb.DecodeAddrType(data)
+	if addrType == adsb.AddrTISBTrackfile {
+		if !a.config.TrackTISBTrackFiles {
+			return
+		}
+		icao |= adsb.TrackFileFlag
+	}
+
 	// Create a basic message structure
 	mm := &adsb.Message{
 		DF:          int(df),
 		ICAO:        icao,
-		Timestamp:   time.Now(),
-		SignalLevel: 100, // Default signal level
+		Timestamp:   received,
+		SignalLevel: signal,
 	}
 
 	// Get or create aircraft entry
 	aircraft := a.aircraft.GetOrCreate(icao)
 
+	// Look new aircraft up in the database, which track file numbers aren't in
+	if aircraft.Messages == 0 && icao&adsb.TrackFileFlag == 0 {
+		if entry, ok := a.aircraftDB.Lookup(icao); ok {
+			aircraft.Registration = entry.Registration
+			aircraft.TypeCode = entry.Type
+			aircraft.Operator = entry.Operator
+		}
+	}
+
+	// Only some messages carry the IMF bit, so once an address is known to
+	// be anonymous keep it that way
+	if !aircraft.AddrType.Anonymous() {
+		aircraft.AddrType = addrType
+	}
+
+	if onGround, ok := adsb.DecodeGroundState(data); ok {
+		// Landing ends the trail, so takeoff starts a fresh one
+		if onGround && !aircraft.OnGround && a.config.AirborneTrailsOnly {
+			aircraft.Trail = nil
+		}
+		aircraft.OnGround = onGround
+	}
+
 	// Process based on message type
 	if len(data) >= 5 {
 		// Extended squitter message type
@@ -164,71 +433,110 @@ func (a *App) processModeS(data []byte, timestamp uint64) {
 
 		if metype >= 1 && metype <= 4 {
 			// Aircraft identification
+			aircraft.SeenTypes |= adsb.SeenIdent
 			callsign := adsb.DecodeCallsign(data[5:11])
 			if callsign != "" {
 				aircraft.Flight = callsign
 			}
+		} else if metype >= 5 && metype <= 8 {
+			// Surface position
+			aircraft.SeenTypes |= adsb.SeenSurface
+
+			speed, track, ok := adsb.DecodeSurfaceMovement(data)
+			if ok {
+				aircraft.Speed = speed
+			}
+			aircraft.HasGroundTrack = track >= 0
+			if track >= 0 {
+				aircraft.GroundTrack = track % 360
+				a.updateDirection(aircraft, adsb.Velocity{Track: float64(track), HasTrack: true}, received)
+			}
 		} else if metype >= 9 && metype <= 18 {
 			// Airborne position
-			alt := adsb.DecodeAltitude(data)
-			if alt != 0 {
+			aircraft.SeenTypes |= adsb.SeenPosition
+			if alt, ok := adsb.DecodeAltitude(data); ok {
 				aircraft.Altitude = alt
+				aircraft.HasAltitude = true
+			}
+			if status, singleAntenna, ok := adsb.DecodeSurveillanceStatus(data); ok {
+				aircraft.Alert = status == adsb.SSPermanentAlert || status == adsb.SSTemporaryAlert
+				aircraft.SPI = status == adsb.SSIdent
+				if aircraft.SPI {
+					aircraft.SeenSPI = received
+				}
+				aircraft.SingleAntenna = singleAntenna
 			}
 
-			// Extract CPR position
-			cprLat := ((uint32(data[6]) & 0x03) << 15) | (uint32(data[7]) << 7) | (uint32(data[8]) >> 1)
-			cprLon := ((uint32(data[8]) & 0x01) << 16) | (uint32(data[9]) << 8) | uint32(data[10])
-			odd := (data[6] & 0x04) != 0
-
-			// Store CPR position
-			if odd {
-				aircraft.OddCPRLat = int(cprLat)
-				aircraft.OddCPRLon = int(cprLon)
-				aircraft.OddCPRTime = time.Now().UnixNano() / int64(time.Millisecond)
-			} else {
-				aircraft.EvenCPRLat = int(cprLat)
-				aircraft.EvenCPRLon = int(cprLon)
-				aircraft.EvenCPRTime = time.Now().UnixNano() / int64(time.Millisecond)
+			// Position accuracy, preferring the operational status NACp when known
+			aircraft.Accuracy = adsb.NICtoRadius(int(metype))
+			if aircraft.NACp > 0 {
+				aircraft.Accuracy = adsb.NACpToRadius(aircraft.NACp)
 			}
 
-			// Try to decode position if we have both odd and even
-			if aircraft.EvenCPRTime > 0 && aircraft.OddCPRTime > 0 {
-				if math.Abs(float64(aircraft.EvenCPRTime-aircraft.OddCPRTime)) <= 10000 {
-					lat, lon, ok := adsb.DecodeCPRPosition(aircraft.EvenCPRLat, aircraft.EvenCPRLon,
-						aircraft.OddCPRLat, aircraft.OddCPRLon, odd)
-					if ok {
-						aircraft.Lat = lat
-						aircraft.Lon = lon
-						aircraft.SeenLatLon = time.Now()
-
-						// Add to trail
-						if len(aircraft.Trail) >= a.config.TrailLength {
-							aircraft.Trail = aircraft.Trail[1:]
-						}
-
-						aircraft.Trail = append(aircraft.Trail, adsb.Position{
-							Lat:       lat,
-							Lon:       lon,
-							Altitude:  aircraft.Altitude,
-							Heading:   aircraft.Heading,
-							Timestamp: time.Now(),
-						})
+			// Extract CPR position
+			cprLat, cprLon, odd := adsb.DecodeCPRFields(data)
+
+			// Store CPR position and decode it against the latest frame of
+			// the opposite parity, never a pair of the same parity
+			if aircraft.StoreCPR(cprLat, cprLon, odd, received.UnixMilli()) {
+				lat, lon, ok := adsb.DecodeCPRPosition(aircraft.EvenCPRLat, aircraft.EvenCPRLon,
+					aircraft.OddCPRLat, aircraft.OddCPRLon, odd)
+				aircraft.CPRStatus, aircraft.CPRStatusAt = cprDecodeStatus(ok, aircraft.EvenCPRLat, aircraft.OddCPRLat), received
+				if ok {
+					aircraft.Lat = lat
+					aircraft.Lon = lon
+					aircraft.HasPosition = true
+					if rec != nil {
+						rec.setPosition(lat, lon)
+					}
+					aircraft.SeenLatLon = received
+
+					// Add to trail once the aircraft has moved on from the last point
+					pos := adsb.Position{
+						Lat:       lat,
+						Lon:       lon,
+						Altitude:  aircraft.Altitude,
+						Heading:   aircraft.Heading,
+						Timestamp: received,
 					}
+					minInterval := time.Duration(a.config.TrailMinSecs) * time.Second
+					a.mutex.RLock()
+					if a.recordsTrail(icao, aircraft) && aircraft.TrailPointDue(pos, a.config.TrailMinDist, minInterval) {
+						aircraft.AddTrailPoint(pos)
+					}
+					a.mutex.RUnlock()
 				}
 			}
 		} else if metype == 19 {
 			// Airborne velocity
-			speed, heading, vertRate, ok := adsb.DecodeVelocity(data)
-			if ok {
-				aircraft.Speed = speed
-				aircraft.Heading = heading
-				aircraft.VertRate = vertRate
+			aircraft.SeenTypes |= adsb.SeenVelocity
+			if v, ok := adsb.DecodeVelocityDetails(data); ok {
+				aircraft.Speed = v.Speed
+				aircraft.VertRate = v.VertRate
+				a.updateDirection(aircraft, v, received)
+			}
+		} else if metype == adsb.TC_STATUS {
+			// Emergency/priority status
+			if state, ok := adsb.DecodeEmergency(data); ok {
+				if state != "" && state != aircraft.Emergency {
+					eventlog.Printf("Emergency: %s %s reports %s\n", adsb.FormatAddress(icao), aircraft.Flight, state)
+				}
+				aircraft.Emergency = state
+			}
+			if squawk, ok := adsb.DecodeStatusSquawk(data); ok {
+				aircraft.Squawk = squawk
+				aircraft.HasSquawk = true
+			}
+		} else if metype == adsb.TC_OPSTATUS {
+			// Operational status
+			if nacp, ok := adsb.DecodeNACp(data); ok {
+				aircraft.NACp = nacp
 			}
 		}
 	}
 
 	// Update last seen time and signal level
-	aircraft.Seen = time.Now()
+	aircraft.Seen = received
 	aircraft.SignalLevel[aircraft.Messages%8] = mm.SignalLevel
 	aircraft.Messages++
 
@@ -237,6 +545,194 @@ func (a *App) processModeS(data []byte, timestamp uint64) {
 	a.sigAcc += float64(mm.SignalLevel)
 }
 
+// cprDecodeStatus describes the outcome of decoding a CPR pair for the debug
+// panel, telling pairs straddling a zone boundary apart from other failures
+func cprDecodeStatus(ok bool, evenLat, oddLat int) string {
+	if ok {
+		return "ok"
+	}
+	if nlEven, nlOdd := adsb.CPRZones(evenLat, oddLat); nlEven != nlOdd {
+		return "zone mismatch"
+	}
+	return "failed"
+}
+
+// trackTimeout is how long a true track is preferred over magnetic heading
+// for the symbol direction after it was last received
+const trackTimeout = 10 * time.Second
+
+// updateDirection stores the track or heading from a velocity message and
+// sets the symbol direction, preferring true track. A magnetic heading is
+// only used, corrected by the configured declination, when no recent track
+// is known.
+func (a *App) updateDirection(aircraft *adsb.Aircraft, v adsb.Velocity, now time.Time) {
+	if v.HasTrack {
+		aircraft.Track = int(math.Round(v.Track)) % 360
+		aircraft.HasTrack = true
+		aircraft.SeenTrack = now
+		aircraft.Heading = aircraft.Track
+	}
+
+	if v.HasHeading {
+		aircraft.MagHeading = int(math.Round(v.Heading)) % 360
+		aircraft.HasMagHeading = true
+
+		if !aircraft.HasTrack || now.Sub(aircraft.SeenTrack) > trackTimeout {
+			trueHeading := math.Mod(v.Heading+a.config.MagneticDeclination+360, 360)
+			aircraft.Heading = int(math.Round(trueHeading)) % 360
+		}
+	}
+}
+
+// processAltitudeReply updates the altitude of a known aircraft from a
+// DF0/4/16 altitude reply, recovering the address from the parity field
+func (a *App) processAltitudeReply(data []byte, received time.Time) {
+	aircraft := a.aircraft.Get(adsb.AddressFromParity(data))
+	if aircraft == nil {
+		return
+	}
+
+	if alt, ok := adsb.DecodeAC13(data); ok {
+		aircraft.Altitude = alt
+		aircraft.HasAltitude = true
+	}
+
+	aircraft.Seen = received
+	a.msgRateAcc++
+}
+
+// processIdentityReply updates the squawk of a tracked aircraft from a
+// surveillance identity reply (DF5). The address is overlaid on the parity,
+// so replies not matching a known aircraft are dropped.
+func (a *App) processIdentityReply(data []byte, received time.Time) {
+	aircraft := a.aircraft.Get(adsb.AddressFromParity(data))
+	if aircraft == nil {
+		return
+	}
+
+	if squawk, ok := adsb.DecodeID13(data); ok {
+		aircraft.Squawk = squawk
+		aircraft.HasSquawk = true
+	}
+
+	aircraft.Seen = received
+	a.msgRateAcc++
+}
+
+// processCommB decodes the BDS register in a DF20/21 reply. The address is
+// overlaid on the parity, so replies not matching a known aircraft are dropped.
+func (a *App) processCommB(data []byte, received time.Time) {
+	if len(data) < 14 {
+		return
+	}
+
+	aircraft := a.aircraft.Get(adsb.AddressFromParity(data))
+	if aircraft == nil {
+		return
+	}
+
+	// DF20 also carries altitude in the AC field and DF21 the squawk
+	if alt, ok := adsb.DecodeAC13(data); ok {
+		aircraft.Altitude = alt
+		aircraft.HasAltitude = true
+	}
+	if squawk, ok := adsb.DecodeID13(data); ok {
+		aircraft.Squawk = squawk
+		aircraft.HasSquawk = true
+	}
+
+	if sel, ok := adsb.DecodeBDS40(data); ok {
+		if sel.MCPAltitudeValid {
+			aircraft.SelectedAltitude = sel.MCPAltitude
+			aircraft.HasSelectedAltitude = true
+		} else if sel.FMSAltitudeValid {
+			aircraft.SelectedAltitude = sel.FMSAltitude
+			aircraft.HasSelectedAltitude = true
+		}
+		if sel.BaroValid {
+			aircraft.BaroSetting = sel.Baro
+			aircraft.HasBaroSetting = true
+		}
+	} else if met, ok := adsb.DecodeBDS44(data); ok {
+		if met.WindValid {
+			aircraft.WindSpeed = met.WindSpeed
+			aircraft.WindDirection = met.WindDirection
+			aircraft.HasWind = true
+		}
+		aircraft.Temperature = met.Temperature
+		aircraft.HasTemperature = true
+	} else {
+		tt, is50 := adsb.DecodeBDS50(data)
+		hs, is60 := adsb.DecodeBDS60(data)
+		if is50 && is60 {
+			// Both registers fit, so tell them apart by the ADS-B ground
+			// speed, dropping the reply when there's none to compare with
+			is50 = aircraft.SeenTypes&adsb.SeenVelocity != 0 && tt.GroundSpeedValid &&
+				math.Abs(float64(tt.GroundSpeed-aircraft.Speed)) <= bdsSpeedTolerance
+			is60 = !is50 && aircraft.SeenTypes&adsb.SeenVelocity != 0
+		}
+		if is50 {
+			applyBDS50(aircraft, tt)
+		} else if is60 {
+			applyBDS60(aircraft, hs)
+		}
+	}
+
+	aircraft.Seen = received
+	a.msgRateAcc++
+}
+
+// bdsSpeedTolerance is how far in knots a BDS 5.0 ground speed may be from
+// the ADS-B one for an ambiguous reply to be taken as BDS 5.0
+const bdsSpeedTolerance = 30
+
+// applyBDS50 stores the valid fields of a track and turn report
+func applyBDS50(aircraft *adsb.Aircraft, tt adsb.BDS50) {
+	// Track and ground speed are left out: ADS-B velocity messages report
+	// them more often and more precisely, and the ADS-B ground speed is
+	// what ambiguous replies are checked against, so it mustn't be
+	// overwritten by one
+	if tt.RollValid {
+		aircraft.Roll = tt.Roll
+		aircraft.HasRoll = true
+	}
+	if tt.TrackRateValid {
+		aircraft.TrackRate = tt.TrackRate
+		aircraft.HasTrackRate = true
+	}
+	if tt.TASValid {
+		aircraft.TAS = tt.TAS
+		aircraft.HasTAS = true
+	}
+}
+
+// applyBDS60 stores the valid fields of a heading and speed report
+func applyBDS60(aircraft *adsb.Aircraft, hs adsb.BDS60) {
+	if hs.HeadingValid {
+		aircraft.MagHeading = int(math.Round(hs.Heading)) % 360
+		aircraft.HasMagHeading = true
+	}
+	if hs.IASValid {
+		aircraft.IAS = hs.IAS
+		aircraft.HasIAS = true
+	}
+	if hs.MachValid {
+		aircraft.Mach = hs.Mach
+		aircraft.HasMach = true
+	}
+
+	// Kept apart from VertRate, which ADS-B reports from either a barometric
+	// or a GNSS source
+	if hs.BaroVertRateValid {
+		aircraft.BaroVertRate = hs.BaroVertRate
+		aircraft.HasBaroVertRate = true
+	}
+	if hs.InertialRateValid {
+		aircraft.InertialVertRate = hs.InertialRate
+		aircraft.HasInertialVertRate = true
+	}
+}
+
 // cleanupStaleAircraft removes aircraft that haven't been seen recently
 func (a *App) cleanupStaleAircraft() {
 	now := time.Now()
@@ -247,18 +743,35 @@ func (a *App) cleanupStaleAircraft() {
 	}
 	a.lastCleanup = now
 
-	ttl := time.Duration(a.config.DisplayTTL) * time.Second
-	a.aircraft.RemoveStale(ttl)
+	airborneTTL, groundTTL := a.config.TTLFor(false), a.config.TTLFor(true)
+
+	anonymousTTL := airborneTTL
+	if a.config.AnonymousTTL > 0 {
+		anonymousTTL = a.config.AnonymousTTL
+	}
+
+	a.aircraft.RemoveStale(time.Duration(airborneTTL)*time.Second, time.Duration(groundTTL)*time.Second,
+		time.Duration(anonymousTTL)*time.Second)
 }
 
 // updateStatistics calculates various statistics
 func (a *App) updateStatistics() {
+	now := time.Now()
+	paused, _ := a.pauseState()
 	numVisible := 0
 	numTotal := 0
 
 	a.aircraft.ForEach(func(icao uint32, aircraft *adsb.Aircraft) {
+		// Rates hold while paused, no messages are applied
+		if !paused {
+			aircraft.UpdateMessageRate(now)
+		}
+		if !aircraft.HasPosition && a.config.HideNoPosition {
+			return
+		}
+
 		numTotal++
-		if aircraft.Lat != 0 && aircraft.Lon != 0 {
+		if aircraft.HasPosition {
 			numVisible++
 		}
 
@@ -285,10 +798,26 @@ func (a *App) updateStatistics() {
 	a.msgRateAcc = 0
 }
 
+// updateTitle shows the feed source and aircraft count in the window title so
+// multiple instances can be told apart
+func (a *App) updateTitle() {
+	if a.vizRenderer == nil {
+		return
+	}
+
+	source := net.JoinHostPort(a.config.ServerAddress, strconv.Itoa(a.config.ServerPort))
+	if a.config.Demo {
+		source = "demo"
+	}
+	if !a.connected.Load() {
+		source += " (disconnected)"
+	}
+
+	a.vizRenderer.SetTitle(fmt.Sprintf("%s - %s - %d aircraft", a.config.Title, source, a.numPlanes))
+}
+
 // Run starts the main application loop
 func (a *App) Run() error {
-	a.running = true
-
 	// Setup cleanup ticker
 	cleanupTicker := time.NewTicker(1 * time.Second)
 	defer cleanupTicker.Stop()
@@ -301,67 +830,112 @@ func (a *App) Run() error {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
-	go func() {
-		<-sigCh
-		fmt.Println("\nReceived shutdown signal. Exiting...")
-		a.running = false
-	}()
+	defer signal.Stop(sigCh)
+
+	a.spawn(func() {
+		select {
+		case <-sigCh:
+			eventlog.Printf("\nReceived shutdown signal. Exiting...\n")
+			a.cancel()
+		case <-a.ctx.Done():
+		}
+	})
 
-	fmt.Println("Starting viz1090-go...")
+	eventlog.Printf("Starting viz1090-go...\n")
 
 	// Main loop
-	for a.running {
+	for a.ctx.Err() == nil {
 		// Handle input - quit if requested
-		if !a.HandleInput() {
-			a.running = false
+		if !a.config.Headless && !a.HandleInput() {
 			break
 		}
 
+		paused, buffered := a.pauseState()
+
 		// Check for cleanup
 		select {
 		case <-cleanupTicker.C:
-			a.cleanupStaleAircraft()
+			if !paused {
+				a.cleanupStaleAircraft()
+			}
 			a.updateStatistics()
+			a.checkDataTimeout()
+			a.checkWatchlist()
+			a.checkInitialSelect()
+			a.checkNewAircraft()
+			a.updateTitle()
+			if a.recorder != nil {
+				a.recorder.Flush()
+			}
+			if a.jsonl != nil {
+				a.jsonl.Flush()
+			}
+			if a.apiServer != nil {
+				a.apiServer.Update(a.aircraft.Copy())
+			}
 		case <-connectionTicker.C:
 			// Try to connect if not already connected
-			if !a.isConnected {
-				go a.connectToBeast()
+			if !a.connected.Load() {
+				a.spawn(func() { a.connectToBeast(a.ctx) })
 			}
 		default:
 			// Continue without blocking
 		}
 
+		// Without a window there is nothing to draw, just keep the tickers serviced
+		if a.config.Headless {
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
 		// Render frame
+		fps, frameTime := a.frameTimer.average()
+		a.mutex.Lock()
+		a.applyViewMode()
+		a.mutex.Unlock()
 		a.mutex.RLock()
+		a.vizRenderer.SetStats(viz.Stats{
+			ShortFrames: a.shortFrames,
+			FPS:         fps,
+			FrameTime:   frameTime,
+			Paused:      paused,
+			Buffered:    buffered,
+			NoData:      a.noData,
+			Measuring:   a.measuring,
+			ViewMode:    a.viewMode.String(),
+			DecodeTime:  time.Duration(a.decodeTime.Swap(0)),
+		})
+		a.vizRenderer.SetMeasurement(a.measure.points)
 		a.vizRenderer.RenderFrame(a.aircraft.Copy(), a.centerLat, a.centerLon, a.maxDistance, a.selectedICAO)
 		a.mutex.RUnlock()
+		work := time.Since(a.lastFrameTime)
 
-		// Cap frame rate
-		elapsed := time.Since(a.lastFrameTime)
-		targetFrameTime := 33 * time.Millisecond // ~30fps
-		if elapsed < targetFrameTime {
-			time.Sleep(targetFrameTime - elapsed)
+		// Cap frame rate, leaving an uncapped loop to vsync
+		if a.config.MaxFPS > 0 {
+			targetFrameTime := time.Second / time.Duration(a.config.MaxFPS)
+			if work < targetFrameTime {
+				time.Sleep(targetFrameTime - work)
+			}
 		}
-		a.lastFrameTime = time.Now()
+		now := time.Now()
+		a.frameTimer.add(now.Sub(a.lastFrameTime), work)
+		a.lastFrameTime = now
 	}
 
+	// Return only once the receiver, servers and connection attempts have exited
+	a.shutdown()
 	return nil
 }
 
 // Cleanup releases all resources
 func (a *App) Cleanup() {
-	a.running = false
-
-	if a.beastConn != nil {
-		a.beastConn.Close()
-		a.beastConn = nil
-	}
+	a.shutdown()
 
 	if a.vizRenderer != nil {
 		a.vizRenderer.Cleanup()
 	}
 
-	fmt.Println("Cleanup complete")
+	eventlog.Printf("Cleanup complete\n")
 }
 
 // HandleInput processes all SDL events and updates the application state accordingly
@@ -378,22 +952,71 @@ func (a *App) HandleInput() bool {
 					return false
 				case sdl.K_EQUALS, sdl.K_PLUS:
 					// Zoom in
-					a.maxDistance *= 0.8
+					a.zoomBy(0.8)
 				case sdl.K_MINUS:
 					// Zoom out
-					a.maxDistance *= 1.25
+					a.zoomBy(1.25)
+				case sdl.K_RIGHTBRACKET:
+					// Increase UI scale
+					a.changeUIScale(1)
+				case sdl.K_LEFTBRACKET:
+					// Decrease UI scale
+					a.changeUIScale(-1)
+				case sdl.K_r:
+					// Toggle receiver marker
+					a.config.ShowReceiver = !a.config.ShowReceiver
+				case sdl.K_p:
+					// Toggle altitude profile panel
+					a.config.ShowAltitudeProfile = !a.config.ShowAltitudeProfile
+				case sdl.K_w:
+					// Toggle wind barbs
+					a.config.ShowWind = !a.config.ShowWind
+				case sdl.K_a:
+					// Zoom to fit all traffic
+					a.zoomToTraffic()
+				case sdl.K_c:
+					// Toggle conflict alerts
+					a.config.ShowConflicts = !a.config.ShowConflicts
+				case sdl.K_SPACE:
+					// Freeze or resume the display
+					a.togglePause()
+				case sdl.K_t:
+					// Toggle trails
+					a.toggleTrails()
+				case sdl.K_f:
+					// Toggle frame rate readout
+					a.config.ShowFPS = !a.config.ShowFPS
+				case sdl.K_h:
+					// Toggle the performance HUD
+					a.config.ShowPerfHUD = !a.config.ShowPerfHUD
+				case sdl.K_l:
+					// Toggle event log pane
+					a.config.ShowLog = !a.config.ShowLog
+				case sdl.K_d:
+					// Toggle compact one-line aircraft tags
+					a.config.CompactLabels = !a.config.CompactLabels
+				case sdl.K_m:
+					// Toggle the measuring tool
+					a.toggleMeasure()
+				case sdl.K_v:
+					// Cycle free, receiver and follow views
+					a.cycleViewMode()
+				case sdl.K_F11:
+					// Toggle fullscreen
+					a.toggleFullscreen()
 				}
 			}
 
 		case *sdl.MouseWheelEvent:
-			// Handle mouse wheel for zooming
+			// Handle mouse wheel for zooming about the cursor
 			zoomFactor := 1.0
 			if e.Y > 0 {
 				zoomFactor = 0.8 // Zoom in
 			} else if e.Y < 0 {
 				zoomFactor = 1.25 // Zoom out
 			}
-			a.maxDistance *= zoomFactor
+			mouseX, mouseY, _ := sdl.GetMouseState()
+			a.zoomAtCursor(int(mouseX), int(mouseY), zoomFactor)
 
 		case *sdl.MouseButtonEvent:
 			if e.Type == sdl.MOUSEBUTTONDOWN {
@@ -410,12 +1033,22 @@ func (a *App) HandleInput() bool {
 	return true
 }
 
+// changeUIScale adjusts the renderer's UI scale by delta steps
+func (a *App) changeUIScale(delta int) {
+	if err := a.vizRenderer.SetUIScale(a.vizRenderer.GetUIScale() + delta); err != nil {
+		eventlog.Printf("Failed to change UI scale: %v\n", err)
+	}
+}
+
 // handleMouseButtonDown processes mouse button events
 func (a *App) handleMouseButtonDown(x, y int32, button uint8, clicks int32) {
 	if button == sdl.BUTTON_LEFT {
 		if clicks == 2 {
 			// Double-click: Zoom in at the clicked location
 			a.zoomToPosition(int(x), int(y), 0.5)
+		} else if a.measuring {
+			// Single click while measuring: Place a point
+			a.measureAt(int(x), int(y))
 		} else {
 			// Single click: Select aircraft
 			a.selectAircraftAt(int(x), int(y))
@@ -428,123 +1061,184 @@ func (a *App) handleMapPan(xrel, yrel int) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
-	// Convert pixel movement to longitude/latitude change
-	// The formula adjusts for current zoom level and latitude
-	scale := a.maxDistance / float64(a.vizRenderer.GetHeight()/2)
-
-	// Y movement (latitude change)
-	latChange := float64(yrel) * scale / 60.0
-
-	// X movement needs to account for longitude compression at higher latitudes
-	lonFactor := math.Cos(a.centerLat * math.Pi / 180.0)
-	lonChange := float64(xrel) * scale / (60.0 * lonFactor)
+	// Dragging takes the view out of receiver or follow mode
+	a.viewMode = viewFree
 
-	// Apply the changes (note: invert directions for natural map movement)
-	a.centerLat -= latChange
-	a.centerLon -= lonChange
+	// The new center is the point that was under the screen center before
+	// it moved with the mouse (inverted for natural map movement)
+	v := a.view()
+	a.centerLat, a.centerLon = v.pixelToLatLon(v.width/2-xrel, v.height/2-yrel)
 }
 
-// zoomToPosition zooms the map to a specific position
+// zoomToPosition zooms by factor and centers the map on the point under
+// screen position (x, y)
 func (a *App) zoomToPosition(x, y int, factor float64) {
-	a.mutex.Lock()
-	defer a.mutex.Unlock()
+	a.zoomAbout(x, y, float64(a.vizRenderer.GetWidth())/2, float64(a.vizRenderer.GetHeight())/2, factor)
+}
 
-	// First calculate lat/lon at the clicked position
-	lat, lon := a.pixelToLatLon(x, y)
+// zoomBy scales the view radius by factor within the zoom limits
+func (a *App) zoomBy(factor float64) {
+	a.maxDistance = a.zoomLimit(a.maxDistance * factor)
+}
 
-	// Set the new center to this position
-	a.centerLat = lat
-	a.centerLon = lon
+// zoomLimit clamps a view radius in NM to the configured zoom limits
+func (a *App) zoomLimit(distance float64) float64 {
+	return clampZoom(distance, a.config.MinZoom, a.config.MaxZoom)
+}
 
-	// Apply zoom factor
-	a.maxDistance *= factor
+// zoomAtCursor zooms by factor while keeping the point under the cursor fixed
+func (a *App) zoomAtCursor(x, y int, factor float64) {
+	a.zoomAbout(x, y, float64(x), float64(y), factor)
 }
 
-// selectAircraftAt tries to select an aircraft at the given screen position
-func (a *App) selectAircraftAt(x, y int) {
+// zoomAbout zooms by factor within the zoom limits, moving the point under
+// screen position (x, y) to (toX, toY), and stops following anything
+func (a *App) zoomAbout(x, y int, toX, toY, factor float64) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
-	// First deselect current selection
-	a.selectedICAO = 0
+	// Shrink the step at the zoom limits so the point still lands on target
+	factor = a.zoomLimit(a.maxDistance*factor) / a.maxDistance
+	v := a.view().zoomAt(x, y, toX, toY, factor)
+	a.centerLat, a.centerLon, a.maxDistance = v.centerLat, v.centerLon, v.maxDistance
+	a.viewMode = viewFree
+}
 
-	// Find the closest aircraft to the click position
-	var closestAircraft uint32
-	closestDistance := 400.0 // Max selection distance squared (20px radius)
+// toggleTrails turns trail drawing and recording on or off. Trails are
+// dropped when turned off, so they restart from the current positions.
+func (a *App) toggleTrails() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
 
-	a.aircraft.ForEach(func(icao uint32, aircraft *adsb.Aircraft) {
-		// Skip aircraft without position
-		if aircraft.Lat == 0 && aircraft.Lon == 0 {
-			return
-		}
+	a.config.ShowTrails = !a.config.ShowTrails
+	if !a.config.ShowTrails {
+		a.aircraft.ForEach(func(icao uint32, aircraft *adsb.Aircraft) {
+			if !a.recordsTrail(icao, aircraft) {
+				aircraft.Trail = nil
+			}
+		})
+	}
+}
 
-		// Calculate screen position of aircraft
-		aircraftX, aircraftY := a.latLonToPixel(aircraft.Lat, aircraft.Lon)
+// recordsTrail reports whether trail points are kept for an aircraft: all of
+// them while trails are shown, otherwise only the selected one with
+// SelectedTrail, and none on the ground with AirborneTrailsOnly. The caller
+// must hold a.mutex.
+func (a *App) recordsTrail(icao uint32, aircraft *adsb.Aircraft) bool {
+	if a.config.AirborneTrailsOnly && aircraft.OnGround {
+		return false
+	}
+	return a.config.ShowTrails || (a.config.SelectedTrail && icao == a.selectedICAO)
+}
 
-		// Calculate squared distance
-		dx := float64(aircraftX - x)
-		dy := float64(aircraftY - y)
-		distSquared := dx*dx + dy*dy
+// toggleFullscreen switches the window in or out of fullscreen
+func (a *App) toggleFullscreen() {
+	if err := a.vizRenderer.SetFullscreen(!a.config.Fullscreen); err != nil {
+		eventlog.Printf("Failed to toggle fullscreen: %v\n", err)
+		return
+	}
+	a.config.Fullscreen = !a.config.Fullscreen
+}
 
-		// Update if this is closer
-		if distSquared < closestDistance {
-			closestDistance = distSquared
-			closestAircraft = icao
+// zoomToTraffic centers and zooms the map so every positioned aircraft fits
+// with a margin. A single aircraft is centered at the initial zoom, and the
+// view is left alone when nothing has a position.
+func (a *App) zoomToTraffic() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	var lats, lons []float64
+	a.aircraft.ForEach(func(icao uint32, aircraft *adsb.Aircraft) {
+		if aircraft.HasPosition {
+			lats = append(lats, aircraft.Lat)
+			lons = append(lons, aircraft.Lon)
 		}
 	})
 
-	// Set the selected aircraft
-	if closestAircraft != 0 {
-		a.selectedICAO = closestAircraft
-		fmt.Printf("Selected aircraft: %06X\n", closestAircraft)
+	if len(lats) == 0 {
+		return
 	}
-}
-
-// pixelToLatLon converts screen coordinates to latitude/longitude
-func (a *App) pixelToLatLon(x, y int) (float64, float64) {
-	// Get screen dimensions
-	h := a.vizRenderer.GetHeight()
-	w := a.vizRenderer.GetWidth()
-
-	// Calculate offset from center in screen coordinates
-	dx := float64(x - w/2)
-	dy := float64(y - h/2)
+	a.viewMode = viewFree
 
-	// Scale to match the current zoom level
-	scale := a.maxDistance / float64(h/2)
+	if len(lats) == 1 {
+		a.centerLat, a.centerLon = lats[0], lons[0]
+		a.maxDistance = a.zoomLimit(a.config.InitialZoom)
+		return
+	}
 
-	// Convert to lat/lon offsets
-	// For latitude, each degree is roughly 60 nautical miles
-	latOffset := -dy * scale / 60.0
+	// Measure longitudes relative to the first aircraft so traffic either side
+	// of the anti-meridian forms one tight group
+	latMin, latMax := lats[0], lats[0]
+	lonMin, lonMax := 0.0, 0.0
+	for i := range lats {
+		latMin = math.Min(latMin, lats[i])
+		latMax = math.Max(latMax, lats[i])
+		dLon := map_system.NormalizeLon(lons[i] - lons[0])
+		lonMin = math.Min(lonMin, dLon)
+		lonMax = math.Max(lonMax, dLon)
+	}
 
-	// For longitude, we need to account for the latitude
-	lonFactor := math.Cos(a.centerLat * math.Pi / 180.0)
-	lonOffset := dx * scale / (60.0 * lonFactor)
+	a.centerLat = (latMin + latMax) / 2
+	a.centerLon = map_system.NormalizeLon(lons[0] + (lonMin+lonMax)/2)
 
-	return a.centerLat + latOffset, a.centerLon + lonOffset
+	// maxDistance is half the screen height in NM, so scale the horizontal
+	// extent by the aspect ratio before comparing
+	const margin = 1.2
+	aspect := float64(a.vizRenderer.GetHeight()) / float64(a.vizRenderer.GetWidth())
+	halfLat := (latMax - latMin) * 60.0 / 2
+	halfLon := (lonMax - lonMin) * 60.0 * math.Cos(a.centerLat*math.Pi/180.0) / 2
+	a.maxDistance = a.zoomLimit(math.Max(1.0, margin*math.Max(halfLat, halfLon*aspect)))
 }
 
-// latLonToPixel converts latitude/longitude to screen coordinates
-func (a *App) latLonToPixel(lat, lon float64) (int, int) {
-	// Get screen dimensions
-	h := a.vizRenderer.GetHeight()
-	w := a.vizRenderer.GetWidth()
+// selectAircraftAt tries to select an aircraft at the given screen position.
+// Clicking again at the same spot selects the next aircraft under it.
+func (a *App) selectAircraftAt(x, y int) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
 
-	// Calculate latitude offset from center in nautical miles
-	latOffset := (lat - a.centerLat) * 60.0
+	uiScale := a.vizRenderer.GetUIScale()
+	radius := float64(a.config.SelectRadius * uiScale)
+	candidates := pickCandidates(a.aircraft.Copy(), a.view(), x, y, radius)
+	a.setSelected(a.clickCycle.pick(x, y, candidates, clickCycleTolerance*uiScale))
+	a.stopInitialSelect()
+	if a.selectedICAO != 0 {
+		eventlog.Printf("Selected aircraft: %s\n", adsb.FormatAddress(a.selectedICAO))
+	}
+}
 
-	// Calculate longitude offset from center in nautical miles
-	// accounting for latitude compression
-	lonFactor := math.Cos(a.centerLat * math.Pi / 180.0)
-	lonOffset := (lon - a.centerLon) * 60.0 * lonFactor
+// setSelected changes the selected aircraft, dropping the trail of the one
+// it replaces unless trails are still kept for it. The caller must hold
+// a.mutex.
+func (a *App) setSelected(icao uint32) {
+	prev := a.selectedICAO
+	a.selectedICAO = icao
+	a.aircraft.Pin(icao)
+	if prev == 0 || prev == icao {
+		return
+	}
+	if aircraft := a.aircraft.Get(prev); aircraft != nil && !a.recordsTrail(prev, aircraft) {
+		aircraft.Trail = nil
+	}
+}
 
-	// Scale to screen coordinates
-	scale := float64(h/2) / a.maxDistance
+// view returns the current map viewport
+func (a *App) view() viewport {
+	return viewport{
+		centerLat:   a.centerLat,
+		centerLon:   a.centerLon,
+		maxDistance: a.maxDistance,
+		width:       a.vizRenderer.GetWidth(),
+		height:      a.vizRenderer.GetHeight(),
+		projection:  a.projection,
+	}
+}
 
-	// Convert to screen offsets from center
-	dx := lonOffset * scale
-	dy := -latOffset * scale // Invert Y because screen coordinates increase downward
+// pixelToLatLon converts screen coordinates to latitude/longitude
+func (a *App) pixelToLatLon(x, y int) (float64, float64) {
+	return a.view().pixelToLatLon(x, y)
+}
 
-	// Return final screen coordinates
-	return int(float64(w)/2.0 + dx), int(float64(h)/2.0 + dy)
+// latLonToPixel converts latitude/longitude to screen coordinates
+func (a *App) latLonToPixel(lat, lon float64) (int, int) {
+	return a.view().latLonToPixel(lat, lon)
 }
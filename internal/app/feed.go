@@ -0,0 +1,35 @@
+package app
+
+import (
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
+)
+
+// checkDataTimeout flags the feed as silent once no messages have been
+// counted for DataTimeoutSeconds, and drops a connection that has stayed
+// silent that long so the connection ticker reconnects
+func (a *App) checkDataTimeout() {
+	timeout := time.Duration(a.config.DataTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		a.noData = false
+		return
+	}
+
+	// Messages are held uncounted while paused, so don't mistake that for silence
+	now := time.Now()
+	if paused, _ := a.pauseState(); a.msgRate > 0 || paused {
+		a.lastData = now
+	}
+	a.noData = now.Sub(a.lastData) > timeout
+
+	a.connMutex.Lock()
+	defer a.connMutex.Unlock()
+
+	// Give a fresh connection the full timeout before judging it
+	if !a.noData || !a.connected.Load() || now.Sub(a.connectedAt) <= timeout {
+		return
+	}
+	eventlog.Printf("Warning: No data for %v, reconnecting\n", timeout)
+	a.dropConn()
+}
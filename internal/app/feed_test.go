@@ -0,0 +1,96 @@
+package app
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/config"
+)
+
+func TestCheckDataTimeout(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DataTimeoutSeconds = 10
+	a := New(cfg)
+
+	dropped := 0
+	a.dropConn = func() { dropped++ }
+	a.connected.Store(true)
+
+	// A silent feed within the timeout is left alone
+	a.lastData = time.Now().Add(-5 * time.Second)
+	a.connectedAt = a.lastData
+	a.checkDataTimeout()
+	if a.noData || dropped != 0 {
+		t.Fatalf("noData=%v dropped=%d within the timeout", a.noData, dropped)
+	}
+
+	// Past the timeout the feed is marked dead and the connection dropped
+	a.lastData = time.Now().Add(-15 * time.Second)
+	a.connectedAt = a.lastData
+	a.checkDataTimeout()
+	if !a.noData || dropped != 1 {
+		t.Fatalf("noData=%v dropped=%d after the timeout", a.noData, dropped)
+	}
+
+	// A fresh connection gets the full timeout before being dropped again
+	a.connectedAt = time.Now()
+	a.checkDataTimeout()
+	if !a.noData || dropped != 1 {
+		t.Errorf("noData=%v dropped=%d on a fresh connection", a.noData, dropped)
+	}
+
+	// Messages clear the flag
+	a.msgRate = 12
+	a.checkDataTimeout()
+	if a.noData {
+		t.Error("noData still set with messages arriving")
+	}
+}
+
+func TestConnectToBeastDialsOnce(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	var accepted atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			defer conn.Close()
+		}
+	}()
+
+	cfg := config.DefaultConfig()
+	cfg.ServerAddress = "127.0.0.1"
+	cfg.ServerPort = listener.Addr().(*net.TCPAddr).Port
+	a := New(cfg)
+	defer a.shutdown()
+
+	// Overlapping ticks must not open a second connection
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.connectToBeast(a.ctx)
+		}()
+	}
+	wg.Wait()
+	time.Sleep(100 * time.Millisecond)
+
+	if !a.connected.Load() {
+		t.Fatal("not connected")
+	}
+	if n := accepted.Load(); n != 1 {
+		t.Errorf("accepted %d connections, want 1", n)
+	}
+}
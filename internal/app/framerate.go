@@ -0,0 +1,45 @@
+package app
+
+import (
+	"time"
+)
+
+// frameSamples is the number of frames in the rolling frame rate average
+const frameSamples = 30
+
+// frameTimer keeps a rolling average of the frame period and render time
+type frameTimer struct {
+	periods [frameSamples]time.Duration // Time between frame starts
+	work    [frameSamples]time.Duration // Time spent producing each frame
+	next    int
+	count   int
+}
+
+// add records one frame
+func (f *frameTimer) add(period, work time.Duration) {
+	f.periods[f.next] = period
+	f.work[f.next] = work
+	f.next = (f.next + 1) % frameSamples
+	if f.count < frameSamples {
+		f.count++
+	}
+}
+
+// average returns the mean frames per second and render time per frame
+func (f *frameTimer) average() (float64, time.Duration) {
+	if f.count == 0 {
+		return 0, 0
+	}
+
+	var period, work time.Duration
+	for i := 0; i < f.count; i++ {
+		period += f.periods[i]
+		work += f.work[i]
+	}
+
+	fps := 0.0
+	if period > 0 {
+		fps = float64(f.count) / period.Seconds()
+	}
+	return fps, work / time.Duration(f.count)
+}
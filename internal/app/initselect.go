@@ -0,0 +1,84 @@
+package app
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
+)
+
+// initialSelect waits for the aircraft named by config.InitialSelect to
+// appear so it can be selected without a click
+type initialSelect struct {
+	icao   uint32 // Address to match, 0 unless the key is six hex digits
+	flight string // Callsign to match, upper case
+	armed  bool   // Still waiting for the aircraft
+	auto   uint32 // Aircraft selected automatically, 0 when none
+}
+
+// newInitialSelect parses an ICAO hex address or callsign. Six hex digit
+// keys match either way, like watchlist keys. An empty key never matches.
+func newInitialSelect(key string) initialSelect {
+	key = strings.ToUpper(strings.TrimSpace(key))
+	s := initialSelect{flight: key, armed: key != ""}
+	if len(key) == 6 {
+		if icao, err := strconv.ParseUint(key, 16, 32); err == nil {
+			s.icao = uint32(icao)
+		}
+	}
+	return s
+}
+
+// matches reports whether an aircraft is the one waited for
+func (s *initialSelect) matches(icao uint32, flight string) bool {
+	if s.icao != 0 && icao == s.icao {
+		return true
+	}
+	return flight != "" && strings.ToUpper(strings.TrimSpace(flight)) == s.flight
+}
+
+// checkInitialSelect selects the InitialSelect aircraft once it appears,
+// following it with InitialFollow. If it goes stale the selection is cleared
+// and it is selected again when it returns. Selecting by hand stops this.
+func (a *App) checkInitialSelect() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	s := &a.initialSelect
+	if s.auto != 0 && a.aircraft.Get(s.auto) == nil {
+		eventlog.Printf("Initial selection %s lost, waiting for it to return\n", adsb.FormatAddress(s.auto))
+		if a.selectedICAO == s.auto {
+			a.setSelected(0)
+		}
+		s.auto = 0
+		s.armed = true
+	}
+	if !s.armed {
+		return
+	}
+
+	a.aircraft.ForEach(func(icao uint32, aircraft *adsb.Aircraft) {
+		if s.auto != 0 || !s.matches(icao, aircraft.Flight) {
+			return
+		}
+		s.auto = icao
+	})
+	if s.auto == 0 {
+		return
+	}
+
+	s.armed = false
+	a.setSelected(s.auto)
+	if a.config.InitialFollow {
+		a.viewMode = viewFollow
+		a.applyViewMode()
+	}
+	eventlog.Printf("Selected aircraft: %s (initial selection)\n", adsb.FormatAddress(s.auto))
+}
+
+// stopInitialSelect hands selection over to the user
+func (a *App) stopInitialSelect() {
+	a.initialSelect.armed = false
+	a.initialSelect.auto = 0
+}
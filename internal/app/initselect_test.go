@@ -0,0 +1,76 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/config"
+)
+
+func TestInitialSelectByCallsign(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.InitialSelect = "klm1023"
+	cfg.InitialFollow = true
+	a := New(cfg)
+
+	// Nothing to select yet
+	other := a.aircraft.GetOrCreate(0xABCDEF)
+	other.Flight, other.Seen = "KLM10", time.Now()
+	a.checkInitialSelect()
+	if a.selectedICAO != 0 {
+		t.Fatalf("selected %06X before the flight appeared", a.selectedICAO)
+	}
+
+	aircraft := a.aircraft.GetOrCreate(0x40621D)
+	aircraft.Flight, aircraft.Seen = "KLM1023 ", time.Now()
+	aircraft.Lat, aircraft.Lon, aircraft.HasPosition = 52.2, 3.9, true
+	a.checkInitialSelect()
+	if a.selectedICAO != 0x40621D || a.viewMode != viewFollow || a.centerLat != 52.2 {
+		t.Fatalf("selected %06X in %v view at %.1f, want 40621D followed at 52.2", a.selectedICAO, a.viewMode, a.centerLat)
+	}
+
+	// Going stale clears the selection, and a return selects it again
+	aircraft.Seen = time.Now().Add(-time.Hour)
+	a.aircraft.RemoveStale(time.Minute, time.Minute, time.Minute)
+	a.checkInitialSelect()
+	if a.selectedICAO != 0 {
+		t.Errorf("stale aircraft %06X still selected", a.selectedICAO)
+	}
+	aircraft = a.aircraft.GetOrCreate(0x40621D)
+	aircraft.Flight, aircraft.Seen = "KLM1023", time.Now()
+	a.checkInitialSelect()
+	if a.selectedICAO != 0x40621D {
+		t.Errorf("returning aircraft not selected again, selected %06X", a.selectedICAO)
+	}
+
+	// Choosing another aircraft by hand stops the automatic selection
+	a.selectedICAO = 0xABCDEF
+	a.stopInitialSelect()
+	a.aircraft.RemoveStale(time.Minute, time.Minute, time.Minute)
+	a.checkInitialSelect()
+	if a.selectedICAO != 0xABCDEF {
+		t.Errorf("selection changed to %06X after a manual selection", a.selectedICAO)
+	}
+}
+
+func TestInitialSelectByAddress(t *testing.T) {
+	tests := []struct {
+		key    string
+		icao   uint32
+		flight string
+		want   bool
+	}{
+		{"40621d", 0x40621D, "", true},
+		{"40621D", 0x123456, "40621D", true},
+		{"40621D", 0x123456, "KLM1023", false},
+		{"BAW12", 0xBAAA12, "BAW123", false},
+		{"", 0, "", false},
+	}
+
+	for _, tt := range tests {
+		s := newInitialSelect(tt.key)
+		if got := s.armed && s.matches(tt.icao, tt.flight); got != tt.want {
+			t.Errorf("%q matches %06X %q = %v, want %v", tt.key, tt.icao, tt.flight, got, tt.want)
+		}
+	}
+}
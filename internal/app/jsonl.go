@@ -0,0 +1,188 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
+)
+
+// jsonlRecord is one decoded message as written to the JSONL output. Fields
+// the message doesn't carry are left out.
+type jsonlRecord struct {
+	Time      time.Time `json:"time"`
+	DF        int       `json:"df"`
+	ICAO      string    `json:"icao"`
+	TypeCode  int       `json:"tc,omitempty"`
+	Flight    string    `json:"flight,omitempty"`
+	Altitude  *int      `json:"altitude,omitempty"`
+	OnGround  bool      `json:"on_ground,omitempty"`
+	Speed     *int      `json:"speed,omitempty"`
+	Track     *float64  `json:"track,omitempty"`
+	Heading   *float64  `json:"heading,omitempty"`
+	VertRate  *int      `json:"vert_rate,omitempty"`
+	CPR       *jsonlCPR `json:"cpr,omitempty"`
+	Lat       *float64  `json:"lat,omitempty"` // Set once the CPR pair resolves to a position
+	Lon       *float64  `json:"lon,omitempty"`
+	Emergency string    `json:"emergency,omitempty"`
+	NACp      *int      `json:"nacp,omitempty"`
+}
+
+// jsonlCPR holds the raw CPR fields of a position message
+type jsonlCPR struct {
+	Lat int  `json:"lat"`
+	Lon int  `json:"lon"`
+	Odd bool `json:"odd"`
+}
+
+// setPosition records the position decoded from the message's CPR pair
+func (r *jsonlRecord) setPosition(lat, lon float64) {
+	r.Lat, r.Lon = &lat, &lon
+}
+
+// describeMessage decodes the fields of a Mode S frame of valid length into
+// a JSONL record. Replies without an address field report the address
+// recovered from their parity.
+func describeMessage(data []byte, now time.Time) *jsonlRecord {
+	df := int(data[0] >> 3)
+	r := &jsonlRecord{Time: now, DF: df}
+
+	if df != adsb.DF11 && df != adsb.DF17 && df != adsb.DF18 {
+		r.ICAO = adsb.FormatAddress(adsb.AddressFromParity(data))
+		if alt, ok := adsb.DecodeAC13(data); ok {
+			r.Altitude = &alt
+		}
+		return r
+	}
+
+	icao := uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
+	if adsb.DecodeAddrType(data) == adsb.AddrTISBTrackfile {
+		icao |= adsb.TrackFileFlag
+	}
+	r.ICAO = adsb.FormatAddress(icao)
+	if df == adsb.DF11 {
+		return r
+	}
+
+	tc := int(data[4] >> 3)
+	r.TypeCode = tc
+
+	switch {
+	case tc >= 1 && tc <= 4:
+		r.Flight = adsb.DecodeCallsign(data[5:11])
+	case tc >= 5 && tc <= 8:
+		r.OnGround = true
+		speed, track, ok := adsb.DecodeSurfaceMovement(data)
+		if ok {
+			r.Speed = &speed
+		}
+		if track >= 0 {
+			t := float64(track)
+			r.Track = &t
+		}
+		lat, lon, odd := adsb.DecodeCPRFields(data)
+		r.CPR = &jsonlCPR{Lat: lat, Lon: lon, Odd: odd}
+	case tc >= 9 && tc <= 18:
+		if alt, ok := adsb.DecodeAltitude(data); ok {
+			r.Altitude = &alt
+		}
+		lat, lon, odd := adsb.DecodeCPRFields(data)
+		r.CPR = &jsonlCPR{Lat: lat, Lon: lon, Odd: odd}
+	case tc == 19:
+		if v, ok := adsb.DecodeVelocityDetails(data); ok {
+			r.Speed = &v.Speed
+			r.VertRate = &v.VertRate
+			if v.HasTrack {
+				r.Track = &v.Track
+			} else if v.HasHeading {
+				r.Heading = &v.Heading
+			}
+		}
+	case tc == adsb.TC_STATUS:
+		if state, ok := adsb.DecodeEmergency(data); ok {
+			r.Emergency = state
+		}
+	case tc == adsb.TC_OPSTATUS:
+		if nacp, ok := adsb.DecodeNACp(data); ok {
+			r.NACp = &nacp
+		}
+	}
+	return r
+}
+
+// jsonlWriter writes records as newline-delimited JSON, buffered and
+// flushed on the statistics tick
+type jsonlWriter struct {
+	w      *bufio.Writer
+	enc    *json.Encoder
+	closer io.Closer // Nil for stdout, which is left open
+	failed bool      // A write failed and output stopped
+	mutex  sync.Mutex
+}
+
+// openJSONL opens the JSONL output, "-" for stdout or a file path to append
+// to. Stdout is then reserved for records, so the event log and alert bell
+// move to stderr.
+func openJSONL(path string) (*jsonlWriter, error) {
+	if path == "-" {
+		eventlog.SetOutput(os.Stderr)
+		return newJSONLWriter(os.Stdout, nil), nil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open JSONL output: %v", err)
+	}
+	return newJSONLWriter(f, f), nil
+}
+
+// newJSONLWriter buffers records written to out
+func newJSONLWriter(out io.Writer, closer io.Closer) *jsonlWriter {
+	w := bufio.NewWriter(out)
+	return &jsonlWriter{w: w, enc: json.NewEncoder(w), closer: closer}
+}
+
+// write encodes one record as a line
+func (w *jsonlWriter) write(r *jsonlRecord) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.failed {
+		if err := w.enc.Encode(r); err != nil {
+			w.fail(err)
+		}
+	}
+}
+
+// Flush writes buffered records out
+func (w *jsonlWriter) Flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.failed {
+		if err := w.w.Flush(); err != nil {
+			w.fail(err)
+		}
+	}
+}
+
+// Close flushes and closes the output file
+func (w *jsonlWriter) Close() error {
+	w.Flush()
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
+}
+
+// fail stops output after a write error. The caller must hold w.mutex.
+func (w *jsonlWriter) fail(err error) {
+	eventlog.Printf("Warning: JSONL output stopped: %v\n", err)
+	w.failed = true
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/OJPARKINSON/viz1090/internal/config"
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
+	"github.com/OJPARKINSON/viz1090/internal/sim"
+)
+
+func TestJSONLWritesDecodedMessages(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.JSONL = filepath.Join(t.TempDir(), "messages.jsonl")
+	p := newBeastPipe(t, cfg)
+
+	const icao = 0x40621D
+	even, _ := hex.DecodeString("8D40621D58C382D690C8AC2863A7")
+	odd, _ := hex.DecodeString("8D40621D58C386435CC412692AD6")
+	p.send(
+		sim.CreateADSBIdentMessage(icao, "KLM1023"),
+		even,
+		odd,
+		sim.CreateADSBVelocityMessage(icao, 450, 90, -640),
+	)
+	p.close()
+
+	f, err := os.Open(cfg.JSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var records []jsonlRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r jsonlRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 4 {
+		t.Fatalf("got %d records, want 4", len(records))
+	}
+
+	for i, r := range records {
+		if r.DF != 17 || r.ICAO != "40621D" {
+			t.Errorf("record %d: DF %d ICAO %s, want 17 40621D", i, r.DF, r.ICAO)
+		}
+	}
+	if records[0].Flight != "KLM1023" {
+		t.Errorf("Flight = %q, want KLM1023", records[0].Flight)
+	}
+	if r := records[1]; r.CPR == nil || r.CPR.Odd || r.Lat != nil {
+		t.Errorf("even position record = %+v, want raw CPR and no position", r)
+	}
+	if r := records[2]; r.Altitude == nil || *r.Altitude != 38000 ||
+		r.Lat == nil || math.Abs(*r.Lat-52.2657) > 0.001 || math.Abs(*r.Lon-3.9389) > 0.001 {
+		t.Errorf("odd position record = %+v, want 38000 ft at 52.2657,3.9389", r)
+	}
+	if r := records[3]; r.TypeCode != 19 || r.Speed == nil || math.Abs(float64(*r.Speed-450)) > 1 ||
+		r.VertRate == nil || *r.VertRate != -640 {
+		t.Errorf("velocity record = %+v, want 450 kt at -640 ft/min", r)
+	}
+}
+
+// failWriter fails every write
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestJSONLWriteFailureStopsOutput(t *testing.T) {
+	w := newJSONLWriter(failWriter{}, nil)
+	w.write(&jsonlRecord{DF: 17})
+	if w.failed {
+		t.Fatal("buffered write failed before a flush")
+	}
+
+	w.Flush()
+	if !w.failed {
+		t.Fatal("failed flush not reported")
+	}
+	recent := eventlog.Default.Recent(1)
+	if len(recent) != 1 || !strings.Contains(recent[0].Text, "disk full") {
+		t.Errorf("last log entry %v, want the write error", recent)
+	}
+}
+
+func TestJSONLStdoutMovesEventLogToStderr(t *testing.T) {
+	defer eventlog.SetOutput(os.Stdout)
+
+	w, err := openJSONL("-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if eventlog.Output() != os.Stderr {
+		t.Error("event log still writes to stdout alongside JSONL records")
+	}
+}
@@ -0,0 +1,34 @@
+package app
+
+import "github.com/OJPARKINSON/viz1090/internal/map_system"
+
+// measurement holds the points clicked with the measuring tool
+type measurement struct {
+	points []map_system.Point
+}
+
+// add records a clicked point. A click after a finished measurement starts
+// a new one.
+func (m *measurement) add(lat, lon float64) {
+	if len(m.points) == 2 {
+		m.points = m.points[:0]
+	}
+	m.points = append(m.points, map_system.Point{Lat: lat, Lon: lon})
+}
+
+// toggleMeasure turns the measuring tool on or off, clearing any measurement
+func (a *App) toggleMeasure() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.measuring = !a.measuring
+	a.measure.points = a.measure.points[:0]
+}
+
+// measureAt adds the map point under a click to the measurement
+func (a *App) measureAt(x, y int) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.measure.add(a.pixelToLatLon(x, y))
+}
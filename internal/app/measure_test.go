@@ -0,0 +1,22 @@
+package app
+
+import "testing"
+
+func TestMeasurementRestartsAfterTwoPoints(t *testing.T) {
+	var m measurement
+
+	m.add(51.0, 0.0)
+	if len(m.points) != 1 {
+		t.Fatalf("%d points after one click, want 1", len(m.points))
+	}
+	m.add(52.0, 1.0)
+	if len(m.points) != 2 {
+		t.Fatalf("%d points after two clicks, want 2", len(m.points))
+	}
+
+	// A third click starts over from the clicked point
+	m.add(40.0, -70.0)
+	if len(m.points) != 1 || m.points[0].Lat != 40.0 || m.points[0].Lon != -70.0 {
+		t.Errorf("points after third click = %v, want just 40,-70", m.points)
+	}
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/beast"
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
+)
+
+// maxPauseBuffer caps the messages held while paused. The oldest are dropped
+// beyond it, so a long pause catches up on recent traffic only.
+const maxPauseBuffer = 200000
+
+// pausedMessage is a message held while paused, with the time it arrived
+type pausedMessage struct {
+	msg      *beast.Message
+	received time.Time
+}
+
+// handleMessage applies a received Mode S message, or holds it while the
+// display is paused or the buffer is still being caught up on
+func (a *App) handleMessage(msg *beast.Message) {
+	a.pauseMutex.Lock()
+	defer a.pauseMutex.Unlock()
+
+	if a.paused || a.replaying {
+		if len(a.pauseBuffer) >= maxPauseBuffer {
+			if a.pauseDropped == 0 {
+				eventlog.Printf("Warning: Pause buffer full, dropping the oldest messages\n")
+			}
+			a.pauseBuffer = a.pauseBuffer[1:]
+			a.pauseDropped++
+		}
+		a.pauseBuffer = append(a.pauseBuffer, pausedMessage{msg: msg, received: time.Now()})
+		return
+	}
+
+	a.processModeS(msg.Data, msg.Timestamp, msg.SignalLevel, time.Now())
+}
+
+// togglePause freezes or resumes the display. Messages keep arriving while
+// paused and are applied in order on resume, in the background so the
+// display isn't held up.
+func (a *App) togglePause() {
+	a.pauseMutex.Lock()
+	defer a.pauseMutex.Unlock()
+
+	if !a.paused {
+		a.paused = true
+		eventlog.Printf("Display paused\n")
+		return
+	}
+
+	a.paused = false
+	eventlog.Printf("Display resumed, applying %d buffered messages (%d dropped)\n",
+		len(a.pauseBuffer), a.pauseDropped)
+	a.pauseDropped = 0
+
+	// A replay interrupted by pausing again may still be finishing a batch
+	if !a.replaying {
+		a.replaying = true
+		a.spawn(a.replayPaused)
+	}
+}
+
+// replayPaused applies buffered messages until the buffer is empty or the
+// display is paused again. New messages queue behind the buffer meanwhile,
+// so everything is applied in order. Messages older than the removal TTL
+// are skipped, as the aircraft they describe would already be gone.
+func (a *App) replayPaused() {
+	maxAge := time.Duration(max(a.config.TTLFor(false), a.config.TTLFor(true))) * time.Second
+	expired := 0
+
+	for a.ctx.Err() == nil {
+		a.pauseMutex.Lock()
+		if a.paused || len(a.pauseBuffer) == 0 {
+			a.replaying = false
+			a.pauseMutex.Unlock()
+			break
+		}
+		batch := a.pauseBuffer
+		a.pauseBuffer = nil
+		a.pauseMutex.Unlock()
+
+		for _, p := range batch {
+			if maxAge > 0 && time.Since(p.received) > maxAge {
+				expired++
+				continue
+			}
+			a.processModeS(p.msg.Data, p.msg.Timestamp, p.msg.SignalLevel, p.received)
+		}
+	}
+
+	if expired > 0 {
+		eventlog.Printf("Skipped %d buffered messages older than the aircraft TTL\n", expired)
+	}
+}
+
+// pauseState reports whether the display is paused and how many messages are waiting
+func (a *App) pauseState() (bool, int) {
+	a.pauseMutex.Lock()
+	defer a.pauseMutex.Unlock()
+
+	return a.paused, len(a.pauseBuffer)
+}
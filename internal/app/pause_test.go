@@ -0,0 +1,174 @@
+package app
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/config"
+	"github.com/OJPARKINSON/viz1090/internal/sim"
+)
+
+// waitBuffered waits until n messages are held in the pause buffer
+func (p *beastPipe) waitBuffered(n int) {
+	p.t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, buffered := p.app.pauseState(); buffered == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			p.t.Fatalf("pause buffer never reached %d messages", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitForReplay waits until the messages buffered while paused are applied
+func (p *beastPipe) waitForReplay() {
+	p.t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		p.app.pauseMutex.Lock()
+		replaying := p.app.replaying
+		p.app.pauseMutex.Unlock()
+		if !replaying {
+			return
+		}
+		if time.Now().After(deadline) {
+			p.t.Fatal("replay did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// backdate moves the receive time of the newest buffered message back by d
+func (p *beastPipe) backdate(d time.Duration) {
+	p.app.pauseMutex.Lock()
+	defer p.app.pauseMutex.Unlock()
+
+	last := &p.app.pauseBuffer[len(p.app.pauseBuffer)-1]
+	last.received = last.received.Add(-d)
+}
+
+func TestPauseDropsOldestPastCap(t *testing.T) {
+	const (
+		dropped = 10
+		early   = 0x000AAA // Only heard in frames that are dropped
+		kept    = 0x4840D6
+	)
+
+	p := newBeastPipe(t, nil)
+	p.app.togglePause()
+
+	// Send the whole backlog in one write, the last frame renaming the
+	// kept aircraft so the final state shows the buffer was applied in order
+	var feed []byte
+	for i := 0; i < dropped; i++ {
+		feed = append(feed, sim.EncodeBeastMessage(sim.ModeLong, sim.CreateADSBIdentMessage(early, "EARLY1"), 0, 150)...)
+	}
+	for i := 0; i < maxPauseBuffer; i++ {
+		flight := "KLM1023"
+		if i == maxPauseBuffer-1 {
+			flight = "KLM1024"
+		}
+		feed = append(feed, sim.EncodeBeastMessage(sim.ModeLong, sim.CreateADSBIdentMessage(kept, flight), 0, 150)...)
+	}
+	p.feed.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := p.feed.Write(feed); err != nil {
+		t.Fatalf("write frames: %v", err)
+	}
+
+	// The last frame is buffered as the final drop is counted
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		p.app.pauseMutex.Lock()
+		n := p.app.pauseDropped
+		p.app.pauseMutex.Unlock()
+		if n == dropped {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("dropped %d messages, want %d", n, dropped)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if paused, buffered := p.app.pauseState(); !paused || buffered != maxPauseBuffer {
+		t.Fatalf("paused=%v buffered=%d, want paused with %d", paused, buffered, maxPauseBuffer)
+	}
+	if p.app.aircraft.Len() != 0 {
+		t.Fatalf("tracking %d aircraft while paused", p.app.aircraft.Len())
+	}
+
+	p.app.togglePause()
+	p.waitForReplay()
+	p.close()
+
+	if paused, buffered := p.app.pauseState(); paused || buffered != 0 {
+		t.Errorf("paused=%v buffered=%d after resume", paused, buffered)
+	}
+	if p.app.aircraft.Get(early) != nil {
+		t.Errorf("aircraft %06X from dropped messages was applied", early)
+	}
+	a := p.app.aircraft.Get(kept)
+	if a == nil {
+		t.Fatalf("aircraft %06X not created", kept)
+	}
+	if a.Flight != "KLM1024" {
+		t.Errorf("Flight = %q, want KLM1024 from the last message", a.Flight)
+	}
+	if a.Messages != maxPauseBuffer {
+		t.Errorf("Messages = %d, want %d", a.Messages, maxPauseBuffer)
+	}
+}
+
+func TestPauseReplayKeepsReceiveTimes(t *testing.T) {
+	const (
+		icao    = 0x40621D
+		expired = 0x000AAA
+	)
+	even, _ := hex.DecodeString("8D40621D58C382D690C8AC2863A7")
+	odd, _ := hex.DecodeString("8D40621D58C386435CC412692AD6")
+
+	cfg := config.DefaultConfig()
+	cfg.DisplayTTL = 60
+	p := newBeastPipe(t, cfg)
+	p.app.togglePause()
+
+	// An aircraft last heard before the TTL is never brought back
+	p.send(sim.CreateADSBIdentMessage(expired, "EARLY1"))
+	p.waitBuffered(1)
+	p.backdate(2 * time.Minute)
+
+	// An even frame received 30s before the odd one, far outside the CPR
+	// pair window, though both are applied together on resume
+	p.send(even)
+	p.waitBuffered(2)
+	p.backdate(30 * time.Second)
+	p.send(odd)
+	p.waitBuffered(3)
+
+	p.app.togglePause()
+	p.waitForReplay()
+	p.close()
+
+	if p.app.aircraft.Get(expired) != nil {
+		t.Errorf("aircraft %06X from an expired message was applied", expired)
+	}
+	a := p.app.aircraft.Get(icao)
+	if a == nil {
+		t.Fatalf("aircraft %06X not created", icao)
+	}
+	if a.HasPosition {
+		t.Errorf("decoded %.4f,%.4f from frames received 30s apart", a.Lat, a.Lon)
+	}
+	if age := time.Since(a.Seen); age > 5*time.Second {
+		t.Errorf("Seen %v ago, want the odd frame's receive time", age)
+	}
+	if a.EvenCPRTime > a.OddCPRTime-20000 {
+		t.Errorf("CPR times %d and %d are not 30s apart", a.EvenCPRTime, a.OddCPRTime)
+	}
+}
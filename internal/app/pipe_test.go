@@ -0,0 +1,345 @@
+package app
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+	"github.com/OJPARKINSON/viz1090/internal/config"
+	"github.com/OJPARKINSON/viz1090/internal/sim"
+)
+
+// beastPipe connects a headless App to an in-memory Beast feed, so tests can
+// inject frames and inspect the resulting aircraft state without sockets
+type beastPipe struct {
+	t    *testing.T
+	app  *App
+	feed net.Conn // Simulator end of the pipe
+	done chan struct{}
+}
+
+// newBeastPipe starts a headless App reading from one end of a net.Pipe.
+// cfg may be nil for the defaults.
+func newBeastPipe(t *testing.T, cfg *config.Config) *beastPipe {
+	t.Helper()
+
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	cfg.Headless = true
+
+	a := New(cfg)
+	if err := a.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	feed, conn := net.Pipe()
+	a.connected.Store(true)
+
+	p := &beastPipe{t: t, app: a, feed: feed, done: make(chan struct{})}
+	go func() {
+		a.receiveBeastData(a.ctx, conn)
+		close(p.done)
+	}()
+	t.Cleanup(p.close)
+	return p
+}
+
+// send writes Mode S frames to the app as Beast messages
+func (p *beastPipe) send(frames ...[]byte) {
+	p.t.Helper()
+
+	for _, frame := range frames {
+		msgType := sim.ModeLong
+		if len(frame) == 7 {
+			msgType = sim.ModeShort
+		}
+		p.feed.SetWriteDeadline(time.Now().Add(time.Second))
+		if _, err := p.feed.Write(sim.EncodeBeastMessage(msgType, frame, 0, 150)); err != nil {
+			p.t.Fatalf("write frame: %v", err)
+		}
+	}
+}
+
+// close ends the feed and waits until every frame sent has been processed
+func (p *beastPipe) close() {
+	p.feed.Close()
+	select {
+	case <-p.done:
+	case <-time.After(time.Second):
+		p.t.Fatal("receiver did not stop after the feed closed")
+	}
+	p.app.shutdown()
+}
+
+func TestBeastPipeDecodesFlight(t *testing.T) {
+	p := newBeastPipe(t, nil)
+
+	// The simulator's CPR encoding is simplified, so positions come from a
+	// recorded even/odd pair
+	const icao = 0x40621D
+	even, _ := hex.DecodeString("8D40621D58C382D690C8AC2863A7")
+	odd, _ := hex.DecodeString("8D40621D58C386435CC412692AD6")
+	p.send(
+		sim.CreateADSBIdentMessage(icao, "KLM1023"),
+		even,
+		odd,
+		sim.CreateADSBVelocityMessage(icao, 450, 90, -640),
+	)
+	p.close()
+
+	a := p.app.aircraft.Get(icao)
+	if a == nil {
+		t.Fatalf("aircraft %06X not created", icao)
+	}
+	if a.Flight != "KLM1023" {
+		t.Errorf("Flight = %q, want KLM1023", a.Flight)
+	}
+	if !a.HasPosition || math.Abs(a.Lat-52.2657) > 0.001 || math.Abs(a.Lon-3.9389) > 0.001 {
+		t.Errorf("position = %v %.4f,%.4f, want 52.2657,3.9389", a.HasPosition, a.Lat, a.Lon)
+	}
+	if !a.HasAltitude || a.Altitude != 38000 {
+		t.Errorf("Altitude = %d, want 38000", a.Altitude)
+	}
+	if math.Abs(float64(a.Speed-450)) > 1 {
+		t.Errorf("Speed = %d, want 450", a.Speed)
+	}
+	if a.Messages != 4 {
+		t.Errorf("Messages = %d, want 4", a.Messages)
+	}
+	if p.app.aircraft.Len() != 1 {
+		t.Errorf("tracking %d aircraft, want 1", p.app.aircraft.Len())
+	}
+}
+
+// withCPRLat returns a copy of an airborne position frame carrying a
+// different CPR latitude, with its CRC recomputed
+func withCPRLat(frame []byte, lat int) []byte {
+	out := append([]byte(nil), frame...)
+	out[6] = out[6]&^0x03 | byte(lat>>15)&0x03
+	out[7] = byte(lat >> 7)
+	out[8] = out[8]&0x01 | byte(lat<<1)
+	crc := adsb.ModeSChecksum(out)
+	out[11], out[12], out[13] = byte(crc>>16), byte(crc>>8), byte(crc)
+	return out
+}
+
+func TestCPRPairsLatestOppositeParity(t *testing.T) {
+	const icao = 0x40621D
+	even, _ := hex.DecodeString("8D40621D58C382D690C8AC2863A7")
+	odd, _ := hex.DecodeString("8D40621D58C386435CC412692AD6")
+
+	// An older even frame that would put the aircraft elsewhere if paired
+	evenLat, evenLon, _ := adsb.DecodeCPRFields(even)
+	oddLat, oddLon, _ := adsb.DecodeCPRFields(odd)
+	staleLat := (evenLat + 40000) % (1 << 17)
+	if lat, _, ok := adsb.DecodeCPRPosition(staleLat, evenLon, oddLat, oddLon, true); ok && math.Abs(lat-52.2657) < 0.1 {
+		t.Fatalf("stale even frame decodes to the same position, test frame is no use")
+	}
+
+	p := newBeastPipe(t, nil)
+	p.send(withCPRLat(even, staleLat), even)
+	p.close()
+
+	a := p.app.aircraft.Get(icao)
+	if a == nil || a.HasPosition {
+		t.Fatal("two even frames decoded a position")
+	}
+
+	p = newBeastPipe(t, nil)
+	p.send(withCPRLat(even, staleLat), even, odd)
+	p.close()
+
+	a = p.app.aircraft.Get(icao)
+	if !a.HasPosition || math.Abs(a.Lat-52.2657) > 0.001 || math.Abs(a.Lon-3.9389) > 0.001 {
+		t.Errorf("position = %v %.4f,%.4f, want 52.2657,3.9389 from the latest even", a.HasPosition, a.Lat, a.Lon)
+	}
+}
+
+func TestCorruptCallsignKeepsFlight(t *testing.T) {
+	const icao = 0x4840D6
+	good, _ := hex.DecodeString("8D4840D6202CC371C32CE0576098")
+
+	// A frame that passes CRC but decodes to "K?M1023"
+	corrupt := append([]byte(nil), good...)
+	corrupt[5], corrupt[6] = 0x2D, 0xB3
+	crc := adsb.ModeSChecksum(corrupt)
+	corrupt[11], corrupt[12], corrupt[13] = byte(crc>>16), byte(crc>>8), byte(crc)
+
+	p := newBeastPipe(t, nil)
+	p.send(good, corrupt)
+	p.close()
+
+	a := p.app.aircraft.Get(icao)
+	if a == nil {
+		t.Fatalf("aircraft %06X not created", icao)
+	}
+	if a.Flight != "KLM1023" {
+		t.Errorf("flight after a corrupt ident = %q, want KLM1023 kept", a.Flight)
+	}
+}
+
+// withCA returns a copy of a DF17 frame with a different capability field,
+// with its CRC recomputed
+func withCA(frame []byte, ca byte) []byte {
+	out := append([]byte(nil), frame...)
+	out[0] = out[0]&^0x07 | ca
+	crc := adsb.ModeSChecksum(out)
+	out[11], out[12], out[13] = byte(crc>>16), byte(crc>>8), byte(crc)
+	return out
+}
+
+func TestAirborneTrailsOnlyStopsOnGround(t *testing.T) {
+	const icao = 0x40621D
+	even, _ := hex.DecodeString("8D40621D58C382D690C8AC2863A7")
+	odd, _ := hex.DecodeString("8D40621D58C386435CC412692AD6")
+
+	cfg := config.DefaultConfig()
+	cfg.AirborneTrailsOnly = true
+	cfg.TrailMinDist = 0
+	cfg.TrailMinSecs = 0
+
+	// Airborne, then taxiing with airborne position type codes
+	p := newBeastPipe(t, cfg)
+	p.send(even, odd, withCA(even, adsb.CAGround), withCA(odd, adsb.CAGround))
+	p.close()
+
+	a := p.app.aircraft.Get(icao)
+	if a == nil || !a.OnGround {
+		t.Fatal("aircraft not on the ground after CA reported it")
+	}
+	if len(a.Trail) != 0 {
+		t.Errorf("trail has %d points on the ground, want none", len(a.Trail))
+	}
+
+	// Taking off again starts a fresh trail
+	p = newBeastPipe(t, cfg)
+	p.send(withCA(even, adsb.CAGround), withCA(odd, adsb.CAGround), even, odd)
+	p.close()
+
+	a = p.app.aircraft.Get(icao)
+	if a.OnGround || len(a.Trail) != 2 {
+		t.Errorf("OnGround %v with %d trail points, want airborne with 2", a.OnGround, len(a.Trail))
+	}
+}
+
+// altitudeReply builds a 56-bit DF4 surveillance altitude reply from icao
+// reporting alt in 25ft increments, with the address overlaid on the parity
+func altitudeReply(icao uint32, alt int) []byte {
+	n := (alt + 1000) / 25
+	ac13 := (n&0x7E0)<<2 | (n&0x10)<<1 | 0x10 | n&0x0F
+	frame := []byte{4 << 3, 0, byte(ac13 >> 8), byte(ac13), 0, 0, 0}
+	ap := adsb.ModeSChecksum(frame) ^ icao
+	frame[4], frame[5], frame[6] = byte(ap>>16), byte(ap>>8), byte(ap)
+	return frame
+}
+
+func TestFrameLengthsFollowDF(t *testing.T) {
+	const icao = 0x40621D
+	even, _ := hex.DecodeString("8D40621D58C382D690C8AC2863A7")
+	odd, _ := hex.DecodeString("8D40621D58C386435CC412692AD6")
+	df4 := altitudeReply(icao, 39000)
+	if len(df4) != adsb.FrameLength(adsb.DF4) || len(even) != adsb.FrameLength(adsb.DF17) {
+		t.Fatalf("frame lengths %d and %d, want 7 and 14", len(df4), len(even))
+	}
+
+	p := newBeastPipe(t, nil)
+	p.send(
+		even, odd,
+		df4,
+		append(append([]byte(nil), df4...), make([]byte, 7)...), // DF4 in a long frame
+		even[:7], // Truncated DF17
+	)
+	p.close()
+
+	a := p.app.aircraft.Get(icao)
+	if a == nil || !a.HasPosition {
+		t.Fatal("14-byte DF17 pair did not decode a position")
+	}
+	if a.Altitude != 39000 {
+		t.Errorf("Altitude = %d, want 39000 from the 7-byte DF4", a.Altitude)
+	}
+	if p.app.shortFrames != 2 {
+		t.Errorf("dropped %d frames, want the 2 whose length doesn't match their DF", p.app.shortFrames)
+	}
+}
+
+// coarseTISB builds a DF18 CF=3 coarse TIS-B airborne position for addr,
+// flagged as a track file number rather than an ICAO address when trackFile
+func coarseTISB(addr uint32, trackFile bool) []byte {
+	frame := []byte{18<<3 | 3, byte(addr >> 16), byte(addr >> 8), byte(addr), 0x4C, 0x32, 0x80, 0x1E, 0x62, 0x74, 0x50, 0, 0, 0}
+	if trackFile {
+		frame[4] |= 0x80 // IMF
+	}
+	crc := adsb.ModeSChecksum(frame)
+	frame[11], frame[12], frame[13] = byte(crc>>16), byte(crc>>8), byte(crc)
+	return frame
+}
+
+func TestTISBTrackFileKeptOutOfICAORange(t *testing.T) {
+	const trackNumber = 0x000123
+
+	p := newBeastPipe(t, nil)
+	p.send(coarseTISB(trackNumber, true))
+	p.close()
+
+	if a := p.app.aircraft.Get(trackNumber); a != nil {
+		t.Fatalf("track file number %06X created an aircraft at that ICAO address", trackNumber)
+	}
+	p.app.aircraft.ForEach(func(key uint32, a *adsb.Aircraft) {
+		if key <= 0xFFFFFF {
+			t.Errorf("aircraft created in the ICAO range at %06X", key)
+		}
+	})
+	a := p.app.aircraft.Get(trackNumber | adsb.TrackFileFlag)
+	if a == nil || a.AddrType != adsb.AddrTISBTrackfile {
+		t.Fatal("track file not tracked under its own key")
+	}
+	if got := adsb.FormatAddress(a.ICAO); got != "~000123" {
+		t.Errorf("track file shown as %q, want ~000123", got)
+	}
+}
+
+func TestTISBTrackFilesSuppressed(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.TrackTISBTrackFiles = false
+	p := newBeastPipe(t, cfg)
+	p.send(coarseTISB(0x000123, true), coarseTISB(0xA1B2C3, false))
+	p.close()
+
+	if n := p.app.aircraft.Len(); n != 1 || p.app.aircraft.Get(0xA1B2C3) == nil {
+		t.Errorf("tracking %d aircraft, want only the ICAO addressed TIS-B target", n)
+	}
+}
+
+func TestRecordFileCapturesFeed(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.RecordFile = filepath.Join(t.TempDir(), "feed.beast")
+	p := newBeastPipe(t, cfg)
+
+	const icao = 0x40621D
+	frames := [][]byte{
+		sim.CreateADSBIdentMessage(icao, "KLM1023"),
+		sim.CreateADSBVelocityMessage(icao, 450, 90, -640),
+	}
+	var want []byte
+	for _, frame := range frames {
+		want = append(want, sim.EncodeBeastMessage(sim.ModeLong, frame, 0, 150)...)
+	}
+	p.send(frames...)
+	p.close()
+
+	got, err := os.ReadFile(cfg.RecordFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("recorded %d bytes, want the %d bytes sent", len(got), len(want))
+	}
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// receiverInfo is the part of dump1090's receiver.json holding the antenna location
+type receiverInfo struct {
+	Lat *float64 `json:"lat"`
+	Lon *float64 `json:"lon"`
+}
+
+// loadReceiverLocation reads the receiver location from a dump1090-style
+// receiver.json, given as an http(s) URL or a file path
+func loadReceiverLocation(source string) (lat, lon float64, err error) {
+	var body []byte
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		client := &http.Client{Timeout: 5 * time.Second}
+		resp, err := client.Get(source)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to fetch %s: %v", source, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return 0, 0, fmt.Errorf("failed to fetch %s: %s", source, resp.Status)
+		}
+		body, err = io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to read %s: %v", source, err)
+		}
+	} else {
+		body, err = os.ReadFile(source)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to read %s: %v", source, err)
+		}
+	}
+
+	var info receiverInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse %s: %v", source, err)
+	}
+	if info.Lat == nil || info.Lon == nil {
+		return 0, 0, fmt.Errorf("%s has no receiver location", source)
+	}
+	if *info.Lat < -90 || *info.Lat > 90 || *info.Lon < -180 || *info.Lon > 180 {
+		return 0, 0, fmt.Errorf("%s has an invalid receiver location %.4f,%.4f", source, *info.Lat, *info.Lon)
+	}
+
+	return *info.Lat, *info.Lon, nil
+}
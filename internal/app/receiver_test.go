@@ -0,0 +1,64 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadReceiverLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		lat, lon float64
+		ok       bool
+	}{
+		{"dump1090", `{"version":"7.2","refresh":1000,"history":120,"lat":51.4706,"lon":-0.4619}`, 51.4706, -0.4619, true},
+		{"null-island", `{"lat":0,"lon":0}`, 0, 0, true},
+		{"no-location", `{"version":"7.2","refresh":1000}`, 0, 0, false},
+		{"no-lon", `{"lat":51.4706}`, 0, 0, false},
+		{"bad-lat", `{"lat":91,"lon":0}`, 0, 0, false},
+		{"bad-lon", `{"lat":0,"lon":-180.5}`, 0, 0, false},
+		{"not-json", `<html></html>`, 0, 0, false},
+	}
+
+	dir := t.TempDir()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, tt := range tests {
+			if r.URL.Path == "/"+tt.name {
+				w.Write([]byte(tt.body))
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name+".json")
+		if err := os.WriteFile(path, []byte(tt.body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, source := range []string{path, server.URL + "/" + tt.name} {
+			lat, lon, err := loadReceiverLocation(source)
+			if (err == nil) != tt.ok {
+				t.Errorf("%s: err = %v, want ok %v", source, err, tt.ok)
+				continue
+			}
+			if lat != tt.lat || lon != tt.lon {
+				t.Errorf("%s: got %v,%v, want %v,%v", source, lat, lon, tt.lat, tt.lon)
+			}
+		}
+	}
+
+	// Missing files and failed requests are errors
+	if _, _, err := loadReceiverLocation(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("missing file loaded")
+	}
+	if _, _, err := loadReceiverLocation(server.URL + "/missing"); err == nil {
+		t.Error("404 response loaded")
+	}
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
+)
+
+// recorder copies the raw Beast stream to a file, so a session can be
+// replayed or attached to a bug report
+type recorder struct {
+	file   *os.File
+	w      *bufio.Writer
+	failed bool // A write failed and recording stopped
+	mutex  sync.Mutex
+}
+
+// openRecorder opens a recording file, appending to it if it exists
+func openRecorder(path string) (*recorder, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open record file: %v", err)
+	}
+	return &recorder{file: f, w: bufio.NewWriter(f)}, nil
+}
+
+// Write records received bytes. It never fails, so a full disk stops the
+// recording rather than the feed.
+func (r *recorder) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.failed {
+		if _, err := r.w.Write(p); err != nil {
+			r.fail(err)
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes buffered data out to the file
+func (r *recorder) Flush() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.failed {
+		if err := r.w.Flush(); err != nil {
+			r.fail(err)
+		}
+	}
+}
+
+// Close flushes and closes the file
+func (r *recorder) Close() error {
+	r.Flush()
+	return r.file.Close()
+}
+
+// fail stops recording after a write error. The caller must hold r.mutex.
+func (r *recorder) fail(err error) {
+	eventlog.Printf("Warning: Recording stopped: %v\n", err)
+	r.failed = true
+}
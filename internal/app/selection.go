@@ -0,0 +1,125 @@
+package app
+
+import (
+	"sort"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+// pickAircraft returns the aircraft whose symbol is closest to screen
+// position (x, y) within radius pixels. Failing that, an aircraft whose label
+// box contains the point is picked, so labels work as larger touch targets.
+// 0 means nothing was hit.
+func pickAircraft(aircraft map[uint32]*adsb.Aircraft, v viewport, x, y int, radius float64) uint32 {
+	candidates := pickCandidates(aircraft, v, x, y, radius)
+	if len(candidates) == 0 {
+		return 0
+	}
+	return candidates[0]
+}
+
+// pickCandidates returns every aircraft whose symbol is within radius pixels
+// of (x, y), nearest first, followed by those whose label box contains the point
+func pickCandidates(aircraft map[uint32]*adsb.Aircraft, v viewport, x, y int, radius float64) []uint32 {
+	type hit struct {
+		icao     uint32
+		distance float64
+	}
+	var symbolHits []hit
+	var labelHits []uint32
+
+	for icao, a := range aircraft {
+		// Skip aircraft without position
+		if !a.HasPosition {
+			continue
+		}
+
+		// Calculate squared distance from the aircraft's screen position
+		ax, ay := v.latLonToPixel(a.Lat, a.Lon)
+		dx := float64(ax - x)
+		dy := float64(ay - y)
+		if distSquared := dx*dx + dy*dy; distSquared <= radius*radius {
+			symbolHits = append(symbolHits, hit{icao, distSquared})
+			continue
+		}
+
+		// Labels that are drawn are hit targets too
+		if a.LabelW > 0 && a.LabelH > 0 && a.LabelOpacity > 0 && a.LabelLevel < 1.5 && !a.Stacked &&
+			float64(x) >= a.LabelX && float64(x) <= a.LabelX+a.LabelW &&
+			float64(y) >= a.LabelY && float64(y) <= a.LabelY+a.LabelH {
+			labelHits = append(labelHits, icao)
+		}
+	}
+
+	sort.Slice(symbolHits, func(i, j int) bool {
+		if symbolHits[i].distance != symbolHits[j].distance {
+			return symbolHits[i].distance < symbolHits[j].distance
+		}
+		return symbolHits[i].icao < symbolHits[j].icao
+	})
+	sort.Slice(labelHits, func(i, j int) bool { return labelHits[i] < labelHits[j] })
+
+	candidates := make([]uint32, 0, len(symbolHits)+len(labelHits))
+	for _, h := range symbolHits {
+		candidates = append(candidates, h.icao)
+	}
+	return append(candidates, labelHits...)
+}
+
+// clickCycleTolerance is how far in pixels, at UI scale 1, a click may be
+// from the last one and still cycle to the next candidate
+const clickCycleTolerance = 4
+
+// clickCycle steps through the candidates under repeated clicks at the same
+// spot, so aircraft hidden behind another can be reached
+type clickCycle struct {
+	x, y       int
+	index      int
+	candidates []uint32
+}
+
+// pick returns the aircraft to select for a click at (x, y). A click within
+// tolerance pixels of the last one, over the same aircraft, advances to the
+// next candidate; anything else starts over with the first.
+func (c *clickCycle) pick(x, y int, candidates []uint32, tolerance int) uint32 {
+	if len(candidates) == 0 {
+		c.candidates = nil
+		return 0
+	}
+
+	near := abs(x-c.x) <= tolerance && abs(y-c.y) <= tolerance
+	if near && sameSet(candidates, c.candidates) {
+		// Keep the original order so small pointer moves don't reshuffle it
+		c.index = (c.index + 1) % len(c.candidates)
+	} else {
+		c.x, c.y = x, y
+		c.index = 0
+		c.candidates = candidates
+	}
+	return c.candidates[c.index]
+}
+
+// sameSet reports whether a and b hold the same addresses in any order
+func sameSet(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[uint32]bool, len(a))
+	for _, icao := range a {
+		seen[icao] = true
+	}
+	for _, icao := range b {
+		if !seen[icao] {
+			return false
+		}
+	}
+	return true
+}
+
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,149 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+	"github.com/OJPARKINSON/viz1090/internal/config"
+)
+
+func TestPickAircraftByLabel(t *testing.T) {
+	v := viewport{centerLat: 51.5, centerLon: 0, maxDistance: 50, width: 800, height: 600}
+	am := adsb.NewAircraftMap(0)
+
+	// Two aircraft with labels placed away from their symbols
+	for _, ac := range []struct {
+		icao     uint32
+		lat, lon float64
+		labelX   float64
+		labelY   float64
+	}{
+		{0x000001, 51.5, 0.0, 500, 100},
+		{0x000002, 51.2, -0.5, 100, 450},
+	} {
+		a := am.GetOrCreate(ac.icao)
+		a.Lat, a.Lon, a.HasPosition = ac.lat, ac.lon, true
+		a.LabelX, a.LabelY, a.LabelW, a.LabelH = ac.labelX, ac.labelY, 100, 40
+		a.LabelOpacity = 1
+	}
+	aircraft := am.Copy()
+
+	tests := []struct {
+		name string
+		x, y int
+		want uint32
+	}{
+		{"inside first label", 550, 120, 0x000001},
+		{"inside second label", 110, 480, 0x000002},
+		{"label edge", 600, 140, 0x000001},
+		{"on first symbol", 402, 301, 0x000001},
+		{"empty space", 700, 550, 0},
+	}
+
+	for _, tt := range tests {
+		if got := pickAircraft(aircraft, v, tt.x, tt.y, 20); got != tt.want {
+			t.Errorf("%s: picked %06X, want %06X", tt.name, got, tt.want)
+		}
+	}
+
+	// Hidden labels are not hit targets
+	aircraft[0x000001].LabelLevel = 2
+	if got := pickAircraft(aircraft, v, 550, 120, 20); got != 0 {
+		t.Errorf("hidden label picked %06X", got)
+	}
+}
+
+func TestPickAircraftRadius(t *testing.T) {
+	v := viewport{centerLat: 0, centerLon: 0, maxDistance: 50, width: 800, height: 600}
+	am := adsb.NewAircraftMap(0)
+	a := am.GetOrCreate(0xABCDEF)
+	a.HasPosition = true
+
+	// 30px from the symbol at the screen center
+	if got := pickAircraft(am.Copy(), v, 430, 300, 20); got != 0 {
+		t.Errorf("picked %06X outside a 20px radius", got)
+	}
+	if got := pickAircraft(am.Copy(), v, 430, 300, 40); got != 0xABCDEF {
+		t.Errorf("picked %06X, want ABCDEF inside a 40px radius", got)
+	}
+}
+
+func TestClickCycle(t *testing.T) {
+	v := viewport{centerLat: 0, centerLon: 0, maxDistance: 50, width: 800, height: 600}
+	am := adsb.NewAircraftMap(0)
+	for _, icao := range []uint32{0x000003, 0x000001, 0x000002} {
+		a := am.GetOrCreate(icao)
+		a.HasPosition = true
+	}
+	aircraft := am.Copy()
+
+	var c clickCycle
+	click := func(x, y int) uint32 {
+		return c.pick(x, y, pickCandidates(aircraft, v, x, y, 20), 4)
+	}
+
+	// Repeated clicks walk the stack and wrap around
+	var got []uint32
+	for i := 0; i < 4; i++ {
+		got = append(got, click(400, 300))
+	}
+	want := []uint32{0x000001, 0x000002, 0x000003, 0x000001}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("clicks selected %06X, want %06X", got, want)
+		}
+	}
+
+	// A small pointer move keeps cycling, a larger one starts over
+	if icao := click(402, 301); icao != 0x000002 {
+		t.Errorf("nearby click selected %06X, want 000002", icao)
+	}
+	if icao := click(410, 300); icao != 0x000001 {
+		t.Errorf("click elsewhere selected %06X, want 000001", icao)
+	}
+
+	// Empty space clears the cycle
+	if icao := click(700, 50); icao != 0 {
+		t.Errorf("empty click selected %06X", icao)
+	}
+	if icao := click(700, 50); icao != 0 {
+		t.Errorf("second empty click selected %06X", icao)
+	}
+}
+
+func TestSelectionChangeDropsSelectedTrail(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ShowTrails = false
+	cfg.SelectedTrail = true
+	a := New(cfg)
+
+	first := a.aircraft.GetOrCreate(0x000001)
+	second := a.aircraft.GetOrCreate(0x000002)
+	for _, ac := range []*adsb.Aircraft{first, second} {
+		ac.AddTrailPoint(adsb.Position{Lat: 51.5, Timestamp: time.Now()})
+	}
+
+	a.setSelected(0x000001)
+	a.setSelected(0x000002)
+	if first.Trail != nil {
+		t.Errorf("previous selection kept %d trail points", len(first.Trail))
+	}
+	if second.Trail == nil {
+		t.Error("new selection lost its trail")
+	}
+
+	a.setSelected(0)
+	if second.Trail != nil {
+		t.Errorf("deselected aircraft kept %d trail points", len(second.Trail))
+	}
+
+	// Trails stay when every aircraft records one
+	a.config.ShowTrails = true
+	first.AddTrailPoint(adsb.Position{Lat: 51.5, Timestamp: time.Now()})
+	a.setSelected(0x000001)
+	a.setSelected(0)
+	if first.Trail == nil {
+		t.Error("trail dropped on deselect while trails are shown")
+	}
+}
@@ -0,0 +1,51 @@
+package app
+
+// viewMode decides what the map is centered on each frame
+type viewMode int
+
+// View modes, in the order the view key cycles through them
+const (
+	viewFree     viewMode = iota // Centered wherever the user panned to
+	viewReceiver                 // North up, centered on the receiver
+	viewFollow                   // Centered on the selected aircraft
+)
+
+// String returns the name shown in the status row
+func (m viewMode) String() string {
+	switch m {
+	case viewReceiver:
+		return "rcvr"
+	case viewFollow:
+		return "follow"
+	default:
+		return "free"
+	}
+}
+
+// next returns the mode the view key switches to
+func (m viewMode) next() viewMode {
+	return (m + 1) % (viewFollow + 1)
+}
+
+// cycleViewMode switches to the next view mode
+func (a *App) cycleViewMode() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.viewMode = a.viewMode.next()
+	a.applyViewMode()
+}
+
+// applyViewMode moves the map center for the current view mode. Following
+// holds the view still while nothing with a position is selected. The
+// caller must hold a.mutex.
+func (a *App) applyViewMode() {
+	switch a.viewMode {
+	case viewReceiver:
+		a.centerLat, a.centerLon = a.config.InitialLat, a.config.InitialLon
+	case viewFollow:
+		if aircraft := a.aircraft.Get(a.selectedICAO); aircraft != nil && aircraft.HasPosition {
+			a.centerLat, a.centerLon = aircraft.Lat, aircraft.Lon
+		}
+	}
+}
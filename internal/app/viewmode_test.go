@@ -0,0 +1,43 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/OJPARKINSON/viz1090/internal/config"
+)
+
+func TestViewModeCycle(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.InitialLat, cfg.InitialLon = 51.47, -0.45
+	a := New(cfg)
+	a.centerLat, a.centerLon = 40.0, -70.0
+
+	// Free pan leaves the view where it is
+	a.applyViewMode()
+	if a.centerLat != 40.0 || a.centerLon != -70.0 {
+		t.Errorf("free view moved to %.2f,%.2f", a.centerLat, a.centerLon)
+	}
+
+	a.cycleViewMode()
+	if a.viewMode != viewReceiver || a.centerLat != 51.47 || a.centerLon != -0.45 {
+		t.Errorf("receiver view %v centered at %.2f,%.2f, want rcvr at 51.47,-0.45", a.viewMode, a.centerLat, a.centerLon)
+	}
+
+	// Following without a selection holds the view
+	a.cycleViewMode()
+	if a.viewMode != viewFollow || a.centerLat != 51.47 {
+		t.Errorf("follow view %v without a selection moved to %.2f,%.2f", a.viewMode, a.centerLat, a.centerLon)
+	}
+
+	aircraft := a.aircraft.GetOrCreate(0x40621D)
+	aircraft.Lat, aircraft.Lon, aircraft.HasPosition = 52.2, 3.9, true
+	a.selectedICAO = 0x40621D
+	a.applyViewMode()
+	if a.centerLat != 52.2 || a.centerLon != 3.9 {
+		t.Errorf("follow view centered at %.2f,%.2f, want the selected aircraft at 52.2,3.9", a.centerLat, a.centerLon)
+	}
+
+	if a.cycleViewMode(); a.viewMode != viewFree {
+		t.Errorf("view mode after follow = %v, want free", a.viewMode)
+	}
+}
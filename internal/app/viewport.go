@@ -0,0 +1,88 @@
+package app
+
+import (
+	"math"
+
+	"github.com/OJPARKINSON/viz1090/internal/map_system"
+)
+
+// viewport describes the visible map area: the screen size in pixels, the
+// geographic center and the distance in NM from the center to the top edge
+type viewport struct {
+	centerLat   float64
+	centerLon   float64
+	maxDistance float64
+	width       int
+	height      int
+	projection  map_system.ProjectionKind
+}
+
+// project returns the map projection about the view center
+func (v viewport) project() map_system.Projection {
+	return map_system.NewProjection(v.projection, v.centerLat, v.centerLon)
+}
+
+// pixelToLatLon converts screen coordinates to latitude/longitude
+func (v viewport) pixelToLatLon(x, y int) (float64, float64) {
+	// Scale the offset from the center to NM, inverting Y because screen
+	// coordinates increase downward
+	scale := v.maxDistance / float64(v.height/2)
+	dx := float64(x-v.width/2) * scale
+	dy := -float64(y-v.height/2) * scale
+
+	return v.project().Inverse(dx, dy)
+}
+
+// latLonToPixel converts latitude/longitude to screen coordinates
+func (v viewport) latLonToPixel(lat, lon float64) (int, int) {
+	x, y := v.latLonToScreen(lat, lon)
+	return int(x), int(y)
+}
+
+// latLonToScreen converts latitude/longitude to unrounded screen coordinates
+func (v viewport) latLonToScreen(lat, lon float64) (float64, float64) {
+	// Offset from center in nautical miles
+	east, north := v.project().Forward(lat, lon)
+
+	// Scale to screen coordinates
+	scale := float64(v.height/2) / v.maxDistance
+
+	return float64(v.width)/2.0 + east*scale, float64(v.height)/2.0 - north*scale
+}
+
+// clampZoom limits a view radius in NM to [min, max], where a limit of 0 is
+// ignored
+func clampZoom(distance, min, max float64) float64 {
+	if min > 0 && distance < min {
+		return min
+	}
+	if max > 0 && distance > max {
+		return max
+	}
+	return distance
+}
+
+// zoomAt returns the viewport scaled by factor with the center moved so the
+// point under screen position (x, y) ends up at (toX, toY)
+func (v viewport) zoomAt(x, y int, toX, toY, factor float64) viewport {
+	lat, lon := v.pixelToLatLon(x, y)
+
+	zoomed := v
+	zoomed.maxDistance *= factor
+
+	// The center that keeps the point in place depends on the projection
+	// about that center, so refine it by moving the center along with the
+	// point's drift until the drift vanishes
+	for i := 0; i < 50; i++ {
+		sx, sy := zoomed.latLonToScreen(lat, lon)
+		driftX, driftY := sx-toX, sy-toY
+		if math.Abs(driftX) < 1e-9 && math.Abs(driftY) < 1e-9 {
+			break
+		}
+
+		scale := zoomed.maxDistance / float64(v.height/2)
+		zoomed.centerLat, zoomed.centerLon = zoomed.project().Inverse(driftX*scale, -driftY*scale)
+	}
+
+	return zoomed
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"math"
+	"testing"
+
+	"github.com/OJPARKINSON/viz1090/internal/config"
+	"github.com/OJPARKINSON/viz1090/internal/map_system"
+)
+
+func TestZoomAtKeepsCursorPoint(t *testing.T) {
+	v := viewport{centerLat: 51.47, centerLon: -0.45, maxDistance: 50, width: 1280, height: 720}
+
+	cursors := [][2]int{{640, 360}, {0, 0}, {1279, 719}, {100, 600}, {1000, 50}}
+	for _, cursor := range cursors {
+		for _, factor := range []float64{0.8, 1.25, 0.5} {
+			x, y := cursor[0], cursor[1]
+			lat, lon := v.pixelToLatLon(x, y)
+
+			zoomed := v.zoomAt(x, y, float64(x), float64(y), factor)
+			if zoomed.maxDistance != v.maxDistance*factor {
+				t.Errorf("maxDistance = %v, want %v", zoomed.maxDistance, v.maxDistance*factor)
+			}
+
+			gotLat, gotLon := zoomed.pixelToLatLon(x, y)
+			if math.Abs(gotLat-lat) > 1e-9 || math.Abs(gotLon-lon) > 1e-9 {
+				t.Errorf("cursor (%d,%d) zoom %v: point moved from %.6f,%.6f to %.6f,%.6f",
+					x, y, factor, lat, lon, gotLat, gotLon)
+			}
+
+			sx, sy := zoomed.latLonToScreen(lat, lon)
+			if math.Abs(sx-float64(x)) > 1e-6 || math.Abs(sy-float64(y)) > 1e-6 {
+				t.Errorf("cursor (%d,%d) zoom %v: point projects to %.3f,%.3f", x, y, factor, sx, sy)
+			}
+		}
+	}
+}
+
+func TestZoomStopsAtLimits(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.MinZoom, cfg.MaxZoom = 0.5, 5000
+	a := New(cfg)
+
+	for i := 0; i < 100; i++ {
+		a.zoomBy(0.8)
+	}
+	if a.maxDistance != 0.5 {
+		t.Errorf("zoomed in to %v NM, want to stop at 0.5", a.maxDistance)
+	}
+
+	for i := 0; i < 100; i++ {
+		a.zoomBy(1.25)
+	}
+	if a.maxDistance != 5000 {
+		t.Errorf("zoomed out to %v NM, want to stop at 5000", a.maxDistance)
+	}
+
+	// Zero limits leave the zoom unbounded
+	if got := clampZoom(0.01, 0, 0); got != 0.01 {
+		t.Errorf("clampZoom without limits = %v", got)
+	}
+}
+
+func TestViewportAcrossAntiMeridian(t *testing.T) {
+	v := viewport{centerLat: 0, centerLon: 179, maxDistance: 200, width: 800, height: 600}
+
+	// 2 degrees east across the dateline is 120 NM right of center
+	x, y := v.latLonToScreen(0, -179)
+	if want := 400 + 120*300/200.0; math.Abs(x-want) > 1e-6 || math.Abs(y-300) > 1e-6 {
+		t.Errorf("-179 projects to %.2f,%.2f, want %.2f,300", x, y, want)
+	}
+
+	// And the inverse wraps back into -180..180
+	lat, lon := v.pixelToLatLon(580, 300)
+	if math.Abs(lat) > 1e-9 || math.Abs(lon-(-179)) > 1e-9 {
+		t.Errorf("pixel right of center maps to %.4f,%.4f, want 0,-179", lat, lon)
+	}
+}
+
+func TestZoomAtKeepsCursorPointAzimuthal(t *testing.T) {
+	v := viewport{centerLat: 70, centerLon: 20, maxDistance: 1500, width: 1280, height: 720,
+		projection: map_system.AzimuthalEquidistant}
+
+	for _, cursor := range [][2]int{{0, 0}, {1279, 719}, {100, 600}} {
+		x, y := cursor[0], cursor[1]
+		lat, lon := v.pixelToLatLon(x, y)
+
+		sx, sy := v.zoomAt(x, y, float64(x), float64(y), 0.5).latLonToScreen(lat, lon)
+		if math.Abs(sx-float64(x)) > 1e-6 || math.Abs(sy-float64(y)) > 1e-6 {
+			t.Errorf("cursor (%d,%d): point projects to %.3f,%.3f after zooming", x, y, sx, sy)
+		}
+	}
+}
+
+func TestZoomAtRecenters(t *testing.T) {
+	for _, kind := range []map_system.ProjectionKind{map_system.Equirectangular, map_system.AzimuthalEquidistant} {
+		v := viewport{centerLat: 51.47, centerLon: -0.45, maxDistance: 50, width: 1280, height: 720, projection: kind}
+
+		for _, cursor := range [][2]int{{0, 0}, {1279, 719}, {100, 600}} {
+			x, y := cursor[0], cursor[1]
+			lat, lon := v.pixelToLatLon(x, y)
+
+			zoomed := v.zoomAt(x, y, 640, 360, 0.5)
+			if math.Abs(zoomed.centerLat-lat) > 1e-9 || math.Abs(zoomed.centerLon-lon) > 1e-9 {
+				t.Errorf("%v cursor (%d,%d): centered on %.6f,%.6f, want %.6f,%.6f",
+					kind, x, y, zoomed.centerLat, zoomed.centerLon, lat, lon)
+			}
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
+)
+
+// checkWatchlist logs watched aircraft the first time they are seen, and
+// forgets aircraft that have since been removed so a return is logged again
+func (a *App) checkWatchlist() {
+	if !a.config.WatchAlert || a.watchlist.Len() == 0 {
+		return
+	}
+
+	current := make(map[uint32]bool, len(a.watchSeen))
+	a.aircraft.ForEach(func(icao uint32, aircraft *adsb.Aircraft) {
+		entry, ok := a.watchlist.Match(icao, aircraft.Flight)
+		if !ok {
+			return
+		}
+		current[icao] = true
+		if !a.watchSeen[icao] {
+			eventlog.Printf("Watchlist: %s %s %s\n", adsb.FormatAddress(icao), aircraft.Flight, entry.Label)
+		}
+	})
+	a.watchSeen = current
+}
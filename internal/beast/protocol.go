@@ -7,9 +7,10 @@ import (
 // Beast protocol constants
 const (
 	// Message types
-	ModeAC    = '1' // Mode A/C message
-	ModeShort = '2' // Mode S short message
-	ModeLong  = '3' // Mode S long message
+	ModeAC     = '1' // Mode A/C message
+	ModeShort  = '2' // Mode S short message
+	ModeLong   = '3' // Mode S long message
+	ModeStatus = '4' // Receiver status frame (Radarcape DIP switches and GPS state)
 
 	// Message lengths (excluding escape bytes)
 	ModeACLen    = 2
@@ -18,6 +19,19 @@ const (
 
 	// Beast protocol constants
 	EscapeChar = 0x1A
+
+	// Command frames sent to the receiver are 0x1A + '1' + setting, where
+	// uppercase enables and lowercase disables the emulated DIP switch
+	CommandType = '1'
+
+	SettingBinaryFormat = 'C' // Binary Beast output rather than AVR text
+	SettingAVRFormat    = 'c'
+	SettingDFFilterOn   = 'D' // Only forward DF11/DF17/DF18
+	SettingDFFilterOff  = 'd'
+	SettingMLATOn       = 'E' // Include MLAT timestamps
+	SettingMLATOff      = 'e'
+	SettingModeACOn     = 'J' // Forward Mode A/C replies
+	SettingModeACOff    = 'j'
 )
 
 // Message represents a decoded Beast protocol message
@@ -31,111 +45,111 @@ type Message struct {
 // Decoder reads and decodes Beast format messages from an io.Reader
 type Decoder struct {
 	r        io.Reader
+	readBuf  []byte
 	buffer   []byte
 	msgBuf   []byte
 	escaping bool
+	err      error
 }
 
 // NewDecoder creates a new Beast protocol decoder
 func NewDecoder(r io.Reader) *Decoder {
 	return &Decoder{
-		r:      r,
-		buffer: make([]byte, 0, 256),
-		msgBuf: make([]byte, 0, 32),
+		r:       r,
+		readBuf: make([]byte, 4096),
+		msgBuf:  make([]byte, 0, 32),
 	}
 }
 
-// ReadMessage reads and decodes the next Beast message
+// ReadMessage reads and decodes the next Beast message. Frames may be split
+// arbitrarily across reads; ReadMessage blocks until a complete frame has
+// arrived and only returns an error once the underlying reader fails.
 func (d *Decoder) ReadMessage() (*Message, error) {
-	// Read more data if buffer is empty
-	if len(d.buffer) == 0 {
-		buf := make([]byte, 4096)
-		n, err := d.r.Read(buf)
-		if err != nil {
-			return nil, err
+	for {
+		if msg := d.decodeBuffered(); msg != nil {
+			return msg, nil
+		}
+
+		// Buffer is exhausted without a complete frame, report any earlier read error
+		if d.err != nil {
+			return nil, d.err
 		}
-		d.buffer = append(d.buffer, buf[:n]...)
+
+		// Block for more data, keeping partial frame state in msgBuf
+		n, err := d.r.Read(d.readBuf)
+		d.buffer = d.readBuf[:n]
+		d.err = err
 	}
+}
 
-	// Process buffer until we have a complete message
+// decodeBuffered consumes buffered bytes until a complete message is found,
+// returning nil if the buffer runs out first. Bytes outside a frame are skipped
+// until the next 0x1A + type start marker, which resyncs after garbage.
+func (d *Decoder) decodeBuffered() *Message {
+	// Escape state is kept on the decoder so a 0x1A split across two reads is
+	// resolved correctly.
 	for len(d.buffer) > 0 {
 		b := d.buffer[0]
 		d.buffer = d.buffer[1:]
 
 		if d.escaping {
 			// Previous byte was an escape character
-			d.msgBuf = append(d.msgBuf, b)
 			d.escaping = false
-		} else if b == EscapeChar {
-			// Current byte is an escape character
-			if len(d.buffer) == 0 {
-				// Need more data to see if this is an escape sequence
-				d.buffer = append([]byte{EscapeChar}, d.buffer...)
-				return nil, io.ErrUnexpectedEOF
-			}
 
-			// Look at next byte
-			nextByte := d.buffer[0]
-			if nextByte == '1' || nextByte == '2' || nextByte == '3' {
-				// Start of a new message
-				if len(d.msgBuf) > 0 {
-					// Process the message we've collected so far
-					msg, err := d.parseMessage()
-					if err == nil {
-						return msg, nil
-					}
-					// If we couldn't parse it, just start a new one
+			switch b {
+			case EscapeChar:
+				// Doubled escape is a literal 0x1A data byte
+				if len(d.msgBuf) == 0 {
+					continue
 				}
-
-				// Start new message buffer
-				d.msgBuf = []byte{EscapeChar, nextByte}
-				d.buffer = d.buffer[1:]
-			} else {
-				// Escaped data byte
-				d.escaping = true
-			}
-		} else {
-			// Regular data byte
-			d.msgBuf = append(d.msgBuf, b)
-		}
-
-		// Check if we have enough data for a complete message
-		if len(d.msgBuf) >= 2 {
-			// First byte should be escape char, second is message type
-			if d.msgBuf[0] != EscapeChar {
-				// Invalid format, reset and try again
-				d.msgBuf = d.msgBuf[:0]
+				d.msgBuf = append(d.msgBuf, b)
+			case ModeAC, ModeShort, ModeLong, ModeStatus:
+				// Start of a new message; anything collected so far was a truncated frame
+				d.msgBuf = append(d.msgBuf[:0], EscapeChar, b)
 				continue
-			}
-
-			var expectedLen int
-			switch d.msgBuf[1] {
-			case ModeAC:
-				expectedLen = 2 + 6 + 1 + ModeACLen // 0x1A + type + timestamp + signal + MODEAC
-			case ModeShort:
-				expectedLen = 2 + 6 + 1 + ModeShortLen // 0x1A + type + timestamp + signal + Short Mode S
-			case ModeLong:
-				expectedLen = 2 + 6 + 1 + ModeLongLen // 0x1A + type + timestamp + signal + Long Mode S
 			default:
-				// Invalid message type, reset
+				// Not a frame we understand, drop it and wait for the next start marker
 				d.msgBuf = d.msgBuf[:0]
 				continue
 			}
+		} else if b == EscapeChar {
+			// Current byte is an escape character, meaning depends on the next byte
+			d.escaping = true
+			continue
+		} else {
+			// Regular data byte, ignored unless we're inside a frame
+			if len(d.msgBuf) == 0 {
+				continue
+			}
+			d.msgBuf = append(d.msgBuf, b)
+		}
 
-			// Check if we have a full message
-			if len(d.msgBuf) >= expectedLen {
-				msg, err := d.parseMessage()
-				d.msgBuf = d.msgBuf[:0]
-				if err != nil {
-					continue // Try to find next valid message
-				}
-				return msg, nil
+		// Check if we have a full message
+		if len(d.msgBuf) >= frameLength(d.msgBuf[1]) {
+			msg, err := d.parseMessage()
+			d.msgBuf = d.msgBuf[:0]
+			if err != nil {
+				continue // Try to find next valid message
 			}
+			return msg
 		}
 	}
 
 	// Need more data
-	return nil, io.ErrUnexpectedEOF
+	return nil
+}
+
+// frameLength returns the unescaped length of a frame of the given type:
+// 0x1A + type + 6-byte timestamp + signal level + Mode A/C or Mode S data
+func frameLength(msgType byte) int {
+	switch msgType {
+	case ModeAC:
+		return 2 + 6 + 1 + ModeACLen
+	case ModeShort:
+		return 2 + 6 + 1 + ModeShortLen
+	default: // Mode S long and status frames
+		return 2 + 6 + 1 + ModeLongLen
+	}
 }
 
 // parseMessage extracts fields from the message buffer
@@ -146,7 +160,7 @@ func (d *Decoder) parseMessage() (*Message, error) {
 
 	// Get message type
 	msgType := d.msgBuf[1]
-	if msgType != ModeAC && msgType != ModeShort && msgType != ModeLong {
+	if msgType != ModeAC && msgType != ModeShort && msgType != ModeLong && msgType != ModeStatus {
 		return nil, io.ErrUnexpectedEOF
 	}
 
@@ -157,7 +171,7 @@ func (d *Decoder) parseMessage() (*Message, error) {
 		dataLen = ModeACLen
 	case ModeShort:
 		dataLen = ModeShortLen
-	case ModeLong:
+	case ModeLong, ModeStatus:
 		dataLen = ModeLongLen
 	}
 
@@ -225,3 +239,13 @@ func EncodeMessage(msgType byte, data []byte, timestamp uint64, signalLevel byte
 
 	return buf
 }
+
+// EncodeSettings builds the command frames for a string of DIP switch
+// settings, e.g. "Cd" for binary output without the DF filter
+func EncodeSettings(settings string) []byte {
+	buf := make([]byte, 0, 3*len(settings))
+	for i := 0; i < len(settings); i++ {
+		buf = append(buf, EscapeChar, CommandType, settings[i])
+	}
+	return buf
+}
@@ -0,0 +1,219 @@
+package beast
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+// chunkReader returns its data in the given chunks, one per Read call
+type chunkReader struct {
+	chunks [][]byte
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if len(c.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, c.chunks[0])
+	c.chunks[0] = c.chunks[0][n:]
+	if len(c.chunks[0]) == 0 {
+		c.chunks = c.chunks[1:]
+	}
+	return n, nil
+}
+
+// readAllMessages decodes messages until the reader is exhausted
+func readAllMessages(t *testing.T, d *Decoder) []*Message {
+	t.Helper()
+
+	var msgs []*Message
+	for {
+		msg, err := d.ReadMessage()
+		if errors.Is(err, io.EOF) {
+			return msgs
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+type testFrame struct {
+	msgType   byte
+	data      []byte
+	timestamp uint64
+	signal    byte
+}
+
+func (f testFrame) encode() []byte {
+	return EncodeMessage(f.msgType, f.data, f.timestamp, f.signal)
+}
+
+func assertMessages(t *testing.T, got []*Message, want []testFrame) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %d messages, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].Type != want[i].msgType {
+			t.Errorf("message %d: type %q, want %q", i, got[i].Type, want[i].msgType)
+		}
+		if got[i].Timestamp != want[i].timestamp/12000000 {
+			t.Errorf("message %d: timestamp %d, want %d", i, got[i].Timestamp, want[i].timestamp/12000000)
+		}
+		if got[i].SignalLevel != want[i].signal {
+			t.Errorf("message %d: signal %#x, want %#x", i, got[i].SignalLevel, want[i].signal)
+		}
+		if !bytes.Equal(got[i].Data, want[i].data) {
+			t.Errorf("message %d: data % x, want % x", i, got[i].Data, want[i].data)
+		}
+	}
+}
+
+// escapeFrames covers doubled escapes in every part of a frame, including a
+// final data byte of 0x1A immediately followed by the next frame's start marker
+var escapeFrames = []testFrame{
+	{ModeLong, []byte{0x8D, 0x1A, 0x2B, 0x3C, 0x1A, 0x1A, 0, 1, 2, 3, 4, 5, 6, 7}, 0x1A1A001A1A1A, 0x80},
+	{ModeShort, []byte{0x5D, 0x4B, 0x1A, 0x00, 0x00, 0x00, 0x1A}, 0x000102030405, 0x1A},
+	{ModeLong, []byte{0x8D, 0x40, 0x62, 0x1D, 0x58, 0xC3, 0x82, 0xD6, 0x90, 0xC8, 0xAC, 0x28, 0x63, 0xA7}, 0x1A0000000000, 0x1A},
+	{ModeAC, []byte{0x1A, 0x1A}, 0x00000000001A, 0x20},
+}
+
+func encodeAll(frames []testFrame) []byte {
+	var stream []byte
+	for _, f := range frames {
+		stream = append(stream, f.encode()...)
+	}
+	return stream
+}
+
+func TestDecodeDoubledEscapes(t *testing.T) {
+	d := NewDecoder(bytes.NewReader(encodeAll(escapeFrames)))
+	assertMessages(t, readAllMessages(t, d), escapeFrames)
+}
+
+func TestDecodeEscapeSplitAcrossReads(t *testing.T) {
+	stream := encodeAll(escapeFrames)
+
+	// Split the stream at every offset, which includes splitting between the
+	// two bytes of each doubled escape and between 0x1A and a frame type
+	for split := 1; split < len(stream); split++ {
+		first := append([]byte(nil), stream[:split]...)
+		second := append([]byte(nil), stream[split:]...)
+
+		d := NewDecoder(&chunkReader{chunks: [][]byte{first, second}})
+		msgs := readAllMessages(t, d)
+		if len(msgs) != len(escapeFrames) {
+			t.Fatalf("split at %d: expected %d messages, got %d", split, len(escapeFrames), len(msgs))
+		}
+		assertMessages(t, msgs, escapeFrames)
+	}
+}
+
+func TestDecodeResyncAfterTruncatedFrame(t *testing.T) {
+	good := []testFrame{
+		{ModeLong, []byte{0x8D, 0x40, 0x62, 0x1D, 0x58, 0xC3, 0x82, 0xD6, 0x90, 0xC8, 0xAC, 0x28, 0x63, 0xA7}, 0x000102030405, 0x90},
+		{ModeShort, []byte{0x5D, 0x4B, 0x1A, 0x00, 0x00, 0x00, 0x1A}, 0x000102030406, 0x91},
+	}
+
+	truncated := testFrame{ModeLong, []byte{0x8D, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13}, 0x000102030404, 0x80}.encode()
+
+	var stream []byte
+	stream = append(stream, 0x00, 0xFF, 0x33) // Leading garbage
+	stream = append(stream, truncated[:12]...)
+	stream = append(stream, good[0].encode()...)
+	stream = append(stream, truncated[:5]...)
+	stream = append(stream, good[1].encode()...)
+
+	d := NewDecoder(bytes.NewReader(stream))
+	assertMessages(t, readAllMessages(t, d), good)
+}
+
+func TestDecodeOneByteAtATime(t *testing.T) {
+	truncated := testFrame{ModeLong, []byte{0x8D, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13}, 0x000102030404, 0x80}.encode()
+
+	var stream []byte
+	stream = append(stream, 0x42, 0x1A, 0x39, 0x00) // Garbage, including an unknown 0x1A frame type
+	stream = append(stream, encodeAll(escapeFrames)...)
+	stream = append(stream, truncated[:10]...)
+	stream = append(stream, encodeAll(escapeFrames)...)
+
+	whole := readAllMessages(t, NewDecoder(bytes.NewReader(stream)))
+	byteWise := readAllMessages(t, NewDecoder(iotest.OneByteReader(bytes.NewReader(stream))))
+
+	if len(whole) != 2*len(escapeFrames) {
+		t.Fatalf("expected %d messages, got %d", 2*len(escapeFrames), len(whole))
+	}
+	if len(byteWise) != len(whole) {
+		t.Fatalf("byte-at-a-time decoded %d messages, all-at-once decoded %d", len(byteWise), len(whole))
+	}
+	for i := range whole {
+		if whole[i].Type != byteWise[i].Type ||
+			whole[i].Timestamp != byteWise[i].Timestamp ||
+			whole[i].SignalLevel != byteWise[i].SignalLevel ||
+			!bytes.Equal(whole[i].Data, byteWise[i].Data) {
+			t.Errorf("message %d differs: %+v vs %+v", i, whole[i], byteWise[i])
+		}
+	}
+}
+
+func TestDecodeReturnsReadErrorAfterBufferedFrames(t *testing.T) {
+	frame := escapeFrames[0]
+
+	// The reader delivers the last frame together with io.EOF
+	d := NewDecoder(iotest.DataErrReader(bytes.NewReader(frame.encode())))
+
+	msg, err := d.ReadMessage()
+	if err != nil {
+		t.Fatalf("expected buffered frame before error, got %v", err)
+	}
+	assertMessages(t, []*Message{msg}, []testFrame{frame})
+
+	if _, err := d.ReadMessage(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestDecodeStatusFrame(t *testing.T) {
+	frames := []testFrame{
+		escapeFrames[0],
+		{ModeStatus, []byte{0x1A, 0x00, 0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, 0x000102030405, 0x00},
+		escapeFrames[1],
+	}
+
+	d := NewDecoder(bytes.NewReader(encodeAll(frames)))
+	assertMessages(t, readAllMessages(t, d), frames)
+}
+
+func TestEncodeSettings(t *testing.T) {
+	got := EncodeSettings("CdE")
+	want := []byte{0x1A, '1', 'C', 0x1A, '1', 'd', 0x1A, '1', 'E'}
+	if !bytes.Equal(got, want) {
+		t.Errorf("EncodeSettings(\"CdE\") = % x, want % x", got, want)
+	}
+
+	if got := EncodeSettings(""); len(got) != 0 {
+		t.Errorf("expected no bytes for empty settings, got % x", got)
+	}
+}
+
+func TestDecodeShortAndLongModeS(t *testing.T) {
+	frames := []testFrame{
+		{ModeShort, []byte{0x20, 0x00, 0x06, 0x10, 0x4C, 0x7B, 0x3E}, 0x000000000100, 0x40},                                          // DF4
+		{ModeLong, []byte{0x8D, 0x40, 0x62, 0x1D, 0x58, 0xC3, 0x82, 0xD6, 0x90, 0xC8, 0xAC, 0x28, 0x63, 0xA7}, 0x000000000200, 0x40}, // DF17
+	}
+
+	d := NewDecoder(bytes.NewReader(encodeAll(frames)))
+	msgs := readAllMessages(t, d)
+	assertMessages(t, msgs, frames)
+	for i, want := range []int{ModeShortLen, ModeLongLen} {
+		if len(msgs[i].Data) != want {
+			t.Errorf("message %d: %d data bytes, want %d", i, len(msgs[i].Data), want)
+		}
+	}
+}
@@ -1,50 +1,325 @@
 package config
 
+// WatchEntry is how an aircraft on the watchlist is highlighted
+type WatchEntry struct {
+	Color string // Symbol color as #rrggbb, empty for the default watch color
+	Label string // Text added to the aircraft's label, empty for none
+}
+
+// MapLayer is a line layer of the vector map
+type MapLayer struct {
+	Name  string
+	File  string // Binary line geometry from the map converter, may be gzip compressed
+	Color string // Line color as #rrggbb
+}
+
 // Config stores application configuration settings
 type Config struct {
 	// Network settings
 	ServerAddress string
 	ServerPort    int
+	Demo          bool   // Run the built-in simulator instead of connecting to a receiver
+	BeastSettings string // DIP switch settings sent after connecting, e.g. "CdE", empty to send none
+	APIAddr       string // Listen address for the HTTP aircraft API, e.g. ":8080", empty to disable
+	ReceiverURL   string // dump1090 receiver.json URL or file giving the antenna location, empty to use InitialLat/InitialLon
+	JSONL         string // Write each decoded message as a JSON line to this file, "-" for stdout, empty to disable
+	RecordFile    string // Append the raw Beast feed to this file, empty to disable
+	AircraftDB    string // CSV of ICAO address, registration, type and operator, empty to disable
+
+	// Seconds without messages before the feed is considered dead and the
+	// connection is reopened, 0 to disable. DataTimeoutBlank greys out the
+	// display while the feed is silent.
+	DataTimeoutSeconds int
+	DataTimeoutBlank   bool
 
 	// Display settings
-	ScreenWidth  int
-	ScreenHeight int
-	Fullscreen   bool
-	UIScale      int
-	Metric       bool
+	Headless           bool   // Run without a window, decoding and serving the API only
+	Title              string // Base window title
+	ScreenWidth        int
+	ScreenHeight       int
+	Fullscreen         bool
+	Display            int // Monitor to open the window on, sized to it when fullscreen
+	UIScale            int
+	Metric             bool
+	TransitionAltitude int    // Altitudes in feet at or above this are shown as flight levels, 0 for always feet
+	VertRateThreshold  int    // Vertical rate in ft/min from which a climb or descent arrow follows the altitude, 0 to disable
+	SelectRadius       int    // Click selection radius in pixels at UI scale 1
+	MaxFPS             int    // Frame rate cap, 0 to leave pacing to vsync
+	ShowFPS            bool   // Show the frame rate in the status bar
+	ShowPerfHUD        bool   // Show a graph of recent frame times broken down by phase
+	FontPath           string // TTF font file, empty for the bundled Terminus
+	FontSize           int    // Font size in points, 0 to derive from UIScale
+	AntiAlias          bool   // Draw smooth map, grid, trail and ring lines, at some cost in frame time
+
+	// Map projection: "equirectangular", or "azimuthal" for true distance and
+	// bearing from the view center over wide areas and near the poles
+	Projection string
+
+	// Vector map line layers, drawn in order
+	MapLayers []MapLayer
+
+	// Map index shape: quadtree nodes below MapMaxDepth aren't split, and a
+	// node is split only once it holds more than MapNodeCapacity lines, 0 to
+	// split on every line
+	MapMaxDepth     int
+	MapNodeCapacity int
+
+	// Raster base map, a PNG or JPEG with north-up equirectangular
+	// projection. Bounds are in degrees at the image edges; when all zero
+	// they are read from the image's world file (e.g. map.pgw).
+	BaseImage      string
+	BaseImageNorth float64
+	BaseImageSouth float64
+	BaseImageWest  float64
+	BaseImageEast  float64
+
+	// Local magnetic declination in degrees, east positive, applied to
+	// magnetic headings when no true track is available
+	MagneticDeclination float64
 
 	// Initial map settings
 	InitialLat  float64
 	InitialLon  float64
 	InitialZoom float64
+	MinZoom     float64 // Smallest view radius in NM, 0 for no limit
+	MaxZoom     float64 // Largest view radius in NM, 0 for no limit
+
+	// Select the aircraft with this ICAO hex address or callsign when it
+	// appears, and center on it with InitialFollow
+	InitialSelect string
+	InitialFollow bool
 
 	// Visualization options
-	ShowTrails  bool
-	TrailLength int
-	LabelDetail int
-	DisplayTTL  int
+	ShowTrails         bool
+	SelectedTrail      bool // Keep recording and drawing the selected aircraft's trail when ShowTrails is off
+	AirborneTrailsOnly bool // Drop trails on landing and record none while on the ground
+	TrailLength        int
+	TrailWidth         int     // Trail line width in pixels
+	TrailMinDist       float64 // Minimum movement in NM between trail points
+	TrailMinSecs       int     // Seconds after which a trail point is added regardless of movement, 0 to disable
+	SymbolScale        float64 // Aircraft symbol size relative to the UI scale
+	LabelDetail        int
+	CompactLabels      bool    // Draw a one-line flight and altitude tag instead of the boxed label
+	LabelShowType      bool    // Add the aircraft type from AircraftDB after the callsign
+	LabelFullNM        float64 // Distance from the view center beyond which labels show only the callsign, 0 to disable
+	LabelHideNM        float64 // Distance from the view center beyond which labels are hidden, 0 to disable
+	DisplayTTL         int
+	ShowGraticule      bool
+	ShowReceiver       bool
+	ShowAccuracy       bool
+
+	// Where new labels start before overlaps are resolved: "auto" for the
+	// least crowded side of the symbol, or "below", "above", "left" or
+	// "right", LabelOffset pixels away at UI scale 1
+	LabelPlacement string
+	LabelOffset    int
+
+	// Color aircraft by altitude. AltitudeColors is a JSON list of stops in
+	// increasing altitude order, e.g. [{"altitude": 0, "color": "#ff8c00"},
+	// {"altitude": 40000, "color": "#c850ff"}]; empty for the built-in gradient.
+	ColorByAltitude bool
+	AltitudeColors  string
+
+	// Dim aircraft symbols that few messages are received from, keeping
+	// their color
+	BrightnessByRate bool
+
+	// Seconds without a position before an aircraft is drawn as a ghost, 0 to disable
+	GhostSeconds int
+
+	// Predicted loss of separation overlay
+	ShowConflicts     bool
+	ConflictNM        float64 // Horizontal separation minimum in NM
+	ConflictFeet      int     // Vertical separation minimum in feet
+	ConflictLookahead int     // Prediction window in seconds
+
+	// Seconds without a message before an aircraft starts fading, and how
+	// long the fade takes. The fade is shortened to finish before removal.
+	FadeStartSeconds    int
+	FadeDurationSeconds int
+
+	// Per-category display TTLs in seconds, 0 to use DisplayTTL
+	DisplayTTLAirborne int
+	DisplayTTLGround   int
+
+	// TTL in seconds for TIS-B/ADS-R targets with anonymous addresses, 0 to
+	// use the airborne TTL
+	AnonymousTTL int
+
+	// Track TIS-B targets identified by a ground station track file number
+	// rather than an ICAO address. They are kept apart from real aircraft
+	// and shown with a "~" before the number.
+	TrackTISBTrackFiles bool
+
+	// Leave aircraft without a decoded position out of the display and counts
+	HideNoPosition bool
+
+	// Most aircraft tracked at once, 0 for no limit. Beyond it the least
+	// recently seen aircraft other than the selected one is dropped.
+	MaxAircraft int
+
+	// Draw wind barbs for aircraft reporting Comm-B meteorological data
+	ShowWind bool
+
+	// Ring aircraft for a few seconds after they squawk ident (SPI)
+	ShowIdent bool
+
+	// Add squawks to labels, and draw aircraft squawking the emergency codes
+	// 7500/7600/7700 in the emergency color
+	ShowSquawk               bool
+	HighlightEmergencySquawk bool
+
+	// Tag aircraft below ApproachFeet within ApproachNM of an airport with
+	// its code, ApproachNM 0 to disable
+	ApproachNM   float64
+	ApproachFeet int
+
+	// Altitude profile panel for the selected aircraft
+	ShowAltitudeProfile bool
+	ProfileSeconds      int
+
+	// Show recent events in an on-screen log pane
+	ShowLog bool
+
+	// Aircraft to highlight, keyed by ICAO hex address or callsign prefix.
+	// Entries from WatchlistFile are added to these; WatchAlert logs when a
+	// watched aircraft appears.
+	Watchlist     map[string]WatchEntry
+	WatchlistFile string
+	WatchAlert    bool
+
+	// Alert with a log entry, a screen flash and optionally the terminal bell
+	// when an aircraft starts matching the filters below after not matching
+	// for AlertDebounceSeconds, or at all with a zero debounce. Zero limits
+	// are not applied.
+	AlertOnNew           bool
+	AlertBell            bool
+	AlertWatchlistOnly   bool
+	AlertMaxNM           float64 // Distance from the receiver location
+	AlertMinFeet         int
+	AlertMaxFeet         int
+	AlertDebounceSeconds int
 
 	// Debug options
 	Debug bool
 }
 
+// TTLFor returns the removal TTL in seconds for an aircraft on the ground or
+// airborne, falling back to DisplayTTL when no category TTL is set
+func (c *Config) TTLFor(onGround bool) int {
+	if onGround && c.DisplayTTLGround > 0 {
+		return c.DisplayTTLGround
+	}
+	if !onGround && c.DisplayTTLAirborne > 0 {
+		return c.DisplayTTLAirborne
+	}
+	return c.DisplayTTL
+}
+
 // DefaultConfig returns a configuration with sensible defaults
 func DefaultConfig() *Config {
 	return &Config{
-		ServerAddress: "localhost",
-		ServerPort:    30005,
-		ScreenWidth:   0, // Auto-detect
-		ScreenHeight:  0, // Auto-detect
-		Fullscreen:    false,
-		UIScale:       1,
-		Metric:        false,
-		InitialLat:    37.6188,
-		InitialLon:    -122.3756,
-		InitialZoom:   50.0, // NM
-		ShowTrails:    true,
-		TrailLength:   50,
-		LabelDetail:   2,
-		DisplayTTL:    30,
-		Debug:         false,
+		ServerAddress:      "localhost",
+		ServerPort:         30005,
+		Demo:               false,
+		BeastSettings:      "",
+		APIAddr:            "",
+		ReceiverURL:        "",
+		JSONL:              "",
+		RecordFile:         "",
+		AircraftDB:         "",
+		DataTimeoutSeconds: 30,
+		DataTimeoutBlank:   true,
+		Headless:           false,
+		Title:              "viz1090-go",
+		ScreenWidth:        0, // Auto-detect
+		ScreenHeight:       0, // Auto-detect
+		Fullscreen:         false,
+		Display:            0,
+		UIScale:            1,
+		Metric:             false,
+		TransitionAltitude: 18000,
+		VertRateThreshold:  500,
+		SelectRadius:       20,
+		MaxFPS:             30,
+		ShowFPS:            false,
+		ShowPerfHUD:        false,
+		FontPath:           "",
+		FontSize:           0,
+		AntiAlias:          false,
+		Projection:         "equirectangular",
+		MapLayers: []MapLayer{
+			{Name: "map", File: "mapdata.bin", Color: "#21007a"},
+			{Name: "airports", File: "airportdata.bin", Color: "#5500ff"},
+		},
+		MapMaxDepth:              25,
+		MapNodeCapacity:          32,
+		BaseImage:                "",
+		MagneticDeclination:      0,
+		InitialLat:               37.6188,
+		InitialLon:               -122.3756,
+		InitialZoom:              50.0, // NM
+		MinZoom:                  0.5,
+		MaxZoom:                  5000,
+		InitialSelect:            "",
+		InitialFollow:            false,
+		ShowTrails:               true,
+		SelectedTrail:            true,
+		AirborneTrailsOnly:       false,
+		TrailLength:              50,
+		TrailWidth:               1,
+		TrailMinDist:             0.25,
+		TrailMinSecs:             15,
+		SymbolScale:              1.0,
+		LabelDetail:              2,
+		CompactLabels:            false,
+		LabelShowType:            false,
+		LabelFullNM:              40,
+		LabelHideNM:              0,
+		LabelPlacement:           "auto",
+		LabelOffset:              20,
+		DisplayTTL:               30,
+		FadeStartSeconds:         15,
+		FadeDurationSeconds:      15,
+		DisplayTTLAirborne:       0,
+		DisplayTTLGround:         0,
+		AnonymousTTL:             10,
+		TrackTISBTrackFiles:      true,
+		ShowGraticule:            false,
+		ShowReceiver:             true,
+		ShowAccuracy:             false,
+		ColorByAltitude:          false,
+		BrightnessByRate:         false,
+		AltitudeColors:           "",
+		GhostSeconds:             10,
+		ShowConflicts:            false,
+		ConflictNM:               3.0,
+		ConflictFeet:             1000,
+		ConflictLookahead:        120,
+		HideNoPosition:           false,
+		MaxAircraft:              0,
+		ShowWind:                 false,
+		ShowIdent:                true,
+		ShowSquawk:               false,
+		HighlightEmergencySquawk: true,
+		ApproachNM:               8.0,
+		ApproachFeet:             5000,
+		Debug:                    false,
+
+		ShowAltitudeProfile: false,
+		ProfileSeconds:      300,
+		ShowLog:             false,
+
+		Watchlist:     map[string]WatchEntry{},
+		WatchlistFile: "",
+		WatchAlert:    true,
+
+		AlertOnNew:           false,
+		AlertBell:            false,
+		AlertWatchlistOnly:   false,
+		AlertMaxNM:           0,
+		AlertMinFeet:         0,
+		AlertMaxFeet:         0,
+		AlertDebounceSeconds: 300,
 	}
 }
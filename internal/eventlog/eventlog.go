@@ -0,0 +1,102 @@
+package eventlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSize is the number of entries kept by the default log
+const DefaultSize = 100
+
+// Entry is a single timestamped log message
+type Entry struct {
+	Time time.Time
+	Text string
+}
+
+// Log keeps the most recent messages in a fixed size ring buffer
+type Log struct {
+	entries []Entry
+	next    int       // Index the next entry is written to
+	count   int       // Number of valid entries
+	out     io.Writer // Where Printf echoes messages
+	mutex   sync.Mutex
+}
+
+// New creates a log holding at most size entries, echoing to stdout
+func New(size int) *Log {
+	return &Log{entries: make([]Entry, max(size, 1)), out: os.Stdout}
+}
+
+// SetOutput sets where Printf echoes messages
+func (l *Log) SetOutput(w io.Writer) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.out = w
+}
+
+// Output returns where Printf echoes messages
+func (l *Log) Output() io.Writer {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.out
+}
+
+// Add records a message, overwriting the oldest once the log is full
+func (l *Log) Add(text string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.entries[l.next] = Entry{Time: time.Now(), Text: text}
+	l.next = (l.next + 1) % len(l.entries)
+	if l.count < len(l.entries) {
+		l.count++
+	}
+}
+
+// Printf writes a formatted message to the output and records it in the log
+func (l *Log) Printf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	fmt.Fprint(l.Output(), msg)
+	if text := strings.TrimSpace(msg); text != "" {
+		l.Add(text)
+	}
+}
+
+// Recent returns up to n of the newest entries, oldest first
+func (l *Log) Recent(n int) []Entry {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	n = min(n, l.count)
+	recent := make([]Entry, n)
+	start := l.next - n + len(l.entries)
+	for i := range recent {
+		recent[i] = l.entries[(start+i)%len(l.entries)]
+	}
+	return recent
+}
+
+// Default is the log shown in the on-screen event pane
+var Default = New(DefaultSize)
+
+// Printf writes a formatted message to the default log and its output
+func Printf(format string, args ...interface{}) {
+	Default.Printf(format, args...)
+}
+
+// SetOutput sets where the default log echoes messages, stdout until set
+func SetOutput(w io.Writer) {
+	Default.SetOutput(w)
+}
+
+// Output returns where the default log echoes messages
+func Output() io.Writer {
+	return Default.Output()
+}
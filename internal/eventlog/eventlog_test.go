@@ -0,0 +1,64 @@
+package eventlog
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// texts returns the text of each entry
+func texts(entries []Entry) []string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.Text)
+	}
+	return out
+}
+
+func TestRingWrapsOldestFirst(t *testing.T) {
+	l := New(3)
+	for i := 1; i <= 5; i++ {
+		l.Add(fmt.Sprintf("event %d", i))
+	}
+
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{10, []string{"event 3", "event 4", "event 5"}},
+		{3, []string{"event 3", "event 4", "event 5"}},
+		{2, []string{"event 4", "event 5"}},
+		{0, nil},
+	}
+	for _, tt := range tests {
+		if got := texts(l.Recent(tt.n)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Recent(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+
+	// Entries are in time order across the wrap
+	recent := l.Recent(3)
+	for i := 1; i < len(recent); i++ {
+		if recent[i].Time.Before(recent[i-1].Time) {
+			t.Errorf("entry %d is older than entry %d", i, i-1)
+		}
+	}
+}
+
+func TestPrintfEchoesAndRecords(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(2)
+	l.SetOutput(&buf)
+
+	l.Printf("Connected to %s\n", "localhost:30005")
+	l.Printf("\n")
+	if got := buf.String(); got != "Connected to localhost:30005\n\n" {
+		t.Errorf("output = %q", got)
+	}
+
+	// Blank messages are echoed but not kept
+	if got := texts(l.Recent(2)); !reflect.DeepEqual(got, []string{"Connected to localhost:30005"}) {
+		t.Errorf("Recent = %q", got)
+	}
+}
@@ -2,6 +2,8 @@ package map_system
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -9,6 +11,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
 )
 
 // Constants for the map system
@@ -45,118 +49,193 @@ type QuadTree struct {
 	LonMin float64
 	LonMax float64
 	Lines  []*Line
+	Labels []*MapLabel
 	NW     *QuadTree
 	NE     *QuadTree
 	SW     *QuadTree
 	SE     *QuadTree
 }
 
+// Layer is a named set of map lines, such as coastlines or roads, drawn in
+// a single color
+type Layer struct {
+	Name  string
+	Root  *QuadTree
+	Lines []*Line
+}
+
+// Default quadtree limits. A capacity of 0 splits nodes on every insert
+// down to the depth limit, which makes for a much larger and slower tree.
+const (
+	DefaultMaxDepth     = 25
+	DefaultNodeCapacity = 32
+)
+
 // Map contains all map data structures
 type Map struct {
-	Root         *QuadTree
-	AirportRoot  *QuadTree
-	MapLines     []*Line
-	AirportLines []*Line
+	Layers       []*Layer // Line layers in drawing order
 	PlaceNames   []*MapLabel
 	AirportNames []*MapLabel
+
+	airportTree *QuadTree // Index of AirportNames for NearestAirport
+
+	// Quadtree shape for layers loaded from now on. Nodes deeper than
+	// MaxDepth are not split, and a node is only split once it holds more
+	// than NodeCapacity lines.
+	MaxDepth     int
+	NodeCapacity int
 }
 
 // NewMap creates a new map instance
 func NewMap() *Map {
 	return &Map{
-		Root:         &QuadTree{LatMin: 180.0, LatMax: -180.0, LonMin: 180.0, LonMax: -180.0},
-		AirportRoot:  &QuadTree{LatMin: 180.0, LatMax: -180.0, LonMin: 180.0, LonMax: -180.0},
-		MapLines:     make([]*Line, 0),
-		AirportLines: make([]*Line, 0),
+		Layers:       make([]*Layer, 0),
 		PlaceNames:   make([]*MapLabel, 0),
 		AirportNames: make([]*MapLabel, 0),
+		MaxDepth:     DefaultMaxDepth,
+		NodeCapacity: DefaultNodeCapacity,
 	}
 }
 
-// LoadMapData loads map data from binary and text files
-func (m *Map) LoadMapData(mapDataFile, airportDataFile, placeNamesFile, airportNamesFile string) error {
-	// Just log errors but continue even if files are missing
-	if _, err := os.Stat(mapDataFile); os.IsNotExist(err) {
-		fmt.Printf("Warning: Map data file not found: %s\n", mapDataFile)
-	} else if err := m.loadMapGeometry(mapDataFile, &m.Root, &m.MapLines); err != nil {
-		fmt.Printf("Warning: Failed to load map data: %v\n", err)
+// newLayer creates an empty layer
+func newLayer(name string) *Layer {
+	return &Layer{
+		Name: name,
+		Root: &QuadTree{LatMin: 180.0, LatMax: -180.0, LonMin: 180.0, LonMax: -180.0},
+	}
+}
+
+// LoadLayer loads line geometry from a binary file as a layer drawn after
+// those already loaded. A gzip compressed copy is used if the file is missing.
+func (m *Map) LoadLayer(name, filename string) error {
+	filename = findDataFile(filename)
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return fmt.Errorf("%s layer file not found: %s", name, filename)
 	}
 
-	if _, err := os.Stat(airportDataFile); os.IsNotExist(err) {
-		fmt.Printf("Warning: Airport data file not found: %s\n", airportDataFile)
-	} else if err := m.loadMapGeometry(airportDataFile, &m.AirportRoot, &m.AirportLines); err != nil {
-		fmt.Printf("Warning: Failed to load airport data: %v\n", err)
+	layer := newLayer(name)
+	if err := m.loadMapGeometry(filename, &layer.Root, &layer.Lines); err != nil {
+		return fmt.Errorf("failed to load %s layer: %v", name, err)
 	}
+	m.Layers = append(m.Layers, layer)
+	return nil
+}
 
+// LoadLabels loads the place and airport names from text files, falling
+// back to gzip compressed copies of missing files
+func (m *Map) LoadLabels(placeNamesFile, airportNamesFile string) {
+	placeNamesFile = findDataFile(placeNamesFile)
+	airportNamesFile = findDataFile(airportNamesFile)
+
+	// Just log errors but continue even if files are missing
 	if _, err := os.Stat(placeNamesFile); os.IsNotExist(err) {
-		fmt.Printf("Warning: Place names file not found: %s\n", placeNamesFile)
+		eventlog.Printf("Warning: Place names file not found: %s\n", placeNamesFile)
 	} else if err := m.loadLabels(placeNamesFile, &m.PlaceNames); err != nil {
-		fmt.Printf("Warning: Failed to load place names: %v\n", err)
+		eventlog.Printf("Warning: Failed to load place names: %v\n", err)
 	}
 
 	if _, err := os.Stat(airportNamesFile); os.IsNotExist(err) {
-		fmt.Printf("Warning: Airport names file not found: %s\n", airportNamesFile)
+		eventlog.Printf("Warning: Airport names file not found: %s\n", airportNamesFile)
 	} else if err := m.loadLabels(airportNamesFile, &m.AirportNames); err != nil {
-		fmt.Printf("Warning: Failed to load airport names: %v\n", err)
+		eventlog.Printf("Warning: Failed to load airport names: %v\n", err)
 	}
+	m.indexAirports()
+}
 
-	// Continue even if nothing was loaded
-	return nil
+// indexAirports builds the quadtree NearestAirport searches from AirportNames
+func (m *Map) indexAirports() {
+	root := &QuadTree{LatMin: 180.0, LatMax: -180.0, LonMin: 180.0, LonMax: -180.0}
+	for _, label := range m.AirportNames {
+		root.extend(label.Location.Lat, label.Location.Lon)
+	}
+	for _, label := range m.AirportNames {
+		m.insertLabel(root, label, 0)
+	}
+	m.airportTree = root
 }
 
-// loadMapGeometry loads map line geometry from a binary file
-func (m *Map) loadMapGeometry(filename string, root **QuadTree, lines *[]*Line) error {
-	file, err := os.Open(filename)
-	if err != nil {
-		return err
+// gzipReadCloser closes both a gzip stream and the file under it
+type gzipReadCloser struct {
+	*gzip.Reader
+	file *os.File
+}
+
+// Close closes the gzip stream and the file
+func (g gzipReadCloser) Close() error {
+	err := g.Reader.Close()
+	if ferr := g.file.Close(); err == nil {
+		err = ferr
 	}
-	defer file.Close()
+	return err
+}
 
-	// Read the entire file
-	data, err := io.ReadAll(file)
+// openDataFile opens a data file, transparently decompressing it when it
+// starts with the gzip magic bytes, whatever its suffix
+func openDataFile(filename string) (io.ReadCloser, error) {
+	file, err := os.Open(filename)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Each point is 4 bytes (float32)
-	numFloats := len(data) / 4
-	points := make([]float32, numFloats)
-
-	// Convert byte array to float32 array
-	for i := 0; i < numFloats; i++ {
-		points[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4 : (i+1)*4]))
+	magic := make([]byte, 2)
+	n, _ := io.ReadFull(file, magic)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if n < 2 || !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return file, nil
 	}
 
-	// Update quadtree bounds by examining all points
-	for i := 0; i < numFloats; i += 2 {
-		if points[i] == 0 {
-			continue
-		}
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read gzip data in %s: %v", filename, err)
+	}
+	return gzipReadCloser{Reader: zr, file: file}, nil
+}
 
-		lon := float64(points[i])
-		lat := float64(points[i+1])
+// findDataFile returns filename, or its .gz variant when only that exists
+func findDataFile(filename string) string {
+	if _, err := os.Stat(filename); err == nil {
+		return filename
+	}
+	if _, err := os.Stat(filename + ".gz"); err == nil {
+		return filename + ".gz"
+	}
+	return filename
+}
 
-		if lon < (*root).LonMin {
-			(*root).LonMin = lon
-		} else if lon > (*root).LonMax {
-			(*root).LonMax = lon
-		}
+// geometryChunk is how many bytes of geometry are read at a time, a whole
+// number of lon/lat points
+const geometryChunk = 64 * 1024
 
-		if lat < (*root).LatMin {
-			(*root).LatMin = lat
-		} else if lat > (*root).LatMax {
-			(*root).LatMax = lat
+// loadMapGeometry loads map line geometry from a binary file, which may be
+// gzip compressed. The file is streamed twice, once for the quadtree bounds
+// and once to build and insert lines, so it is never held in memory whole.
+func (m *Map) loadMapGeometry(filename string, root **QuadTree, lines *[]*Line) error {
+	// Update quadtree bounds by examining all points
+	err := forEachPoint(filename, func(lon, lat float32) {
+		if lon == 0 {
+			return
 		}
+		(*root).extend(float64(lat), float64(lon))
+	})
+	if err != nil {
+		return err
 	}
 
-	// Create lines from points
-	for i := 0; i < numFloats-2; i += 2 {
-		if points[i] == 0 || points[i+1] == 0 || points[i+2] == 0 || points[i+3] == 0 {
-			continue
+	// Create lines between consecutive points, where 0 separates polylines
+	var prevLon, prevLat float32
+	first := true
+	return forEachPoint(filename, func(lon, lat float32) {
+		defer func() { prevLon, prevLat, first = lon, lat, false }()
+		if first || prevLon == 0 || prevLat == 0 || lon == 0 || lat == 0 {
+			return
 		}
 
-		startPoint := Point{Lon: float64(points[i]), Lat: float64(points[i+1])}
-		endPoint := Point{Lon: float64(points[i+2]), Lat: float64(points[i+3])}
+		startPoint := Point{Lon: float64(prevLon), Lat: float64(prevLat)}
+		endPoint := Point{Lon: float64(lon), Lat: float64(lat)}
 
 		line := &Line{
 			Start:  startPoint,
@@ -169,14 +248,53 @@ func (m *Map) loadMapGeometry(filename string, root **QuadTree, lines *[]*Line)
 
 		*lines = append(*lines, line)
 		m.insertIntoQuadTree(*root, line, 0)
+	})
+}
+
+// forEachPoint reads a geometry file in chunks, calling f with each
+// little-endian float32 lon/lat pair in file order
+func forEachPoint(filename string, f func(lon, lat float32)) error {
+	file, err := openDataFile(filename)
+	if err != nil {
+		return err
 	}
+	defer file.Close()
 
-	return nil
+	buf := make([]byte, geometryChunk)
+	for {
+		n, err := io.ReadFull(file, buf)
+		for i := 0; i+8 <= n; i += 8 {
+			f(math.Float32frombits(binary.LittleEndian.Uint32(buf[i:])),
+				math.Float32frombits(binary.LittleEndian.Uint32(buf[i+4:])))
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
 }
 
-// loadLabels loads text labels from a file
+// extend grows the bounds of a root node to take in a point. Each axis
+// updates either its minimum or its maximum, never both.
+func (tree *QuadTree) extend(lat, lon float64) {
+	if lon < tree.LonMin {
+		tree.LonMin = lon
+	} else if lon > tree.LonMax {
+		tree.LonMax = lon
+	}
+
+	if lat < tree.LatMin {
+		tree.LatMin = lat
+	} else if lat > tree.LatMax {
+		tree.LatMax = lat
+	}
+}
+
+// loadLabels loads text labels from a file, which may be gzip compressed
 func (m *Map) loadLabels(filename string, labels *[]*MapLabel) error {
-	file, err := os.Open(filename)
+	file, err := openDataFile(filename)
 	if err != nil {
 		return err
 	}
@@ -212,18 +330,19 @@ func (m *Map) loadLabels(filename string, labels *[]*MapLabel) error {
 	return scanner.Err()
 }
 
-// insertIntoQuadTree inserts a line into the quadtree
+// contains reports whether a point lies inside the node's bounds
+func (tree *QuadTree) contains(p Point) bool {
+	return p.Lat >= tree.LatMin && p.Lat <= tree.LatMax &&
+		p.Lon >= tree.LonMin && p.Lon <= tree.LonMax
+}
+
+// insertIntoQuadTree inserts a line into the quadtree. Lines with one end
+// outside a node stay in it; others move down into a child once the node
+// holds more than NodeCapacity lines, until MaxDepth is reached.
 func (m *Map) insertIntoQuadTree(tree *QuadTree, line *Line, depth int) bool {
 	// Check if line intersects with this quad
-	startInside := line.Start.Lat >= tree.LatMin &&
-		line.Start.Lat <= tree.LatMax &&
-		line.Start.Lon >= tree.LonMin &&
-		line.Start.Lon <= tree.LonMax
-
-	endInside := line.End.Lat >= tree.LatMin &&
-		line.End.Lat <= tree.LatMax &&
-		line.End.Lon >= tree.LonMin &&
-		line.End.Lon <= tree.LonMax
+	startInside := tree.contains(line.Start)
+	endInside := tree.contains(line.End)
 
 	// If neither end is inside, line may still cross the quad, but for simplicity we'll skip it
 	if !startInside && !endInside {
@@ -236,106 +355,197 @@ func (m *Map) insertIntoQuadTree(tree *QuadTree, line *Line, depth int) bool {
 		return true
 	}
 
-	// If we're at a very deep level, just add it here
-	if depth > 25 {
+	// Leaves collect lines until they are full, and at the depth limit for good
+	if tree.NW == nil {
+		if depth > m.MaxDepth || len(tree.Lines) < m.NodeCapacity {
+			tree.Lines = append(tree.Lines, line)
+			return true
+		}
+		m.split(tree, depth)
+	}
+
+	// If we couldn't insert into any child, add it to this node
+	if !m.insertIntoChildren(tree, line, depth) {
 		tree.Lines = append(tree.Lines, line)
-		return true
 	}
 
-	// Otherwise, try to add to a child
-	inserted := false
+	return true
+}
+
+// insertLabel adds a label to the leaf holding its location. Leaves split once
+// they hold more than NodeCapacity labels, until MaxDepth is reached.
+func (m *Map) insertLabel(tree *QuadTree, label *MapLabel, depth int) {
+	for tree.NW != nil {
+		tree = tree.childFor(label.Location)
+		depth++
+	}
+
+	tree.Labels = append(tree.Labels, label)
+	if depth >= m.MaxDepth || len(tree.Labels) <= m.NodeCapacity {
+		return
+	}
 
-	// Create child nodes if they don't exist
-	if tree.NW == nil {
-		midLat := tree.LatMin + 0.5*(tree.LatMax-tree.LatMin)
-		midLon := tree.LonMin + 0.5*(tree.LonMax-tree.LonMin)
-
-		tree.NW = &QuadTree{
-			LatMin: tree.LatMin,
-			LatMax: midLat,
-			LonMin: tree.LonMin,
-			LonMax: midLon,
-		}
+	m.split(tree, depth)
+	labels := tree.Labels
+	tree.Labels = nil
+	for _, l := range labels {
+		m.insertLabel(tree, l, depth)
+	}
+}
 
-		tree.NE = &QuadTree{
-			LatMin: tree.LatMin,
-			LatMax: midLat,
-			LonMin: midLon,
-			LonMax: tree.LonMax,
+// childFor returns the child of a split node that a point belongs in
+func (tree *QuadTree) childFor(p Point) *QuadTree {
+	for _, child := range []*QuadTree{tree.NW, tree.NE, tree.SW} {
+		if child.contains(p) {
+			return child
 		}
+	}
+	return tree.SE
+}
 
-		tree.SW = &QuadTree{
-			LatMin: midLat,
-			LatMax: tree.LatMax,
-			LonMin: tree.LonMin,
-			LonMax: midLon,
-		}
+// insertIntoChildren inserts a line into the first child of tree that takes it
+func (m *Map) insertIntoChildren(tree *QuadTree, line *Line, depth int) bool {
+	return m.insertIntoQuadTree(tree.NW, line, depth+1) ||
+		m.insertIntoQuadTree(tree.NE, line, depth+1) ||
+		m.insertIntoQuadTree(tree.SW, line, depth+1) ||
+		m.insertIntoQuadTree(tree.SE, line, depth+1)
+}
+
+// split creates the children of a leaf and moves down the lines that lie
+// wholly inside it
+func (m *Map) split(tree *QuadTree, depth int) {
+	midLat := tree.LatMin + 0.5*(tree.LatMax-tree.LatMin)
+	midLon := tree.LonMin + 0.5*(tree.LonMax-tree.LonMin)
+
+	tree.NW = &QuadTree{
+		LatMin: tree.LatMin,
+		LatMax: midLat,
+		LonMin: tree.LonMin,
+		LonMax: midLon,
+	}
 
-		tree.SE = &QuadTree{
-			LatMin: midLat,
-			LatMax: tree.LatMax,
-			LonMin: midLon,
-			LonMax: tree.LonMax,
+	tree.NE = &QuadTree{
+		LatMin: tree.LatMin,
+		LatMax: midLat,
+		LonMin: midLon,
+		LonMax: tree.LonMax,
+	}
+
+	tree.SW = &QuadTree{
+		LatMin: midLat,
+		LatMax: tree.LatMax,
+		LonMin: tree.LonMin,
+		LonMax: midLon,
+	}
+
+	tree.SE = &QuadTree{
+		LatMin: midLat,
+		LatMax: tree.LatMax,
+		LonMin: midLon,
+		LonMax: tree.LonMax,
+	}
+
+	lines := tree.Lines
+	tree.Lines = nil
+	for _, line := range lines {
+		if !tree.contains(line.Start) || !tree.contains(line.End) || !m.insertIntoChildren(tree, line, depth) {
+			tree.Lines = append(tree.Lines, line)
 		}
 	}
+}
 
-	// Try to insert into child nodes
-	if m.insertIntoQuadTree(tree.NW, line, depth+1) {
-		inserted = true
-	} else if m.insertIntoQuadTree(tree.NE, line, depth+1) {
-		inserted = true
-	} else if m.insertIntoQuadTree(tree.SW, line, depth+1) {
-		inserted = true
-	} else if m.insertIntoQuadTree(tree.SE, line, depth+1) {
-		inserted = true
+// NormalizeLon wraps a longitude into the -180 to 180 range
+func NormalizeLon(lon float64) float64 {
+	lon = math.Mod(lon+180, 360)
+	if lon < 0 {
+		lon += 360
 	}
+	return lon - 180
+}
 
-	// If we couldn't insert into any child, add it to this node
-	if !inserted {
-		tree.Lines = append(tree.Lines, line)
+// lonRangeOverlaps reports whether [min, max] overlaps the visible longitude range.
+// A range with lonMin > lonMax straddles the anti-meridian and is treated as the
+// union of [lonMin, 180] and [-180, lonMax].
+func lonRangeOverlaps(min, max, lonMin, lonMax float64) bool {
+	if lonMin <= lonMax {
+		return max >= lonMin && min <= lonMax
 	}
+	return max >= lonMin || min <= lonMax
+}
 
-	return true
+// lonInRange reports whether lon lies inside the visible longitude range,
+// handling ranges that straddle the anti-meridian
+func lonInRange(lon, lonMin, lonMax float64) bool {
+	return lonRangeOverlaps(lon, lon, lonMin, lonMax)
 }
 
-// GetVisibleLines returns all lines visible in the specified geographic area
-func (m *Map) GetVisibleLines(latMin, latMax, lonMin, lonMax float64) ([]*Line, []*Line) {
-	// Get map features
-	mapLines := m.getLinesFromQuadTree(m.Root, latMin, latMax, lonMin, lonMax)
+// GetVisibleLines returns the lines of each layer visible in the specified
+// geographic area, indexed like Layers. If lonMin > lonMax the area is taken
+// to straddle the anti-meridian.
+func (m *Map) GetVisibleLines(latMin, latMax, lonMin, lonMax float64) [][]*Line {
+	return m.AppendVisibleLines(nil, latMin, latMax, lonMin, lonMax)
+}
 
-	// Get airport features
-	airportLines := m.getLinesFromQuadTree(m.AirportRoot, latMin, latMax, lonMin, lonMax)
+// AppendVisibleLines is like GetVisibleLines but appends each layer's lines
+// to the matching slice in lines, so callers can reuse buffers between redraws
+func (m *Map) AppendVisibleLines(lines [][]*Line, latMin, latMax, lonMin, lonMax float64) [][]*Line {
+	for len(lines) < len(m.Layers) {
+		lines = append(lines, nil)
+	}
+	lines = lines[:len(m.Layers)]
 
-	return mapLines, airportLines
+	for i, layer := range m.Layers {
+		lines[i] = appendLinesFromQuadTree(lines[i], layer.Root, latMin, latMax, lonMin, lonMax)
+	}
+	return lines
 }
 
-// getLinesFromQuadTree recursively gets lines from the quadtree that are visible in the specified area
-func (m *Map) getLinesFromQuadTree(tree *QuadTree, latMin, latMax, lonMin, lonMax float64) []*Line {
+// appendLinesFromQuadTree recursively appends lines from the quadtree that are visible in the specified area
+func appendLinesFromQuadTree(lines []*Line, tree *QuadTree, latMin, latMax, lonMin, lonMax float64) []*Line {
 	if tree == nil {
-		return nil
+		return lines
 	}
 
-	// If this quad doesn't overlap with the visible area, return nothing
-	if tree.LatMax < latMin || tree.LatMin > latMax || tree.LonMax < lonMin || tree.LonMin > lonMax {
-		return nil
+	// If this quad doesn't overlap with the visible area, add nothing
+	if tree.LatMax < latMin || tree.LatMin > latMax || !lonRangeOverlaps(tree.LonMin, tree.LonMax, lonMin, lonMax) {
+		return lines
 	}
 
 	// Start with lines in this node
-	lines := make([]*Line, len(tree.Lines))
-	copy(lines, tree.Lines)
+	lines = append(lines, tree.Lines...)
 
 	// Add lines from children
 	if tree.NW != nil {
-		lines = append(lines, m.getLinesFromQuadTree(tree.NW, latMin, latMax, lonMin, lonMax)...)
-		lines = append(lines, m.getLinesFromQuadTree(tree.NE, latMin, latMax, lonMin, lonMax)...)
-		lines = append(lines, m.getLinesFromQuadTree(tree.SW, latMin, latMax, lonMin, lonMax)...)
-		lines = append(lines, m.getLinesFromQuadTree(tree.SE, latMin, latMax, lonMin, lonMax)...)
+		lines = appendLinesFromQuadTree(lines, tree.NW, latMin, latMax, lonMin, lonMax)
+		lines = appendLinesFromQuadTree(lines, tree.NE, latMin, latMax, lonMin, lonMax)
+		lines = appendLinesFromQuadTree(lines, tree.SW, latMin, latMax, lonMin, lonMax)
+		lines = appendLinesFromQuadTree(lines, tree.SE, latMin, latMax, lonMin, lonMax)
 	}
 
 	return lines
 }
 
-// GetVisibleLabels returns all labels visible in the specified geographic area
+// forEachLabel calls f for the labels of every node overlapping the area,
+// some of which may lie just outside it
+func (tree *QuadTree) forEachLabel(latMin, latMax, lonMin, lonMax float64, f func(*MapLabel)) {
+	if tree == nil || tree.LatMax < latMin || tree.LatMin > latMax ||
+		!lonRangeOverlaps(tree.LonMin, tree.LonMax, lonMin, lonMax) {
+		return
+	}
+
+	for _, label := range tree.Labels {
+		f(label)
+	}
+	if tree.NW != nil {
+		tree.NW.forEachLabel(latMin, latMax, lonMin, lonMax, f)
+		tree.NE.forEachLabel(latMin, latMax, lonMin, lonMax, f)
+		tree.SW.forEachLabel(latMin, latMax, lonMin, lonMax, f)
+		tree.SE.forEachLabel(latMin, latMax, lonMin, lonMax, f)
+	}
+}
+
+// GetVisibleLabels returns all labels visible in the specified geographic area.
+// If lonMin > lonMax the area is taken to straddle the anti-meridian.
 func (m *Map) GetVisibleLabels(latMin, latMax, lonMin, lonMax float64) ([]*MapLabel, []*MapLabel) {
 	var visiblePlaces []*MapLabel
 	var visibleAirports []*MapLabel
@@ -343,7 +553,7 @@ func (m *Map) GetVisibleLabels(latMin, latMax, lonMin, lonMax float64) ([]*MapLa
 	// Check place names
 	for _, label := range m.PlaceNames {
 		if label.Location.Lat >= latMin && label.Location.Lat <= latMax &&
-			label.Location.Lon >= lonMin && label.Location.Lon <= lonMax {
+			lonInRange(label.Location.Lon, lonMin, lonMax) {
 			visiblePlaces = append(visiblePlaces, label)
 		}
 	}
@@ -351,10 +561,40 @@ func (m *Map) GetVisibleLabels(latMin, latMax, lonMin, lonMax float64) ([]*MapLa
 	// Check airport names
 	for _, label := range m.AirportNames {
 		if label.Location.Lat >= latMin && label.Location.Lat <= latMax &&
-			label.Location.Lon >= lonMin && label.Location.Lon <= lonMax {
+			lonInRange(label.Location.Lon, lonMin, lonMax) {
 			visibleAirports = append(visibleAirports, label)
 		}
 	}
 
 	return visiblePlaces, visibleAirports
 }
+
+// NearestAirport returns the airport name label closest to lat/lon within
+// maxNM nautical miles, and its distance, or nil when none is that close.
+// Only airports indexed by LoadLabels are searched.
+func (m *Map) NearestAirport(lat, lon, maxNM float64) (*MapLabel, float64) {
+	// Only airports inside the bounding box of the search radius can match
+	dLat := maxNM / 60.0
+	dLon := maxNM / (60.0 * math.Max(math.Cos(lat*math.Pi/180.0), 0.01))
+	latMin, latMax := lat-dLat, lat+dLat
+	lonMin, lonMax := NormalizeLon(lon-dLon), NormalizeLon(lon+dLon)
+	if dLon >= 180 {
+		lonMin, lonMax = -180, 180
+	}
+
+	var nearest *MapLabel
+	nearestDist := maxNM
+	m.airportTree.forEachLabel(latMin, latMax, lonMin, lonMax, func(label *MapLabel) {
+		// Flat earth distance is plenty at approach ranges
+		dy := (label.Location.Lat - lat) * 60.0
+		dx := NormalizeLon(label.Location.Lon-lon) * 60.0 * math.Cos(lat*math.Pi/180.0)
+		if dist := math.Sqrt(dx*dx + dy*dy); dist <= nearestDist {
+			nearest, nearestDist = label, dist
+		}
+	})
+
+	if nearest == nil {
+		return nil, 0
+	}
+	return nearest, nearestDist
+}
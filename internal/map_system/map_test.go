@@ -0,0 +1,474 @@
+package map_system
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeGeometry writes polylines in the binary map format: little-endian
+// float32 lon/lat pairs, with a 0,0 pair separating polylines
+func writeGeometry(t testing.TB, polylines [][]Point) string {
+	t.Helper()
+
+	var buf []byte
+	put := func(f float64) {
+		buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(float32(f)))
+	}
+	for _, line := range polylines {
+		for _, p := range line {
+			put(p.Lon)
+			put(p.Lat)
+		}
+		put(0)
+		put(0)
+	}
+
+	filename := filepath.Join(t.TempDir(), "mapdata.bin")
+	if err := os.WriteFile(filename, buf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadGzipData(t *testing.T) {
+	plain := writeGeometry(t, [][]Point{
+		{{Lat: 51.0, Lon: -0.5}, {Lat: 51.1, Lon: -0.4}, {Lat: 51.2, Lon: -0.2}},
+	})
+	data, err := os.ReadFile(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Compressed copy with a .gz suffix next to a missing plain file
+	compressed := filepath.Join(t.TempDir(), "mapdata.bin.gz")
+	f, err := os.Create(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(f)
+	zw.Write(data)
+	zw.Close()
+	f.Close()
+
+	m := NewMap()
+	if err := m.LoadLayer("map", compressed[:len(compressed)-3]); err != nil {
+		t.Fatal(err)
+	}
+	lines := m.Layers[0].Lines
+	if len(lines) != 2 {
+		t.Fatalf("loaded %d lines from gzip data, want 2", len(lines))
+	}
+	if got := lines[0].Start; got.Lat != float64(float32(51.0)) || got.Lon != float64(float32(-0.5)) {
+		t.Errorf("first point = %+v, want 51.0,-0.5", got)
+	}
+}
+
+func TestNearestAirport(t *testing.T) {
+	m := NewMap()
+	m.AirportNames = []*MapLabel{
+		{Location: Point{Lat: 37.619, Lon: -122.375}, Text: "SFO"},
+		{Location: Point{Lat: 37.721, Lon: -122.221}, Text: "OAK"},
+		{Location: Point{Lat: 10.0, Lon: 179.95}, Text: "WST"},
+	}
+	m.indexAirports()
+
+	tests := []struct {
+		lat, lon, maxNM float64
+		want            string
+	}{
+		{37.60, -122.35, 10, "SFO"},
+		{37.70, -122.25, 10, "OAK"},
+		{37.00, -122.35, 10, ""},     // 37 NM south of SFO
+		{10.0, -179.95, 10, "WST"},   // across the anti-meridian
+		{37.619, -122.375, 0, "SFO"}, // directly overhead
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if label, _ := m.NearestAirport(tt.lat, tt.lon, tt.maxNM); label != nil {
+			got = label.Text
+		}
+		if got != tt.want {
+			t.Errorf("NearestAirport(%v, %v, %v) = %q, want %q", tt.lat, tt.lon, tt.maxNM, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeLon(t *testing.T) {
+	tests := []struct {
+		in, want float64
+	}{
+		{0, 0},
+		{179.5, 179.5},
+		{180.5, -179.5},
+		{-180.5, 179.5},
+		{359, -1},
+		{-359, 1},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeLon(tt.in); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("NormalizeLon(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestVisibleAcrossAntiMeridian(t *testing.T) {
+	m := NewMap()
+
+	filename := writeGeometry(t, [][]Point{
+		{{Lat: 10.0, Lon: 179.2}, {Lat: 10.1, Lon: 179.4}},   // just west of the dateline
+		{{Lat: 10.0, Lon: -179.9}, {Lat: 10.1, Lon: -179.7}}, // just east of the dateline
+		{{Lat: 10.0, Lon: 100.0}, {Lat: 10.1, Lon: 100.2}},   // far away
+	})
+	if err := m.LoadLayer("map", filename); err != nil {
+		t.Fatal(err)
+	}
+
+	m.PlaceNames = []*MapLabel{
+		{Location: Point{Lat: 10.0, Lon: 179.9}, Text: "WEST"},
+		{Location: Point{Lat: 10.0, Lon: -179.9}, Text: "EAST"},
+		{Location: Point{Lat: 10.0, Lon: 100.0}, Text: "FAR"},
+	}
+
+	// A view centered at lon 179.5 spanning one degree either side
+	lonMin := NormalizeLon(179.5 - 1.0)
+	lonMax := NormalizeLon(179.5 + 1.0)
+	if lonMin <= lonMax {
+		t.Fatalf("expected wrapped range, got %v..%v", lonMin, lonMax)
+	}
+
+	lines := m.GetVisibleLines(9.0, 11.0, lonMin, lonMax)
+
+	var west, east bool
+	for _, line := range lines[0] {
+		switch {
+		case line.Start.Lon > 179:
+			west = true
+		case line.Start.Lon < -179:
+			east = true
+		}
+	}
+	if !west || !east {
+		t.Errorf("expected lines on both sides of the dateline, got west=%v east=%v", west, east)
+	}
+
+	places, _ := m.GetVisibleLabels(9.0, 11.0, lonMin, lonMax)
+	if len(places) != 2 {
+		t.Fatalf("expected 2 visible labels, got %d", len(places))
+	}
+	for _, label := range places {
+		if label.Text == "FAR" {
+			t.Errorf("label at lon 100 should not be visible")
+		}
+	}
+}
+
+// denseMap builds a map with a grid of short polylines covering two degrees
+// around 51N 0E, dense enough to split the quadtree several levels deep.
+// capacity sets the quadtree node capacity.
+func denseMap(b testing.TB, capacity int) *Map {
+	b.Helper()
+
+	var polylines [][]Point
+	for i := 0; i < 200; i++ {
+		for j := 0; j < 100; j++ {
+			lat := 50.0 + float64(i)*0.01
+			lon := -1.0 + float64(j)*0.02
+			polylines = append(polylines, []Point{
+				{Lat: lat, Lon: lon},
+				{Lat: lat + 0.004, Lon: lon + 0.005},
+				{Lat: lat + 0.008, Lon: lon + 0.002},
+			})
+		}
+	}
+
+	m := NewMap()
+	m.NodeCapacity = capacity
+	if err := m.LoadLayer("map", writeGeometry(b, polylines)); err != nil {
+		b.Fatal(err)
+	}
+	return m
+}
+
+func BenchmarkVisibleLines(b *testing.B) {
+	m := denseMap(b, DefaultNodeCapacity)
+
+	b.Run("Get", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m.GetVisibleLines(50.5, 51.5, -0.5, 0.5)
+		}
+	})
+
+	b.Run("AppendReused", func(b *testing.B) {
+		b.ReportAllocs()
+		var lines [][]*Line
+		for i := 0; i < b.N; i++ {
+			for j := range lines {
+				lines[j] = lines[j][:0]
+			}
+			lines = m.AppendVisibleLines(lines, 50.5, 51.5, -0.5, 0.5)
+		}
+	})
+}
+
+func TestLayersInOrder(t *testing.T) {
+	m := NewMap()
+	for _, layer := range []struct {
+		name string
+		line []Point
+	}{
+		{"coast", []Point{{Lat: 51.0, Lon: 0.05}, {Lat: 51.1, Lon: 0.1}}},
+		{"roads", []Point{{Lat: 51.2, Lon: 0.2}, {Lat: 51.3, Lon: 0.3}}},
+		{"far", []Point{{Lat: 10.0, Lon: 100.0}, {Lat: 10.1, Lon: 100.1}}},
+	} {
+		if err := m.LoadLayer(layer.name, writeGeometry(t, [][]Point{layer.line})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.LoadLayer("missing", filepath.Join(t.TempDir(), "none.bin")); err == nil {
+		t.Error("missing layer file loaded")
+	}
+
+	if len(m.Layers) != 3 || m.Layers[0].Name != "coast" || m.Layers[1].Name != "roads" {
+		t.Fatalf("layers not kept in load order: %d", len(m.Layers))
+	}
+
+	lines := m.GetVisibleLines(50.0, 52.0, -1.0, 1.0)
+	if len(lines) != 3 || len(lines[0]) != 1 || len(lines[1]) != 1 || len(lines[2]) != 0 {
+		t.Errorf("visible lines per layer = %d/%d/%d, want 1/1/0", len(lines[0]), len(lines[1]), len(lines[2]))
+	}
+}
+
+// loadGeometryReadAll is the original whole-file loader, kept to check the
+// streaming loader against it
+func loadGeometryReadAll(t testing.TB, filename string) *Layer {
+	t.Helper()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	numFloats := len(data) / 4
+	points := make([]float32, numFloats)
+	for i := 0; i < numFloats; i++ {
+		points[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4 : (i+1)*4]))
+	}
+
+	layer := newLayer("map")
+	for i := 0; i < numFloats; i += 2 {
+		if points[i] != 0 {
+			layer.Root.extend(float64(points[i+1]), float64(points[i]))
+		}
+	}
+
+	m := NewMap()
+	for i := 0; i < numFloats-2; i += 2 {
+		if points[i] == 0 || points[i+1] == 0 || points[i+2] == 0 || points[i+3] == 0 {
+			continue
+		}
+		start := Point{Lon: float64(points[i]), Lat: float64(points[i+1])}
+		end := Point{Lon: float64(points[i+2]), Lat: float64(points[i+3])}
+		line := &Line{
+			Start:  start,
+			End:    end,
+			LatMin: math.Min(start.Lat, end.Lat),
+			LatMax: math.Max(start.Lat, end.Lat),
+			LonMin: math.Min(start.Lon, end.Lon),
+			LonMax: math.Max(start.Lon, end.Lon),
+		}
+		layer.Lines = append(layer.Lines, line)
+		m.insertIntoQuadTree(layer.Root, line, 0)
+	}
+	return layer
+}
+
+// sameTree reports whether two quadtrees have the same shape, bounds and lines
+func sameTree(a, b *QuadTree) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.LatMin != b.LatMin || a.LatMax != b.LatMax || a.LonMin != b.LonMin || a.LonMax != b.LonMax ||
+		len(a.Lines) != len(b.Lines) {
+		return false
+	}
+	for i := range a.Lines {
+		if *a.Lines[i] != *b.Lines[i] {
+			return false
+		}
+	}
+	return sameTree(a.NW, b.NW) && sameTree(a.NE, b.NE) && sameTree(a.SW, b.SW) && sameTree(a.SE, b.SE)
+}
+
+// largeGeometry writes a geometry file spanning several read chunks
+func largeGeometry(t testing.TB) string {
+	var polylines [][]Point
+	for i := 0; i < 300; i++ {
+		var line []Point
+		for j := 0; j < 50; j++ {
+			line = append(line, Point{Lat: 40 + float64(i)*0.05 + float64(j)*0.001, Lon: -10 + float64(j)*0.3})
+		}
+		polylines = append(polylines, line)
+	}
+	return writeGeometry(t, polylines)
+}
+
+func TestStreamingLoadMatchesReadAll(t *testing.T) {
+	filename := largeGeometry(t)
+
+	m := NewMap()
+	if err := m.LoadLayer("map", filename); err != nil {
+		t.Fatal(err)
+	}
+	got, want := m.Layers[0], loadGeometryReadAll(t, filename)
+
+	if len(got.Lines) != len(want.Lines) {
+		t.Fatalf("loaded %d lines, want %d", len(got.Lines), len(want.Lines))
+	}
+	if !sameTree(got.Root, want.Root) {
+		t.Error("streamed quadtree differs from the whole-file loader's")
+	}
+}
+
+// BenchmarkLoadGeometry compares the streaming loader against reading the
+// whole file first; run with -benchmem to compare allocations
+func BenchmarkLoadGeometry(b *testing.B) {
+	filename := largeGeometry(b)
+
+	b.Run("Streaming", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m := NewMap()
+			if err := m.LoadLayer("map", filename); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("ReadAll", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			loadGeometryReadAll(b, filename)
+		}
+	})
+}
+
+// treeStats returns the number of nodes in a quadtree and its depth
+func treeStats(tree *QuadTree) (nodes, depth int) {
+	if tree == nil {
+		return 0, 0
+	}
+	nodes = 1
+	for _, child := range []*QuadTree{tree.NW, tree.NE, tree.SW, tree.SE} {
+		n, d := treeStats(child)
+		nodes += n
+		depth = max(depth, d)
+	}
+	return nodes, depth + 1
+}
+
+func TestNodeCapacityKeepsVisibleLines(t *testing.T) {
+	split := denseMap(t, 0)
+	bucket := denseMap(t, 16)
+
+	splitNodes, _ := treeStats(split.Layers[0].Root)
+	bucketNodes, _ := treeStats(bucket.Layers[0].Root)
+	if bucketNodes >= splitNodes {
+		t.Errorf("bucket tree has %d nodes, want fewer than the %d of the always-split tree", bucketNodes, splitNodes)
+	}
+
+	for _, area := range [][4]float64{
+		{50.5, 51.5, -0.5, 0.5},
+		{50.0, 50.1, -1.0, -0.9},
+		{51.9, 52.1, 0.9, 1.1},
+	} {
+		want := make(map[Line]bool)
+		for _, line := range split.GetVisibleLines(area[0], area[1], area[2], area[3])[0] {
+			want[*line] = true
+		}
+		got := bucket.GetVisibleLines(area[0], area[1], area[2], area[3])[0]
+		for _, line := range got {
+			delete(want, *line)
+		}
+		if len(want) > 0 {
+			t.Errorf("area %v: bucket tree misses %d lines", area, len(want))
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	m := NewMap()
+	m.MaxDepth = 3
+	m.NodeCapacity = 0
+	if err := m.LoadLayer("map", largeGeometry(t)); err != nil {
+		t.Fatal(err)
+	}
+	// Nodes at the depth limit aren't split, so the tree is one level deeper
+	if _, depth := treeStats(m.Layers[0].Root); depth > 5 {
+		t.Errorf("tree depth %d, want at most 5 for MaxDepth 3", depth)
+	}
+}
+
+// BenchmarkNodeCapacity compares queries on the always-split tree against
+// bucket trees that split only once a node is full
+func BenchmarkNodeCapacity(b *testing.B) {
+	for _, capacity := range []int{0, 8, 32, 128} {
+		m := denseMap(b, capacity)
+		nodes, depth := treeStats(m.Layers[0].Root)
+
+		b.Run(fmt.Sprintf("capacity=%d", capacity), func(b *testing.B) {
+			b.ReportMetric(float64(nodes), "nodes")
+			b.ReportMetric(float64(depth), "depth")
+			var lines [][]*Line
+			for i := 0; i < b.N; i++ {
+				for j := range lines {
+					lines[j] = lines[j][:0]
+				}
+				lines = m.AppendVisibleLines(lines, 50.5, 51.5, -0.5, 0.5)
+			}
+		})
+	}
+}
+
+func TestNearestAirportMatchesScan(t *testing.T) {
+	m := NewMap()
+	m.NodeCapacity = 4
+	for lat := -60.0; lat <= 60.0; lat += 1.5 {
+		for lon := -180.0; lon < 180.0; lon += 2.5 {
+			m.AirportNames = append(m.AirportNames, &MapLabel{
+				Location: Point{Lat: lat, Lon: lon},
+				Text:     fmt.Sprintf("%.1f,%.1f", lat, lon),
+			})
+		}
+	}
+	m.indexAirports()
+	if m.airportTree.NW == nil {
+		t.Fatal("airport index was not split")
+	}
+
+	for _, q := range []struct{ lat, lon float64 }{
+		{0.3, 0.4}, {51.2, -0.9}, {-33.9, 151.2}, {10.0, 179.9}, {10.0, -179.9}, {59.9, 45.0},
+	} {
+		// Brute force over every airport for the expected answer
+		var want *MapLabel
+		wantDist := 60.0
+		for _, label := range m.AirportNames {
+			dy := (label.Location.Lat - q.lat) * 60.0
+			dx := NormalizeLon(label.Location.Lon-q.lon) * 60.0 * math.Cos(q.lat*math.Pi/180.0)
+			if dist := math.Sqrt(dx*dx + dy*dy); dist <= wantDist {
+				want, wantDist = label, dist
+			}
+		}
+
+		got, _ := m.NearestAirport(q.lat, q.lon, 60)
+		if got != want {
+			t.Errorf("NearestAirport(%v, %v) = %v, want %v", q.lat, q.lon, got, want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package map_system
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// earthRadiusNM is the radius of a sphere on which one minute of arc is one
+// nautical mile
+const earthRadiusNM = 60 * 180 / math.Pi
+
+// ProjectionKind selects how the map is flattened onto the screen
+type ProjectionKind int
+
+const (
+	// Equirectangular scales longitude by the cosine of the latitude. It is
+	// cheap and accurate near the center but stretches over large areas.
+	Equirectangular ProjectionKind = iota
+
+	// AzimuthalEquidistant keeps true distance and bearing from the center,
+	// so circles around the center are true range rings at any scale
+	AzimuthalEquidistant
+)
+
+// ParseProjection returns the projection named by a config value, either
+// "equirectangular" (or empty) or "azimuthal"
+func ParseProjection(name string) (ProjectionKind, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "equirectangular":
+		return Equirectangular, nil
+	case "azimuthal", "azimuthal-equidistant":
+		return AzimuthalEquidistant, nil
+	}
+	return Equirectangular, fmt.Errorf("unknown projection %q", name)
+}
+
+// Projection maps geographic coordinates to offsets in NM east and north of
+// a center point and back
+type Projection struct {
+	Kind      ProjectionKind
+	CenterLat float64
+	CenterLon float64
+}
+
+// NewProjection returns a projection of the given kind about a center point
+func NewProjection(kind ProjectionKind, centerLat, centerLon float64) Projection {
+	return Projection{Kind: kind, CenterLat: centerLat, CenterLon: centerLon}
+}
+
+// Forward returns the offset of a point from the center in NM east and north
+func (p Projection) Forward(lat, lon float64) (east, north float64) {
+	if p.Kind == AzimuthalEquidistant {
+		return p.azimuthalForward(lat, lon)
+	}
+
+	// Scale longitude at the mean latitude, the short way round the anti-meridian
+	east = NormalizeLon(lon-p.CenterLon) * math.Cos((lat+p.CenterLat)/2*math.Pi/180) * 60
+	north = (lat - p.CenterLat) * 60
+	return east, north
+}
+
+// Inverse returns the point at an offset in NM east and north of the center
+func (p Projection) Inverse(east, north float64) (lat, lon float64) {
+	if p.Kind == AzimuthalEquidistant {
+		return p.azimuthalInverse(east, north)
+	}
+
+	lat = p.CenterLat + north/60
+	lon = NormalizeLon(p.CenterLon + east/(60*math.Cos((lat+p.CenterLat)/2*math.Pi/180)))
+	return lat, lon
+}
+
+// azimuthalForward projects a point onto the plane touching the sphere at the
+// center, keeping its great circle distance and bearing from the center
+func (p Projection) azimuthalForward(lat, lon float64) (east, north float64) {
+	phi0 := p.CenterLat * math.Pi / 180
+	phi := lat * math.Pi / 180
+	dLambda := NormalizeLon(lon-p.CenterLon) * math.Pi / 180
+
+	cosC := math.Sin(phi0)*math.Sin(phi) + math.Cos(phi0)*math.Cos(phi)*math.Cos(dLambda)
+	c := math.Acos(math.Max(-1, math.Min(1, cosC)))
+
+	// k scales the plane's sine distance up to the arc distance; the
+	// antipode has no single direction and is left at the far edge
+	k := 1.0
+	if s := math.Sin(c); s > 1e-12 {
+		k = c / s
+	} else if c > math.Pi/2 {
+		return 0, -math.Pi * earthRadiusNM
+	}
+
+	east = earthRadiusNM * k * math.Cos(phi) * math.Sin(dLambda)
+	north = earthRadiusNM * k * (math.Cos(phi0)*math.Sin(phi) - math.Sin(phi0)*math.Cos(phi)*math.Cos(dLambda))
+	return east, north
+}
+
+// azimuthalInverse walks the distance of an offset along its bearing from the
+// center
+func (p Projection) azimuthalInverse(east, north float64) (lat, lon float64) {
+	rho := math.Hypot(east, north)
+	if rho == 0 {
+		return p.CenterLat, p.CenterLon
+	}
+
+	phi0 := p.CenterLat * math.Pi / 180
+	c := rho / earthRadiusNM
+	sinC, cosC := math.Sin(c), math.Cos(c)
+
+	phi := math.Asin(math.Max(-1, math.Min(1, cosC*math.Sin(phi0)+north*sinC*math.Cos(phi0)/rho)))
+	dLambda := math.Atan2(east*sinC, rho*math.Cos(phi0)*cosC-north*math.Sin(phi0)*sinC)
+
+	return phi * 180 / math.Pi, NormalizeLon(p.CenterLon + dLambda*180/math.Pi)
+}
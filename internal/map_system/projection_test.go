@@ -0,0 +1,62 @@
+package map_system
+
+import (
+	"math"
+	"testing"
+)
+
+func TestProjectionRoundTrip(t *testing.T) {
+	centers := []Point{{51.47, -0.45}, {0, 179.5}, {78.2, 15.6}, {-45, -170}}
+	offsets := [][2]float64{{0, 0}, {10, 0}, {0, -25}, {-300, 400}, {1500, -900}, {2500, 2500}}
+
+	for _, kind := range []ProjectionKind{Equirectangular, AzimuthalEquidistant} {
+		for _, c := range centers {
+			p := NewProjection(kind, c.Lat, c.Lon)
+			for _, o := range offsets {
+				lat, lon := p.Inverse(o[0], o[1])
+				if kind == Equirectangular && math.Abs(lat) > 89 {
+					continue // Beyond the pole, where equirectangular has no inverse
+				}
+				east, north := p.Forward(lat, lon)
+				if math.Abs(east-o[0]) > 1e-6 || math.Abs(north-o[1]) > 1e-6 {
+					t.Errorf("kind %d center %v: offset %v -> %.5f,%.5f -> %.6f,%.6f",
+						kind, c, o, lat, lon, east, north)
+				}
+			}
+		}
+	}
+}
+
+func TestAzimuthalKeepsDistanceAndBearing(t *testing.T) {
+	// Heathrow to JFK is about 2990 NM on a 60 NM per degree sphere, bearing 287.9
+	p := NewProjection(AzimuthalEquidistant, 51.47, -0.45)
+	east, north := p.Forward(40.64, -73.78)
+
+	if d := math.Hypot(east, north); math.Abs(d-2990) > 1 {
+		t.Errorf("distance = %.1f NM, want about 2990", d)
+	}
+	bearing := math.Mod(math.Atan2(east, north)*180/math.Pi+360, 360)
+	if math.Abs(bearing-287.9) > 0.5 {
+		t.Errorf("bearing = %.1f, want about 287.9", bearing)
+	}
+}
+
+func TestParseProjection(t *testing.T) {
+	tests := []struct {
+		name string
+		want ProjectionKind
+		ok   bool
+	}{
+		{"", Equirectangular, true},
+		{"equirectangular", Equirectangular, true},
+		{"Azimuthal", AzimuthalEquidistant, true},
+		{"mercator", Equirectangular, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseProjection(tt.name)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseProjection(%q) = %v, %v", tt.name, got, err)
+		}
+	}
+}
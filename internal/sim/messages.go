@@ -0,0 +1,200 @@
+package sim
+
+import (
+	"math"
+	"strings"
+
+	"github.com/OJPARKINSON/viz1090/internal/beast"
+)
+
+// CreateADSBIdentMessage creates an ADS-B aircraft identification message
+func CreateADSBIdentMessage(icao uint32, callsign string) []byte {
+	// DF17 (Extended squitter) + ADS-B Identification message
+	msg := make([]byte, 14)
+
+	// DF17, CA=5
+	msg[0] = (DF17 << 3) | 5
+
+	// ICAO address
+	msg[1] = byte((icao >> 16) & 0xFF)
+	msg[2] = byte((icao >> 8) & 0xFF)
+	msg[3] = byte(icao & 0xFF)
+
+	// Type code = 4 (aircraft ID) + category
+	msg[4] = (TC_IDENT << 3) | 0
+
+	// Pad callsign to 8 characters
+	paddedCallsign := callsign
+	if len(paddedCallsign) < 8 {
+		paddedCallsign += strings.Repeat(" ", 8-len(paddedCallsign))
+	} else if len(paddedCallsign) > 8 {
+		paddedCallsign = paddedCallsign[:8]
+	}
+
+	paddedCallsign = strings.ToUpper(paddedCallsign)
+
+	// Encode callsign using the ICAO 6-bit character set, '#' marks unused codes
+	charset := "#ABCDEFGHIJKLMNOPQRSTUVWXYZ##### ###############0123456789######"
+
+	// First 4 characters
+	var c1, c2, c3, c4 int
+	for i, char := range paddedCallsign[:4] {
+		idx := strings.IndexRune(charset, char)
+		if idx <= 0 {
+			idx = 32 // Space character
+		}
+
+		switch i {
+		case 0:
+			c1 = idx
+		case 1:
+			c2 = idx
+		case 2:
+			c3 = idx
+		case 3:
+			c4 = idx
+		}
+	}
+
+	msg[5] = byte((c1 << 2) | (c2 >> 4))
+	msg[6] = byte(((c2 & 0x0F) << 4) | (c3 >> 2))
+	msg[7] = byte(((c3 & 0x03) << 6) | c4)
+
+	// Last 4 characters
+	c1, c2, c3, c4 = 0, 0, 0, 0
+	for i, char := range paddedCallsign[4:8] {
+		idx := strings.IndexRune(charset, char)
+		if idx <= 0 {
+			idx = 32 // Space character
+		}
+
+		switch i {
+		case 0:
+			c1 = idx
+		case 1:
+			c2 = idx
+		case 2:
+			c3 = idx
+		case 3:
+			c4 = idx
+		}
+	}
+
+	msg[8] = byte((c1 << 2) | (c2 >> 4))
+	msg[9] = byte(((c2 & 0x0F) << 4) | (c3 >> 2))
+	msg[10] = byte(((c3 & 0x03) << 6) | c4)
+
+	// CRC fields are left at zero for simplicity
+
+	return msg
+}
+
+// CreateADSBPositionMessage creates an ADS-B airborne position message
+func CreateADSBPositionMessage(icao uint32, lat, lon float64, alt int, odd bool) []byte {
+	// DF17 (Extended squitter) + ADS-B Airborne Position message
+	msg := make([]byte, 14)
+
+	// DF17, CA=5
+	msg[0] = (DF17 << 3) | 5
+
+	// ICAO address
+	msg[1] = byte((icao >> 16) & 0xFF)
+	msg[2] = byte((icao >> 8) & 0xFF)
+	msg[3] = byte(icao & 0xFF)
+
+	// Type code = 11 (airborne position) + surveillance status (0) + single antenna flag (0) + odd/even flag
+	var tc byte = (TC_AIRBORNE_POS << 3)
+	if odd {
+		tc |= 1 // Set odd/even flag
+	}
+	msg[4] = tc
+
+	// Altitude encoding (25ft resolution), with the Q bit inserted as bit 8
+	n := (alt + 1000) / 25
+	altCode := ((n & 0x7F0) << 1) | 0x010 | (n & 0x00F)
+	msg[5] = byte((altCode >> 4) & 0xFF)
+	msg[6] = byte((altCode & 0x0F) << 4)
+
+	// CPR encoding
+	// This is a simplified CPR encoding - real implementation is more complex
+	latCPR := uint32((lat / 360.0) * 131072)
+	lonCPR := uint32((lon / 360.0) * 131072)
+
+	msg[6] |= byte((latCPR >> 15) & 0x0F)
+	msg[7] = byte((latCPR >> 7) & 0xFF)
+	msg[8] = byte((latCPR & 0x7F) << 1)
+	msg[8] |= byte((lonCPR >> 16) & 0x01)
+	msg[9] = byte((lonCPR >> 8) & 0xFF)
+	msg[10] = byte(lonCPR & 0xFF)
+
+	// CRC fields are left at zero for simplicity
+
+	return msg
+}
+
+// CreateADSBVelocityMessage creates an ADS-B airborne velocity message
+func CreateADSBVelocityMessage(icao uint32, speed, heading, climbRate int) []byte {
+	// DF17 (Extended squitter) + ADS-B Airborne Velocity message
+	msg := make([]byte, 14)
+
+	// DF17, CA=5
+	msg[0] = (DF17 << 3) | 5
+
+	// ICAO address
+	msg[1] = byte((icao >> 16) & 0xFF)
+	msg[2] = byte((icao >> 8) & 0xFF)
+	msg[3] = byte(icao & 0xFF)
+
+	// Type code = 19 (airborne velocity) + subtype 1 (ground speed)
+	msg[4] = (TC_AIRBORNE_VEL << 3) | 1
+
+	// Intent change flag (0), IFR capability flag (1), navigation uncertainty (0)
+	msg[5] = 0x40
+
+	// East-West velocity component
+	ewVel := int(float64(speed) * math.Sin(float64(heading)*math.Pi/180.0))
+	ewDir := 0
+	if ewVel < 0 {
+		ewDir = 1
+		ewVel = -ewVel
+	}
+	ewVel = ewVel + 1 // 1-knot resolution
+	msg[5] |= byte(ewDir << 2)
+	msg[5] |= byte((ewVel >> 8) & 0x03)
+	msg[6] = byte(ewVel & 0xFF)
+
+	// North-South velocity component
+	nsVel := int(float64(speed) * math.Cos(float64(heading)*math.Pi/180.0))
+	nsDir := 0
+	if nsVel < 0 {
+		nsDir = 1
+		nsVel = -nsVel
+	}
+	nsVel = nsVel + 1 // 1-knot resolution
+	msg[7] = byte(nsDir << 7)
+	msg[7] |= byte((nsVel >> 3) & 0x7F)
+	msg[8] = byte((nsVel & 0x07) << 5)
+
+	// Vertical rate
+	vertRate := climbRate
+	vertSign := 0
+	if vertRate < 0 {
+		vertSign = 1
+		vertRate = -vertRate
+	}
+
+	// 64 fpm resolution, offset by one as zero means no information
+	vertRate = (vertRate+32)/64 + 1
+	msg[8] |= byte(vertSign << 3)
+	msg[8] |= byte((vertRate >> 6) & 0x07)
+	msg[9] = byte((vertRate & 0x3F) << 2)
+
+	// CRC fields are left at zero for simplicity
+
+	return msg
+}
+
+// EncodeBeastMessage wraps Mode S data in a Beast frame, escaping 0x1A bytes
+func EncodeBeastMessage(msgType byte, data []byte, timestamp uint64, signalLevel byte) []byte {
+	return beast.EncodeMessage(msgType, data, timestamp, signalLevel)
+}
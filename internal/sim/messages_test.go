@@ -0,0 +1,95 @@
+package sim
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+	"github.com/OJPARKINSON/viz1090/internal/beast"
+)
+
+func TestVelocityMessageRoundTrip(t *testing.T) {
+	tests := []struct {
+		speed, heading, climbRate int
+	}{
+		{450, 45, 1280},
+		{500, 270, -640},
+		{300, 180, 0},
+	}
+
+	for _, tt := range tests {
+		msg := CreateADSBVelocityMessage(0xABCDEF, tt.speed, tt.heading, tt.climbRate)
+
+		speed, heading, vertRate, ok := adsb.DecodeVelocity(msg)
+		if !ok {
+			t.Fatalf("DecodeVelocity rejected message for %+v", tt)
+		}
+
+		// Components are encoded at 1 knot resolution, so allow a little rounding
+		if diff := speed - tt.speed; diff < -1 || diff > 1 {
+			t.Errorf("speed = %d, want %d", speed, tt.speed)
+		}
+		if diff := heading - tt.heading; diff < -1 || diff > 1 {
+			t.Errorf("heading = %d, want %d", heading, tt.heading)
+		}
+		if vertRate != tt.climbRate {
+			t.Errorf("vertical rate = %d, want %d", vertRate, tt.climbRate)
+		}
+	}
+}
+
+func TestBeastFrameRoundTrip(t *testing.T) {
+	// 0x1A in the address and timestamp exercises escaping
+	msg := CreateADSBVelocityMessage(0x1A1A1A, 450, 45, 0)
+	frame := EncodeBeastMessage(ModeLong, msg, 0x1A*12000000, 0x1A)
+
+	decoded, err := beast.NewDecoder(bytes.NewReader(frame)).ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+
+	if decoded.Type != ModeLong {
+		t.Errorf("type = %q, want %q", decoded.Type, ModeLong)
+	}
+	if decoded.SignalLevel != 0x1A {
+		t.Errorf("signal level = %#x, want 0x1a", decoded.SignalLevel)
+	}
+	if decoded.Timestamp != 0x1A {
+		t.Errorf("timestamp = %d seconds, want %d", decoded.Timestamp, 0x1A)
+	}
+	if !bytes.Equal(decoded.Data, msg) {
+		t.Errorf("data = %x, want %x", decoded.Data, msg)
+	}
+}
+
+func TestIdentMessageRoundTrip(t *testing.T) {
+	tests := []struct {
+		callsign, want string
+	}{
+		{"SWA1234", "SWA1234"},
+		{"UAL789", "UAL789"},
+		{"JBU20212", "JBU20212"},
+		{"BAW1234567", "BAW12345"}, // Truncated to 8 characters
+		{"dal456", "DAL456"},
+		{"AB-12", "AB 12"}, // Unencodable characters become spaces
+	}
+
+	for _, tt := range tests {
+		msg := CreateADSBIdentMessage(0xABCDEF, tt.callsign)
+
+		if got := adsb.DecodeCallsign(msg[5:11]); got != tt.want {
+			t.Errorf("callsign %q decoded as %q, want %q", tt.callsign, got, tt.want)
+		}
+	}
+}
+
+func TestPositionMessageAltitude(t *testing.T) {
+	for _, alt := range []int{0, 25, 10000, 38000} {
+		msg := CreateADSBPositionMessage(0xABCDEF, 0, 0, alt, false)
+
+		got, ok := adsb.DecodeAltitude(msg)
+		if !ok || got != alt {
+			t.Errorf("altitude %d decoded as %d (ok=%v)", alt, got, ok)
+		}
+	}
+}
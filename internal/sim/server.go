@@ -0,0 +1,365 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
+)
+
+// Constants for ADS-B message types
+const (
+	// Beast message types
+	ModeAC    = byte('1') // Mode A/C message
+	ModeShort = byte('2') // Mode S short message
+	ModeLong  = byte('3') // Mode S long message
+
+	// Downlink Format types
+	DF17 = 17 // ADS-B message
+	DF18 = 18 // ADS-B message via TIS-B
+
+	// Type codes
+	TC_IDENT        = 4  // Aircraft identification
+	TC_AIRBORNE_POS = 11 // Airborne position
+	TC_AIRBORNE_VEL = 19 // Airborne velocity
+
+	// Other constants
+	EscapeChar = byte(0x1A) // Beast protocol escape character
+)
+
+// writeTimeout bounds how long a client's writer waits on a stalled connection
+// before giving up on it
+const writeTimeout = 5 * time.Second
+
+// clientQueueSize is how many frames may wait for a client, a few seconds of
+// traffic, before new ones are dropped
+const clientQueueSize = 512
+
+// client is a connected consumer with its own queue and writer goroutine, so
+// a slow client can't hold up the simulation or the other clients
+type client struct {
+	conn    net.Conn
+	queue   chan []byte // Encoded frames waiting to be written, closed on removal
+	dropped int         // Frames dropped because the queue was full
+}
+
+// SimAircraft represents a simulated aircraft
+type SimAircraft struct {
+	ICAO      uint32    // 24-bit ICAO address
+	Callsign  string    // Flight number/callsign
+	Lat       float64   // Latitude
+	Lon       float64   // Longitude
+	Alt       int       // Altitude in feet
+	Speed     int       // Ground speed in knots
+	Heading   int       // Track in degrees
+	ClimbRate int       // Vertical rate in ft/min
+	Odd       bool      // CPR odd/even flag toggle
+	LastSeen  time.Time // Time of last position update
+	mutex     sync.Mutex
+}
+
+// BeastServer simulates a Beast format data provider
+type BeastServer struct {
+	aircraft  map[uint32]*SimAircraft
+	listeners []*client
+	mutex     sync.Mutex
+	wg        sync.WaitGroup // Update loop and client goroutines, awaited by Serve
+}
+
+// NewBeastServer creates a new Beast server
+func NewBeastServer() *BeastServer {
+	return &BeastServer{
+		aircraft:  make(map[uint32]*SimAircraft),
+		listeners: make([]*client, 0),
+	}
+}
+
+// AddAircraft adds a new aircraft to the simulation
+func (s *BeastServer) AddAircraft(icao uint32, callsign string, lat, lon float64, alt, speed, heading int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.aircraft[icao] = &SimAircraft{
+		ICAO:      icao,
+		Callsign:  callsign,
+		Lat:       lat,
+		Lon:       lon,
+		Alt:       alt,
+		Speed:     speed,
+		Heading:   heading,
+		ClimbRate: rand.Intn(1000) - 500, // Random climb rate between -500 and 500 ft/min
+		LastSeen:  time.Now(),
+	}
+}
+
+// AddSampleAircraft adds a handful of aircraft spread around the given position
+func (s *BeastServer) AddSampleAircraft(lat, lon float64) {
+	s.AddAircraft(0xABCDEF, "SWA1234", lat, lon, 10000, 450, 45)
+	s.AddAircraft(0x123456, "UAL789", lat+0.1561, lon-0.0438, 25000, 500, 270)
+	s.AddAircraft(0x789ABC, "DAL456", lat+0.2528, lon+0.1029, 35000, 550, 180)
+	s.AddAircraft(0x456DEF, "AAL100", lat+0.3912, lon+0.2756, 15000, 400, 135)
+	s.AddAircraft(0xFEDCBA, "JBU202", lat-0.1188, lon-0.1244, 28000, 480, 90)
+}
+
+// Start runs the server on the specified port until ctx is cancelled
+func (s *BeastServer) Start(ctx context.Context, port int) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf("0.0.0.0:%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to start server: %v", err)
+	}
+
+	eventlog.Printf("Beast server running on port %d\n", port)
+
+	return s.Serve(ctx, listener)
+}
+
+// Serve runs the simulation and accepts clients on an existing listener
+// until ctx is cancelled. The listener and client connections are closed and
+// all goroutines have exited when Serve returns.
+func (s *BeastServer) Serve(ctx context.Context, listener net.Listener) error {
+	// Closing the listener and clients unblocks Accept and client reads
+	stop := context.AfterFunc(ctx, func() {
+		listener.Close()
+		s.closeClients()
+	})
+	defer stop()
+	defer listener.Close()
+
+	// Start the update goroutine
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.updateLoop(ctx)
+	}()
+
+	// Accept connections
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				break // Shutting down
+			}
+			eventlog.Printf("Error accepting connection: %v\n", err)
+			continue
+		}
+
+		eventlog.Printf("Client connected: %s\n", conn.RemoteAddr())
+		c := s.addClient(conn)
+
+		// A client accepted as the context is cancelled may have missed closeClients
+		if ctx.Err() != nil {
+			conn.Close()
+		}
+
+		// Handle client in a goroutine
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handleClient(c)
+		}()
+	}
+
+	s.wg.Wait()
+	return nil
+}
+
+// closeClients closes every client connection
+func (s *BeastServer) closeClients() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, c := range s.listeners {
+		c.conn.Close()
+	}
+}
+
+// addClient registers a connection and starts its writer goroutine
+func (s *BeastServer) addClient(conn net.Conn) *client {
+	c := &client{conn: conn, queue: make(chan []byte, clientQueueSize)}
+
+	s.mutex.Lock()
+	s.listeners = append(s.listeners, c)
+	s.mutex.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.writeClient(c)
+	}()
+	return c
+}
+
+// removeClient closes a client and stops its writer. It is safe to call
+// more than once.
+func (s *BeastServer) removeClient(c *client) {
+	c.conn.Close()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, other := range s.listeners {
+		if other == c {
+			s.listeners = append(s.listeners[:i], s.listeners[i+1:]...)
+			close(c.queue)
+			return
+		}
+	}
+}
+
+// writeClient writes queued frames to a client until its queue is closed,
+// removing the client as soon as a write fails or times out
+func (s *BeastServer) writeClient(c *client) {
+	for msg := range c.queue {
+		c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err := c.conn.Write(msg); err != nil {
+			eventlog.Printf("Error writing to client %s: %v, disconnecting\n", c.conn.RemoteAddr(), err)
+			s.removeClient(c)
+			return
+		}
+	}
+}
+
+// handleClient handles a client connection
+func (s *BeastServer) handleClient(c *client) {
+	defer func() {
+		s.removeClient(c)
+		eventlog.Printf("Client disconnected: %s\n", c.conn.RemoteAddr())
+	}()
+
+	// Drain client input until it disconnects or Serve closes the connection
+	buffer := make([]byte, 1024)
+	for {
+		if _, err := c.conn.Read(buffer); err != nil {
+			break
+		}
+	}
+}
+
+// updateLoop periodically updates aircraft positions and sends messages
+func (s *BeastServer) updateLoop(ctx context.Context) {
+	ticker := time.NewTicker(200 * time.Millisecond) // 5 updates per second
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.updateAircraft()
+			s.sendUpdates()
+		}
+	}
+}
+
+// updateAircraft updates the positions of all simulated aircraft
+func (s *BeastServer) updateAircraft() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+
+	for _, a := range s.aircraft {
+		a.mutex.Lock()
+
+		// Calculate time since last update
+		elapsed := now.Sub(a.LastSeen).Seconds()
+		a.LastSeen = now
+
+		// Update position based on speed and heading
+		distanceNM := float64(a.Speed) * elapsed / 3600.0 // Convert knots to NM/s
+
+		// Convert to radians and calculate movement
+		headingRad := float64(a.Heading) * math.Pi / 180.0
+
+		// Move aircraft - adjust longitude based on latitude for accurate movement
+		latFactor := math.Cos(a.Lat * math.Pi / 180.0)
+		a.Lon += (distanceNM * math.Sin(headingRad)) / (60.0 * latFactor)
+		a.Lat += (distanceNM * math.Cos(headingRad)) / 60.0
+
+		// Update altitude based on climb rate
+		a.Alt += int((float64(a.ClimbRate) * elapsed) / 60.0)
+
+		// Randomly change heading slightly for realistic variation
+		if rand.Float64() < 0.05 { // 5% chance per update
+			a.Heading += rand.Intn(3) - 1 // -1, 0, or 1 degree
+
+			// Keep heading in 0-359 range
+			if a.Heading < 0 {
+				a.Heading += 360
+			} else if a.Heading >= 360 {
+				a.Heading -= 360
+			}
+		}
+
+		// Randomly change climb rate occasionally
+		if rand.Float64() < 0.02 { // 2% chance per update
+			a.ClimbRate = rand.Intn(2000) - 1000 // Between -1000 and 1000 ft/min
+		}
+
+		// Toggle odd/even flag
+		a.Odd = !a.Odd
+
+		a.mutex.Unlock()
+	}
+}
+
+// sendUpdates sends ADS-B messages for all aircraft to all connected clients
+func (s *BeastServer) sendUpdates() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.listeners) == 0 {
+		return // No clients connected
+	}
+
+	timestamp := uint64(time.Now().UnixNano() / 1000000) // Timestamp in milliseconds
+
+	for _, a := range s.aircraft {
+		a.mutex.Lock()
+
+		// Create and send messages for this aircraft
+
+		// Only send ID message occasionally (about every 5 seconds)
+		if rand.Float64() < 0.05 {
+			idMsg := CreateADSBIdentMessage(a.ICAO, a.Callsign)
+			beastMsg := EncodeBeastMessage(ModeLong, idMsg, timestamp, byte(rand.Intn(100)+100))
+			s.broadcast(beastMsg)
+		}
+
+		// Always send position message
+		posMsg := CreateADSBPositionMessage(a.ICAO, a.Lat, a.Lon, a.Alt, a.Odd)
+		beastMsg := EncodeBeastMessage(ModeLong, posMsg, timestamp, byte(rand.Intn(100)+100))
+		s.broadcast(beastMsg)
+
+		// Always send velocity message
+		velMsg := CreateADSBVelocityMessage(a.ICAO, a.Speed, a.Heading, a.ClimbRate)
+		beastMsg = EncodeBeastMessage(ModeLong, velMsg, timestamp, byte(rand.Intn(100)+100))
+		s.broadcast(beastMsg)
+
+		a.mutex.Unlock()
+
+		// Small delay between aircraft updates to prevent flooding
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// broadcast queues a message for every connected client without waiting on
+// any of them. Clients whose queue is full miss the message, like a real
+// Beast server shedding load for a slow feeder. The caller must hold s.mutex.
+func (s *BeastServer) broadcast(msg []byte) {
+	for _, c := range s.listeners {
+		select {
+		case c.queue <- msg:
+		default:
+			if c.dropped == 0 {
+				eventlog.Printf("Client %s is falling behind, dropping frames\n", c.conn.RemoteAddr())
+			}
+			c.dropped++
+		}
+	}
+}
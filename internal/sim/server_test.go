@@ -0,0 +1,75 @@
+package sim
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestBroadcastDropsFailedClients(t *testing.T) {
+	s := NewBeastServer()
+
+	// One client reading normally and one that has gone away
+	live, liveRemote := net.Pipe()
+	dead, deadRemote := net.Pipe()
+	deadRemote.Close()
+	defer live.Close()
+	go io.Copy(io.Discard, liveRemote)
+
+	s.addClient(dead)
+	liveClient := s.addClient(live)
+
+	s.mutex.Lock()
+	s.broadcast([]byte{EscapeChar, ModeShort, 1, 2, 3})
+	s.mutex.Unlock()
+
+	// The dead client's writer removes it without waiting for a reader
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.mutex.Lock()
+		n := len(s.listeners)
+		s.mutex.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d clients left, want only the live one", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if s.listeners[0] != liveClient {
+		t.Error("live client was removed")
+	}
+}
+
+func TestBroadcastDoesNotBlockOnSlowClient(t *testing.T) {
+	s := NewBeastServer()
+
+	// A client that never reads
+	slow, slowRemote := net.Pipe()
+	defer slowRemote.Close()
+	c := s.addClient(slow)
+
+	done := make(chan struct{})
+	go func() {
+		s.mutex.Lock()
+		for i := 0; i < clientQueueSize*2; i++ {
+			s.broadcast([]byte{EscapeChar, ModeShort, 1, 2, 3})
+		}
+		s.mutex.Unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("broadcast blocked on a client that isn't reading")
+	}
+	if c.dropped < clientQueueSize-1 {
+		t.Errorf("dropped %d frames, want the overflow dropped", c.dropped)
+	}
+
+	s.removeClient(c)
+	s.wg.Wait()
+}
@@ -0,0 +1,88 @@
+package viz
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// colorStop is an altitude and the symbol color at it
+type colorStop struct {
+	Altitude int
+	Color    sdl.Color
+}
+
+// defaultAltitudeStops is the built-in gradient from orange near the ground
+// through green and blue to violet at cruise
+var defaultAltitudeStops = []colorStop{
+	{0, sdl.Color{R: 255, G: 140, B: 0, A: 255}},
+	{4000, sdl.Color{R: 255, G: 220, B: 0, A: 255}},
+	{10000, sdl.Color{R: 100, G: 255, B: 100, A: 255}},
+	{20000, sdl.Color{R: 0, G: 200, B: 255, A: 255}},
+	{30000, sdl.Color{R: 80, G: 120, B: 255, A: 255}},
+	{40000, sdl.Color{R: 200, G: 80, B: 255, A: 255}},
+}
+
+// parseAltitudeStops parses a JSON list of {"altitude": feet, "color": "#rrggbb"}
+// stops, which must be in strictly increasing altitude order
+func parseAltitudeStops(text string) ([]colorStop, error) {
+	var raw []struct {
+		Altitude *int   `json:"altitude"`
+		Color    string `json:"color"`
+	}
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, fmt.Errorf("invalid altitude color JSON: %v", err)
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("no altitude color stops")
+	}
+
+	stops := make([]colorStop, len(raw))
+	for i, s := range raw {
+		if s.Altitude == nil {
+			return nil, fmt.Errorf("stop %d has no altitude", i+1)
+		}
+		color, err := parseHexColor(s.Color)
+		if err != nil {
+			return nil, fmt.Errorf("stop %d: %v", i+1, err)
+		}
+		if i > 0 && *s.Altitude <= stops[i-1].Altitude {
+			return nil, fmt.Errorf("stop %d at %d ft is not above the previous stop at %d ft",
+				i+1, *s.Altitude, stops[i-1].Altitude)
+		}
+		stops[i] = colorStop{Altitude: *s.Altitude, Color: color}
+	}
+	return stops, nil
+}
+
+// parseHexColor parses an opaque "#rrggbb" or "rrggbb" color
+func parseHexColor(s string) (sdl.Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 {
+		return sdl.Color{}, fmt.Errorf("color %q is not #rrggbb", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return sdl.Color{}, fmt.Errorf("color %q is not #rrggbb", s)
+	}
+	return sdl.Color{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+}
+
+// altitudeColor interpolates the color for an altitude between the stops,
+// holding the end colors beyond the first and last stop
+func altitudeColor(stops []colorStop, alt int) sdl.Color {
+	if alt <= stops[0].Altitude {
+		return stops[0].Color
+	}
+	for i := 1; i < len(stops); i++ {
+		if alt <= stops[i].Altitude {
+			lo, hi := stops[i-1], stops[i]
+			t := float64(alt-lo.Altitude) / float64(hi.Altitude-lo.Altitude)
+			return lerpColor(lo.Color, hi.Color, t)
+		}
+	}
+	return stops[len(stops)-1].Color
+}
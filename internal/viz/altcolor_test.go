@@ -0,0 +1,58 @@
+package viz
+
+import (
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func TestParseAltitudeStops(t *testing.T) {
+	stops, err := parseAltitudeStops(`[{"altitude": 0, "color": "#ff0000"}, {"altitude": 10000, "color": "0000FF"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stops) != 2 || stops[1].Altitude != 10000 || stops[1].Color != (sdl.Color{B: 255, A: 255}) {
+		t.Errorf("stops = %+v", stops)
+	}
+
+	bad := map[string]string{
+		"not JSON":         `{"altitude": 0}`,
+		"empty":            `[]`,
+		"missing altitude": `[{"color": "#ff0000"}]`,
+		"unsorted":         `[{"altitude": 1000, "color": "#ff0000"}, {"altitude": 500, "color": "#00ff00"}]`,
+		"duplicate":        `[{"altitude": 1000, "color": "#ff0000"}, {"altitude": 1000, "color": "#00ff00"}]`,
+		"short color":      `[{"altitude": 0, "color": "#f00"}]`,
+		"bad hex":          `[{"altitude": 0, "color": "#gg0000"}]`,
+	}
+	for name, text := range bad {
+		if _, err := parseAltitudeStops(text); err == nil {
+			t.Errorf("%s: accepted %s", name, text)
+		}
+	}
+}
+
+func TestAltitudeColor(t *testing.T) {
+	stops := []colorStop{
+		{0, sdl.Color{R: 200, A: 255}},
+		{10000, sdl.Color{G: 200, A: 255}},
+		{20000, sdl.Color{B: 200, A: 255}},
+	}
+
+	tests := []struct {
+		alt  int
+		want sdl.Color
+	}{
+		{-500, sdl.Color{R: 200, A: 255}}, // Below the first stop
+		{0, sdl.Color{R: 200, A: 255}},
+		{5000, sdl.Color{R: 100, G: 100, A: 255}}, // Falling channels interpolate too
+		{10000, sdl.Color{G: 200, A: 255}},
+		{15000, sdl.Color{G: 100, B: 100, A: 255}},
+		{45000, sdl.Color{B: 200, A: 255}}, // Above the last stop
+	}
+
+	for _, tt := range tests {
+		if got := altitudeColor(stops, tt.alt); got != tt.want {
+			t.Errorf("altitudeColor(%d) = %+v, want %+v", tt.alt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package viz
+
+import "fmt"
+
+// FormatAltitude formats an altitude given in feet for display. In metric
+// mode it is shown in meters; otherwise altitudes at or above flTransition
+// are shown as a flight level such as FL350 and lower ones in feet. An
+// flTransition of 0 always shows feet.
+func FormatAltitude(ft int, metric bool, flTransition int) string {
+	if metric {
+		return fmt.Sprintf("%dm", int(float64(ft)*0.3048))
+	}
+	if flTransition > 0 && ft >= flTransition {
+		return fmt.Sprintf("FL%03d", (ft+50)/100)
+	}
+	return fmt.Sprintf("%d'", ft)
+}
+
+// FormatVertRate formats a vertical rate given in ft/min for display, in m/s
+// in metric mode, with an explicit sign for climbs
+func FormatVertRate(fpm int, metric bool) string {
+	if metric {
+		return fmt.Sprintf("%+.1fm/s", float64(fpm)*0.3048/60)
+	}
+	return fmt.Sprintf("%+dft/min", fpm)
+}
+
+// vertRateArrow returns an up or down arrow when the magnitude of a vertical
+// rate in ft/min reaches threshold, or "" when level or threshold is 0
+func vertRateArrow(fpm, threshold int) string {
+	switch {
+	case threshold <= 0:
+		return ""
+	case fpm >= threshold:
+		return "\u2191"
+	case fpm <= -threshold:
+		return "\u2193"
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package viz
+
+import "testing"
+
+func TestFormatAltitude(t *testing.T) {
+	tests := []struct {
+		ft           int
+		metric       bool
+		flTransition int
+		want         string
+	}{
+		{35000, false, 18000, "FL350"},
+		{18000, false, 18000, "FL180"}, // At the transition altitude
+		{17975, false, 18000, "17975'"},
+		{5000, false, 3000, "FL050"},   // Low European transition, padded
+		{34975, false, 18000, "FL350"}, // Rounded to the nearest level
+		{35000, false, 0, "35000'"},    // Flight levels disabled
+		{-200, false, 18000, "-200'"},
+		{35000, true, 18000, "10668m"}, // Metric never uses flight levels
+		{1000, true, 0, "304m"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatAltitude(tt.ft, tt.metric, tt.flTransition); got != tt.want {
+			t.Errorf("FormatAltitude(%d, %v, %d) = %q, want %q", tt.ft, tt.metric, tt.flTransition, got, tt.want)
+		}
+	}
+}
+
+func TestFormatVertRate(t *testing.T) {
+	tests := []struct {
+		fpm    int
+		metric bool
+		want   string
+	}{
+		{1280, false, "+1280ft/min"},
+		{-640, false, "-640ft/min"},
+		{0, false, "+0ft/min"},
+		{1000, true, "+5.1m/s"},
+		{-2000, true, "-10.2m/s"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatVertRate(tt.fpm, tt.metric); got != tt.want {
+			t.Errorf("FormatVertRate(%d, %v) = %q, want %q", tt.fpm, tt.metric, got, tt.want)
+		}
+	}
+}
+
+func TestVertRateArrow(t *testing.T) {
+	tests := []struct {
+		fpm, threshold int
+		want           string
+	}{
+		{500, 500, "\u2191"}, // At the threshold
+		{-1500, 500, "\u2193"},
+		{448, 500, ""},
+		{-448, 500, ""},
+		{3000, 0, ""}, // Disabled
+	}
+
+	for _, tt := range tests {
+		if got := vertRateArrow(tt.fpm, tt.threshold); got != tt.want {
+			t.Errorf("vertRateArrow(%d, %d) = %q, want %q", tt.fpm, tt.threshold, got, tt.want)
+		}
+	}
+}
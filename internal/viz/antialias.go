@@ -0,0 +1,158 @@
+package viz
+
+import (
+	"math"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// aaLevels is how many coverage levels anti-aliased pixels are rounded to,
+// each drawn with one call
+const aaLevels = 8
+
+// wuLine calls plot for each pixel a line from (x0, y0) to (x1, y1) touches,
+// with the fraction of the pixel it covers, using Xiaolin Wu's algorithm
+func wuLine(x0, y0, x1, y1 float64, plot func(x, y int, coverage float64)) {
+	steep := math.Abs(y1-y0) > math.Abs(x1-x0)
+	if steep {
+		x0, y0, x1, y1 = y0, x0, y1, x1
+	}
+	if x0 > x1 {
+		x0, y0, x1, y1 = x1, y1, x0, y0
+	}
+
+	// Plot in the transposed space for steep lines
+	put := func(x, y int, c float64) {
+		if c <= 0 {
+			return
+		}
+		if steep {
+			plot(y, x, c)
+		} else {
+			plot(x, y, c)
+		}
+	}
+
+	gradient := 1.0
+	if dx := x1 - x0; dx != 0 {
+		gradient = (y1 - y0) / dx
+	}
+
+	// Each pixel column gets the two pixels straddling the ideal line,
+	// weighted by distance; the end columns are weighted by how much of
+	// them the line spans
+	xStart, xEnd := int(math.Round(x0)), int(math.Round(x1))
+	y := y0 + gradient*(float64(xStart)-x0)
+	for x := xStart; x <= xEnd; x++ {
+		span := 1.0
+		if x == xStart {
+			span = 1 - (x0 + 0.5 - float64(xStart))
+		}
+		if x == xEnd {
+			span = x1 + 0.5 - float64(xEnd)
+		}
+		if xStart == xEnd {
+			span = x1 - x0
+		}
+
+		base := math.Floor(y)
+		frac := y - base
+		put(x, int(base), (1-frac)*span)
+		put(x, int(base)+1, frac*span)
+		y += gradient
+	}
+}
+
+// clipLine clips a line to the rectangle from (0, 0) to (w, h), so lines
+// running far off screen aren't rasterized, using Liang-Barsky. ok is false
+// when no part of the line is inside.
+func clipLine(x0, y0, x1, y1, w, h float64) (cx0, cy0, cx1, cy1 float64, ok bool) {
+	t0, t1 := 0.0, 1.0
+	dx, dy := x1-x0, y1-y0
+
+	// Each edge as p*t <= q
+	edges := [4][2]float64{{-dx, x0}, {dx, w - x0}, {-dy, y0}, {dy, h - y0}}
+	for _, e := range edges {
+		p, q := e[0], e[1]
+		if p == 0 {
+			if q < 0 {
+				return 0, 0, 0, 0, false
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			t0 = math.Max(t0, t)
+		} else {
+			t1 = math.Min(t1, t)
+		}
+	}
+	if t0 > t1 {
+		return 0, 0, 0, 0, false
+	}
+	return x0 + t0*dx, y0 + t0*dy, x0 + t1*dx, y0 + t1*dy, true
+}
+
+// lineBatch collects the pixels of anti-aliased lines of one color by
+// coverage, so each level is drawn in a single call
+type lineBatch struct {
+	color  sdl.Color
+	levels [aaLevels][]sdl.Point
+}
+
+// add rasterizes the part of a line inside a width by height screen into
+// the batch
+func (b *lineBatch) add(x1, y1, x2, y2, width, height int) {
+	fx1, fy1, fx2, fy2, ok := clipLine(float64(x1), float64(y1), float64(x2), float64(y2), float64(width), float64(height))
+	if !ok {
+		return
+	}
+	wuLine(fx1, fy1, fx2, fy2, func(x, y int, coverage float64) {
+		if level := int(math.Round(coverage * aaLevels)); level > 0 {
+			b.levels[level-1] = append(b.levels[level-1], sdl.Point{X: int32(x), Y: int32(y)})
+		}
+	})
+}
+
+// beginLines starts a run of one pixel lines in a color, anti-aliased when
+// configured. Lines are added with line and finished with endLines.
+func (r *Renderer) beginLines(color sdl.Color) {
+	if !r.config.AntiAlias {
+		r.renderer.SetDrawColor(color.R, color.G, color.B, color.A)
+		return
+	}
+
+	r.lines.color = color
+	for i := range r.lines.levels {
+		r.lines.levels[i] = r.lines.levels[i][:0]
+	}
+}
+
+// line draws a line, or queues it until endLines when anti-aliasing
+func (r *Renderer) line(x1, y1, x2, y2 int) {
+	if !r.config.AntiAlias {
+		r.renderer.DrawLine(int32(x1), int32(y1), int32(x2), int32(y2))
+		return
+	}
+	r.lines.add(x1, y1, x2, y2, r.width, r.height)
+}
+
+// endLines draws the anti-aliased lines queued since beginLines, blending
+// each coverage level in proportion to its alpha
+func (r *Renderer) endLines() {
+	if !r.config.AntiAlias {
+		return
+	}
+
+	c := r.lines.color
+	r.renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	for i, points := range r.lines.levels {
+		if len(points) == 0 {
+			continue
+		}
+		alpha := uint8(int(c.A) * (i + 1) / aaLevels)
+		r.renderer.SetDrawColor(c.R, c.G, c.B, alpha)
+		r.renderer.DrawPoints(points)
+	}
+	r.renderer.SetDrawBlendMode(sdl.BLENDMODE_NONE)
+}
@@ -0,0 +1,75 @@
+package viz
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWuLineCoverage(t *testing.T) {
+	tests := []struct {
+		name           string
+		x0, y0, x1, y1 float64
+	}{
+		{"horizontal", 0, 5, 10, 5},
+		{"shallow", 0, 0, 20, 7},
+		{"steep", 3, 0, 8, 25},
+		{"reversed", 20, 7, 0, 0},
+		{"diagonal", 0, 0, 10, 10},
+	}
+
+	for _, tt := range tests {
+		// Every column (or row for steep lines) along the line should be
+		// covered once in total, split between the pixels it straddles
+		steep := math.Abs(tt.y1-tt.y0) > math.Abs(tt.x1-tt.x0)
+		cover := map[int]float64{}
+		wuLine(tt.x0, tt.y0, tt.x1, tt.y1, func(x, y int, c float64) {
+			if c < 0 || c > 1 {
+				t.Errorf("%s: coverage %v at %d,%d out of range", tt.name, c, x, y)
+			}
+			if steep {
+				cover[y] += c
+			} else {
+				cover[x] += c
+			}
+		})
+
+		lo, hi := math.Min(tt.x0, tt.x1), math.Max(tt.x0, tt.x1)
+		if steep {
+			lo, hi = math.Min(tt.y0, tt.y1), math.Max(tt.y0, tt.y1)
+		}
+		for i := int(lo) + 1; i < int(hi); i++ {
+			if math.Abs(cover[i]-1) > 1e-9 {
+				t.Errorf("%s: column %d covered %.3f, want 1", tt.name, i, cover[i])
+			}
+		}
+	}
+}
+
+func TestWuLineHorizontalIsSolid(t *testing.T) {
+	wuLine(0, 5, 10, 5, func(x, y int, c float64) {
+		if y != 5 {
+			t.Errorf("pixel %d,%d off a horizontal line", x, y)
+		}
+		if x > 0 && x < 10 && c != 1 {
+			t.Errorf("inner pixel %d covered %v, want 1", x, c)
+		}
+	})
+}
+
+func TestClipLine(t *testing.T) {
+	// A line running far off both sides is cut at the edges
+	x0, y0, x1, y1, ok := clipLine(-1e6, 50, 1e6, 50, 800, 600)
+	if !ok || math.Abs(x0) > 1e-6 || math.Abs(x1-800) > 1e-6 || y0 != 50 || y1 != 50 {
+		t.Errorf("clipped to %v,%v %v,%v ok=%v, want 0,50 800,50", x0, y0, x1, y1, ok)
+	}
+
+	// One fully inside is unchanged
+	if x0, y0, x1, y1, ok := clipLine(10, 20, 30, 40, 800, 600); !ok || x0 != 10 || y0 != 20 || x1 != 30 || y1 != 40 {
+		t.Errorf("inside line changed to %v,%v %v,%v", x0, y0, x1, y1)
+	}
+
+	// And one fully outside is dropped
+	if _, _, _, _, ok := clipLine(-50, -10, -10, -50, 800, 600); ok {
+		t.Error("line outside the screen not dropped")
+	}
+}
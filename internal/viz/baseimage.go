@@ -0,0 +1,256 @@
+package viz
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg" // Register JPEG decoding for base images
+	_ "image/png"  // Register PNG decoding for base images
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
+	"github.com/OJPARKINSON/viz1090/internal/map_system"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// baseImageStrips is the number of horizontal strips a base image is drawn
+// in, so the longitude scale can follow latitude down the image
+const baseImageStrips = 32
+
+// baseImageMeshCells is the number of grid cells along each side of the
+// mesh a base image is warped onto when the projection curves parallels
+const baseImageMeshCells = 32
+
+// maxMeshDistance is how far from the view center, in NM, mesh corners are
+// kept. Cells near the antipode wrap around the edge of an azimuthal map.
+const maxMeshDistance = 10000.0
+
+// imageBounds is the geographic extent of a north-up raster image, measured
+// at the outer edges of its edge pixels
+type imageBounds struct {
+	North, South, West, East float64
+}
+
+// baseImage is a georeferenced raster drawn beneath the vector map
+type baseImage struct {
+	texture *sdl.Texture
+	width   int
+	height  int
+	bounds  imageBounds
+}
+
+// worldFilePath returns the world file path for an image: the extension's
+// first and last letters plus 'w', e.g. map.png -> map.pgw
+func worldFilePath(imagePath string) string {
+	ext := filepath.Ext(imagePath)
+	base := strings.TrimSuffix(imagePath, ext)
+	if len(ext) < 3 {
+		return base + ext + "w"
+	}
+	return base + "." + ext[1:2] + ext[len(ext)-1:] + "w"
+}
+
+// parseWorldFile reads the bounds of a width x height image from the six
+// lines of a world file. Rotated images are not supported.
+func parseWorldFile(text string, width, height int) (imageBounds, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 6 {
+		return imageBounds{}, fmt.Errorf("world file has %d values, want 6", len(fields))
+	}
+
+	var v [6]float64
+	for i := range v {
+		f, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return imageBounds{}, fmt.Errorf("invalid world file value %q: %v", fields[i], err)
+		}
+		v[i] = f
+	}
+
+	// A and E are the pixel sizes, D and B rotation, C and F the center of
+	// the top left pixel
+	a, d, b, e, c, f := v[0], v[1], v[2], v[3], v[4], v[5]
+	if d != 0 || b != 0 {
+		return imageBounds{}, fmt.Errorf("rotated world files are not supported")
+	}
+	if a <= 0 || e >= 0 {
+		return imageBounds{}, fmt.Errorf("world file is not north-up")
+	}
+
+	west := c - a/2
+	north := f - e/2
+	return imageBounds{
+		North: north,
+		South: north + e*float64(height),
+		West:  west,
+		East:  west + a*float64(width),
+	}, nil
+}
+
+// loadBaseImage decodes a PNG or JPEG and uploads it as a texture. The
+// configured bounds are used when set, otherwise the image's world file.
+func (r *Renderer) loadBaseImage(path string) (*baseImage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %v", path, err)
+	}
+	size := img.Bounds().Size()
+
+	bounds := imageBounds{
+		North: r.config.BaseImageNorth,
+		South: r.config.BaseImageSouth,
+		West:  r.config.BaseImageWest,
+		East:  r.config.BaseImageEast,
+	}
+	if bounds == (imageBounds{}) {
+		worldFile, err := os.ReadFile(worldFilePath(path))
+		if err != nil {
+			return nil, fmt.Errorf("no bounds configured and no world file: %v", err)
+		}
+		if bounds, err = parseWorldFile(string(worldFile), size.X, size.Y); err != nil {
+			return nil, fmt.Errorf("failed to read world file: %v", err)
+		}
+	}
+	if bounds.North <= bounds.South || bounds.East <= bounds.West {
+		return nil, fmt.Errorf("invalid bounds %+v", bounds)
+	}
+
+	// Convert to packed RGBA, which is ABGR8888 in SDL's little endian terms
+	rgba := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	texture, err := r.renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STATIC,
+		int32(size.X), int32(size.Y))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create texture: %v", err)
+	}
+	if err := texture.Update(nil, unsafe.Pointer(&rgba.Pix[0]), rgba.Stride); err != nil {
+		texture.Destroy()
+		return nil, fmt.Errorf("failed to upload image: %v", err)
+	}
+
+	return &baseImage{texture: texture, width: size.X, height: size.Y, bounds: bounds}, nil
+}
+
+// drawBaseImage draws the base image into the current render target, in
+// strips so each is scaled for its own latitude, or warped onto a projected
+// mesh under the azimuthal projection
+func (r *Renderer) drawBaseImage(centerLat, centerLon, maxDistance float64) {
+	img := r.baseImage
+	if r.projection == map_system.AzimuthalEquidistant {
+		r.drawBaseImageMesh(centerLat, centerLon, maxDistance)
+		return
+	}
+	latPerRow := (img.bounds.North - img.bounds.South) / float64(img.height)
+
+	for i := 0; i < baseImageStrips; i++ {
+		row0 := img.height * i / baseImageStrips
+		row1 := img.height * (i + 1) / baseImageStrips
+		if row1 <= row0 {
+			continue
+		}
+
+		north := img.bounds.North - float64(row0)*latPerRow
+		south := img.bounds.North - float64(row1)*latPerRow
+		x0, y0 := r.latLonToScreen(north, img.bounds.West, centerLat, centerLon, maxDistance)
+		x1, y1 := r.latLonToScreen(south, img.bounds.East, centerLat, centerLon, maxDistance)
+
+		// Skip strips entirely off screen
+		if y1 < 0 || y0 > r.height || x1 < 0 || x0 > r.width {
+			continue
+		}
+
+		src := &sdl.Rect{X: 0, Y: int32(row0), W: int32(img.width), H: int32(row1 - row0)}
+		dst := &sdl.Rect{X: int32(x0), Y: int32(y0), W: int32(x1 - x0), H: int32(y1 - y0)}
+		r.renderer.Copy(img.texture, src, dst)
+	}
+}
+
+// drawBaseImageMesh draws the base image warped onto a grid of projected
+// points, so curved parallels and converging meridians stay registered
+func (r *Renderer) drawBaseImageMesh(centerLat, centerLon, maxDistance float64) {
+	proj := map_system.NewProjection(r.projection, centerLat, centerLon)
+	scale := float64(r.height) / (maxDistance * 2)
+	project := func(lat, lon float64) (float64, float64, bool) {
+		east, north := proj.Forward(lat, lon)
+		return float64(r.width)/2 + east*scale, float64(r.height)/2 - north*scale,
+			math.Hypot(east, north) <= maxMeshDistance
+	}
+
+	vertices, indices := baseImageMesh(r.baseImage.bounds, baseImageMeshCells, r.width, r.height, project)
+	if len(indices) == 0 {
+		return
+	}
+	if err := r.renderer.RenderGeometry(r.baseImage.texture, vertices, indices); err != nil {
+		eventlog.Printf("Warning: Base image can't be warped for this projection, hiding it: %v\n", err)
+		r.baseImage.texture.Destroy()
+		r.baseImage = nil
+	}
+}
+
+// baseImageMesh builds the triangles of a cells x cells grid over the image
+// bounds, placed by project and textured with the matching part of the
+// image. Cells with a corner project rejects, or entirely off one side of
+// a width x height screen, are left out.
+func baseImageMesh(bounds imageBounds, cells, width, height int,
+	project func(lat, lon float64) (x, y float64, ok bool)) ([]sdl.Vertex, []int32) {
+	n := cells + 1
+	vertices := make([]sdl.Vertex, n*n)
+	valid := make([]bool, n*n)
+	for row := 0; row < n; row++ {
+		v := float64(row) / float64(cells)
+		lat := bounds.North - v*(bounds.North-bounds.South)
+		for col := 0; col < n; col++ {
+			u := float64(col) / float64(cells)
+			x, y, ok := project(lat, bounds.West+u*(bounds.East-bounds.West))
+			i := row*n + col
+			vertices[i] = sdl.Vertex{
+				Position: sdl.FPoint{X: float32(x), Y: float32(y)},
+				Color:    sdl.Color{R: 255, G: 255, B: 255, A: 255},
+				TexCoord: sdl.FPoint{X: float32(u), Y: float32(v)},
+			}
+			valid[i] = ok
+		}
+	}
+
+	var indices []int32
+	for row := 0; row < cells; row++ {
+		for col := 0; col < cells; col++ {
+			corners := [4]int{row*n + col, row*n + col + 1, (row+1)*n + col + 1, (row+1)*n + col}
+			if !meshCellVisible(vertices, valid, corners, width, height) {
+				continue
+			}
+			indices = append(indices,
+				int32(corners[0]), int32(corners[1]), int32(corners[2]),
+				int32(corners[0]), int32(corners[2]), int32(corners[3]))
+		}
+	}
+	return vertices, indices
+}
+
+// meshCellVisible reports whether all corners of a mesh cell projected and
+// the cell may overlap the screen
+func meshCellVisible(vertices []sdl.Vertex, valid []bool, corners [4]int, width, height int) bool {
+	minX, minY := float32(math.Inf(1)), float32(math.Inf(1))
+	maxX, maxY := float32(math.Inf(-1)), float32(math.Inf(-1))
+	for _, i := range corners {
+		if !valid[i] {
+			return false
+		}
+		p := vertices[i].Position
+		minX, maxX = min(minX, p.X), max(maxX, p.X)
+		minY, maxY = min(minY, p.Y), max(maxY, p.Y)
+	}
+	return maxX >= 0 && minX <= float32(width) && maxY >= 0 && minY <= float32(height)
+}
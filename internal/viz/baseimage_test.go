@@ -0,0 +1,88 @@
+package viz
+
+import (
+	"math"
+	"testing"
+
+	"github.com/OJPARKINSON/viz1090/internal/map_system"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func TestParseWorldFile(t *testing.T) {
+	// 0.01 degree pixels with the top left pixel centered on 52.995N 1.005W
+	bounds, err := parseWorldFile("0.01\n0\n0\n-0.01\n-1.005\n52.995\n", 200, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := imageBounds{North: 53, South: 52, West: -1.01, East: 0.99}
+	got := [4]float64{bounds.North, bounds.South, bounds.West, bounds.East}
+	for i, w := range [4]float64{want.North, want.South, want.West, want.East} {
+		if math.Abs(got[i]-w) > 1e-9 {
+			t.Errorf("bounds = %+v, want %+v", bounds, want)
+			break
+		}
+	}
+
+	for _, bad := range []string{"0.01 0 0 -0.01 -1", "0.01 0.001 0 -0.01 -1 53", "0.01 0 0 0.01 -1 53"} {
+		if _, err := parseWorldFile(bad, 200, 100); err == nil {
+			t.Errorf("parseWorldFile(%q) accepted", bad)
+		}
+	}
+}
+
+func TestWorldFilePath(t *testing.T) {
+	tests := map[string]string{
+		"map.png":       "map.pgw",
+		"maps/base.jpg": "maps/base.jgw",
+		"map.jpeg":      "map.jgw",
+	}
+	for in, want := range tests {
+		if got := worldFilePath(in); got != want {
+			t.Errorf("worldFilePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBaseImageMeshFollowsProjection(t *testing.T) {
+	bounds := imageBounds{North: 80, South: 60, West: -40, East: 40}
+	proj := map_system.NewProjection(map_system.AzimuthalEquidistant, 70, 0)
+	project := func(lat, lon float64) (float64, float64, bool) {
+		east, north := proj.Forward(lat, lon)
+		return 500 + east, 500 - north, true
+	}
+
+	vertices, indices := baseImageMesh(bounds, 4, 1000, 1000, project)
+	if len(vertices) != 25 {
+		t.Fatalf("%d vertices, want 25 for a 4x4 grid", len(vertices))
+	}
+
+	// The top edge follows the 80N parallel, an arc around the pole that
+	// sags towards the center, rather than a straight line
+	nw, north, ne := vertices[0], vertices[2], vertices[4]
+	if nw.TexCoord != (sdl.FPoint{X: 0, Y: 0}) || ne.TexCoord != (sdl.FPoint{X: 1, Y: 0}) {
+		t.Errorf("corner texture coordinates %v %v, want 0,0 and 1,0", nw.TexCoord, ne.TexCoord)
+	}
+	if north.Position.Y <= nw.Position.Y+50 || nw.Position.Y != ne.Position.Y {
+		t.Errorf("80N at y %.1f %.1f %.1f, want an arc sagging in the middle", nw.Position.Y, north.Position.Y, ne.Position.Y)
+	}
+	if len(indices) != 4*4*6 {
+		t.Errorf("%d indices, want two triangles for each of 16 cells", len(indices))
+	}
+
+	// Cells off screen or with rejected corners are dropped
+	_, indices = baseImageMesh(bounds, 4, 1000, 1000, func(lat, lon float64) (float64, float64, bool) {
+		x, y, _ := project(lat, lon)
+		return x, y, lon < 30
+	})
+	if len(indices) != 4*3*6 {
+		t.Errorf("%d indices with the east column rejected, want %d", len(indices), 4*3*6)
+	}
+	_, indices = baseImageMesh(bounds, 4, 1000, 1000, func(lat, lon float64) (float64, float64, bool) {
+		x, y, ok := project(lat, lon)
+		return x + 5000, y, ok
+	})
+	if len(indices) != 0 {
+		t.Errorf("%d indices for a mesh right of the screen, want none", len(indices))
+	}
+}
@@ -0,0 +1,24 @@
+package viz
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+// drawConflicts joins each pair of aircraft predicted to lose separation with
+// a line, labelled with the time until closest approach
+func (r *Renderer) drawConflicts(aircraft map[uint32]*adsb.Aircraft) {
+	window := time.Duration(r.config.ConflictLookahead) * time.Second
+	conflicts := adsb.FindConflicts(aircraft, r.config.ConflictNM, r.config.ConflictFeet, window)
+
+	for _, c := range conflicts {
+		a, b := aircraft[c.A], aircraft[c.B]
+		r.drawThickLine(a.X, a.Y, b.X, b.Y, 2, ColorConflict)
+
+		secs := int(c.TimeToCPA.Seconds())
+		countdown := fmt.Sprintf("%d:%02d", secs/60, secs%60)
+		r.drawText(countdown, (a.X+b.X)/2+4*r.uiScale, (a.Y+b.Y)/2, r.regularFont, ColorConflict)
+	}
+}
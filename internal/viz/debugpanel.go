@@ -0,0 +1,33 @@
+package viz
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+// debugLines returns the raw CPR decode state of an aircraft for the debug
+// panel, with ages relative to now
+func debugLines(a *adsb.Aircraft, now time.Time) []string {
+	age := func(ms int64) string {
+		if ms == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.1fs", now.Sub(time.UnixMilli(ms)).Seconds())
+	}
+
+	lines := []string{
+		"CPR debug",
+		fmt.Sprintf("even %6d %6d %s", a.EvenCPRLat, a.EvenCPRLon, age(a.EvenCPRTime)),
+		fmt.Sprintf("odd  %6d %6d %s", a.OddCPRLat, a.OddCPRLon, age(a.OddCPRTime)),
+	}
+	if a.EvenCPRTime > 0 && a.OddCPRTime > 0 {
+		nlEven, nlOdd := adsb.CPRZones(a.EvenCPRLat, a.OddCPRLat)
+		lines = append(lines, fmt.Sprintf("nl   %d/%d", nlEven, nlOdd))
+	}
+	if a.CPRStatus != "" {
+		lines = append(lines, fmt.Sprintf("last %s %.1fs", a.CPRStatus, now.Sub(a.CPRStatusAt).Seconds()))
+	}
+	return lines
+}
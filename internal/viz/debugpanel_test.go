@@ -0,0 +1,33 @@
+package viz
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+func TestDebugLines(t *testing.T) {
+	now := time.Now()
+	a := &adsb.Aircraft{
+		EvenCPRLat:  93000,
+		EvenCPRLon:  51372,
+		EvenCPRTime: now.Add(-1500 * time.Millisecond).UnixMilli(),
+		CPRStatus:   "stale pair",
+		CPRStatusAt: now.Add(-2 * time.Second),
+	}
+
+	lines := debugLines(a, now)
+	want := []string{"CPR debug", "even  93000  51372 1.5s", "odd       0      0 -", "last stale pair 2.0s"}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("debugLines = %q, want %q", lines, want)
+	}
+
+	// The zones show once both halves of the pair have arrived
+	a.OddCPRLat, a.OddCPRLon, a.OddCPRTime = 74158, 50194, now.UnixMilli()
+	lines = debugLines(a, now)
+	if len(lines) != 5 || lines[3] != "nl   36/36" {
+		t.Errorf("debugLines with a pair = %q, want nl 36/36", lines)
+	}
+}
@@ -0,0 +1,34 @@
+package viz
+
+import (
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// flashDuration is how long the alert border takes to fade out
+const flashDuration = 1500 * time.Millisecond
+
+// Flash briefly outlines the display to draw attention to an alert
+func (r *Renderer) Flash() {
+	r.flashStart = time.Now()
+}
+
+// drawFlash draws the fading alert border while a flash is in progress
+func (r *Renderer) drawFlash() {
+	elapsed := time.Since(r.flashStart)
+	if r.flashStart.IsZero() || elapsed > flashDuration {
+		return
+	}
+
+	color := lerpColor(ColorSelected, ColorBackground, float64(elapsed)/float64(flashDuration))
+	width := int32(6 * r.uiScale)
+	w, h := int32(r.width), int32(r.height)
+	r.renderer.SetDrawColor(color.R, color.G, color.B, color.A)
+	r.renderer.FillRects([]sdl.Rect{
+		{X: 0, Y: 0, W: w, H: width},
+		{X: 0, Y: h - width, W: w, H: width},
+		{X: 0, Y: 0, W: width, H: h},
+		{X: w - width, Y: 0, W: width, H: h},
+	})
+}
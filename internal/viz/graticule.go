@@ -0,0 +1,106 @@
+package viz
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/OJPARKINSON/viz1090/internal/map_system"
+)
+
+// graticuleIntervals are the candidate grid spacings in degrees, smallest first
+var graticuleIntervals = []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 15, 30, 45}
+
+// graticuleInterval picks the smallest grid spacing that keeps lines at least
+// minSpacing pixels apart at the current zoom
+func graticuleInterval(pixelsPerDegree, minSpacing float64) float64 {
+	for _, interval := range graticuleIntervals {
+		if interval*pixelsPerDegree >= minSpacing {
+			return interval
+		}
+	}
+	return graticuleIntervals[len(graticuleIntervals)-1]
+}
+
+// drawGraticule draws meridians and parallels at an interval adapted to the
+// zoom level, labelling each where it meets the screen edge
+func (r *Renderer) drawGraticule(centerLat, centerLon, maxDistance float64) {
+	latMin, lonMin, latMax, lonMax := r.calculateVisibleBounds(centerLat, centerLon, maxDistance)
+
+	// One degree of latitude is 60 NM
+	pixelsPerDegree := 60.0 * float64(r.height) / (maxDistance * 2)
+	interval := graticuleInterval(pixelsPerDegree, float64(80*r.uiScale))
+
+	// Unwrap the longitude range so it increases across the screen
+	if lonMax < lonMin {
+		lonMax += 360
+	}
+
+	r.beginLines(ColorGraticule)
+	defer r.endLines()
+
+	// Parallels are horizontal in the equirectangular projection and arcs
+	// about the pole in the azimuthal one
+	const segments = 16
+	for lat := math.Ceil(latMin/interval) * interval; lat <= latMax; lat += interval {
+		if lat < -90 || lat > 90 {
+			continue
+		}
+		labelX := 2 * r.uiScale
+		x, y := r.latLonToScreen(lat, centerLon, centerLat, centerLon, maxDistance)
+		if r.projection == map_system.AzimuthalEquidistant {
+			labelX = x + 2*r.uiScale // Label where the arc crosses the center meridian
+			lonStep := (lonMax - lonMin) / segments
+			prevX, prevY := r.latLonToScreen(lat, lonMin, centerLat, centerLon, maxDistance)
+			for i := 1; i <= segments; i++ {
+				x, y := r.latLonToScreen(lat, lonMin+float64(i)*lonStep, centerLat, centerLon, maxDistance)
+				r.line(prevX, prevY, x, y)
+				prevX, prevY = x, y
+			}
+		} else {
+			r.line(0, y, r.width, y)
+		}
+		r.drawText(formatGraticuleLat(lat), labelX, y+2*r.uiScale, r.regularFont, ColorGraticule)
+	}
+
+	// Meridians bend with latitude, so draw them as short segments
+	clampedMin := math.Max(latMin, -90)
+	clampedMax := math.Min(latMax, 90)
+	step := (clampedMax - clampedMin) / segments
+
+	for lon := math.Ceil(lonMin/interval) * interval; lon <= lonMax; lon += interval {
+		wrapped := map_system.NormalizeLon(lon)
+
+		prevX, prevY := r.latLonToScreen(clampedMin, wrapped, centerLat, centerLon, maxDistance)
+		for i := 1; i <= segments; i++ {
+			x, y := r.latLonToScreen(clampedMin+float64(i)*step, wrapped, centerLat, centerLon, maxDistance)
+			r.line(prevX, prevY, x, y)
+			prevX, prevY = x, y
+		}
+
+		// prevX/prevY now sit at the top edge of the view
+		r.drawText(formatGraticuleLon(wrapped), prevX+2*r.uiScale, 2*r.uiScale, r.regularFont, ColorGraticule)
+	}
+}
+
+// formatGraticuleLat formats a latitude grid label such as "37.5N"
+func formatGraticuleLat(lat float64) string {
+	hemisphere := 'N'
+	if lat < 0 {
+		hemisphere = 'S'
+	}
+	return fmt.Sprintf("%g%c", math.Abs(roundGraticule(lat)), hemisphere)
+}
+
+// formatGraticuleLon formats a longitude grid label such as "122W"
+func formatGraticuleLon(lon float64) string {
+	hemisphere := 'E'
+	if lon < 0 {
+		hemisphere = 'W'
+	}
+	return fmt.Sprintf("%g%c", math.Abs(roundGraticule(lon)), hemisphere)
+}
+
+// roundGraticule removes floating point noise accumulated while stepping
+func roundGraticule(v float64) float64 {
+	return math.Round(v*100) / 100
+}
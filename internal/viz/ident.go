@@ -0,0 +1,28 @@
+package viz
+
+import (
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+// identDuration is how long an aircraft is flagged after it reports the
+// ident pulse, about as long as the pulse lasts
+const identDuration = 18 * time.Second
+
+// identActive reports whether an aircraft has reported the ident pulse
+// within identDuration of now
+func identActive(a *adsb.Aircraft, now time.Time) bool {
+	return !a.SeenSPI.IsZero() && now.Sub(a.SeenSPI) < identDuration
+}
+
+// drawIdent rings an aircraft that is squawking ident, blinking once a second
+func (r *Renderer) drawIdent(a *adsb.Aircraft) {
+	if r.clock.Sub(a.SeenSPI)%time.Second >= 500*time.Millisecond {
+		return
+	}
+
+	radius := int(12 * float64(r.uiScale) * r.symbolScale())
+	r.drawCircle(a.X, a.Y, radius, ColorSelected)
+	r.drawText("ID", a.X+radius, a.Y-radius-r.fontSize/2, r.regularFont, ColorSelected)
+}
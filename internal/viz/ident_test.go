@@ -0,0 +1,22 @@
+package viz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+func TestIdentActive(t *testing.T) {
+	now := time.Now()
+
+	if identActive(&adsb.Aircraft{}, now) {
+		t.Error("aircraft that never squawked ident flagged")
+	}
+	if !identActive(&adsb.Aircraft{SeenSPI: now.Add(-5 * time.Second)}, now) {
+		t.Error("recent ident not flagged")
+	}
+	if identActive(&adsb.Aircraft{SeenSPI: now.Add(-identDuration)}, now) {
+		t.Error("ident still flagged after identDuration")
+	}
+}
@@ -0,0 +1,183 @@
+package viz
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+// drawInfoPanel draws details of the selected aircraft in the top right
+// corner, followed in debug mode by its CPR decode state
+func (r *Renderer) drawInfoPanel(a *adsb.Aircraft) {
+	bottom := r.drawTextPanel(r.infoLines(a), PAD*r.uiScale)
+
+	// The signal sparkline fills the space left on the last line
+	if a.Messages > 0 {
+		lastLine := bottom - PAD*r.uiScale - r.lineHeight()
+		r.drawSignalGraph(a, r.width-PAD*r.uiScale-r.charWidth(), lastLine)
+	}
+	if r.config.Debug {
+		r.drawTextPanel(debugLines(a, time.Now()), bottom+PAD*r.uiScale)
+	}
+}
+
+// drawTextPanel draws lines in a box at the right edge starting at y, the
+// first in bold as a title, and returns the y of its bottom edge
+func (r *Renderer) drawTextPanel(lines []string, y int) int {
+	charWidth := r.charWidth()
+	lineHeight := r.lineHeight()
+
+	maxLen := 0
+	for _, line := range lines {
+		if len(line) > maxLen {
+			maxLen = len(line)
+		}
+	}
+
+	w := (maxLen + 2) * charWidth
+	h := len(lines)*lineHeight + 2*PAD*r.uiScale
+	x := r.width - w - PAD*r.uiScale
+
+	r.drawRect(int32(x), int32(y), int32(w), int32(h), ColorLabelBg)
+	r.drawRectOutline(int32(x), int32(y), int32(w), int32(h), ColorSelected)
+
+	textY := y + PAD*r.uiScale
+	for i, line := range lines {
+		font, color := r.regularFont, ColorText
+		if i == 0 {
+			font, color = r.boldFont, ColorLabel
+		}
+		r.drawText(line, x+charWidth, textY, font, color)
+		textY += lineHeight
+	}
+	return y + h
+}
+
+// infoLines returns the text lines shown in the info panel for an aircraft
+func (r *Renderer) infoLines(a *adsb.Aircraft) []string {
+	title := adsb.FormatAddress(a.ICAO)
+	if a.Flight != "" {
+		title = a.Flight + "  " + title
+	}
+
+	alt := "alt  " + r.altitudeText(a)
+	spd := "spd  " + r.formatSpeed(a.Speed)
+
+	lines := []string{title}
+	if a.Registration != "" || a.TypeCode != "" {
+		lines = append(lines, strings.TrimSpace("type "+a.TypeCode+" "+a.Registration))
+	}
+	if a.Operator != "" {
+		lines = append(lines, "op   "+a.Operator)
+	}
+	lines = append(lines, alt, spd)
+	if a.SeenTypes&adsb.SeenVelocity != 0 {
+		lines = append(lines, "vs   "+FormatVertRate(a.VertRate, r.metric))
+	}
+	if a.HasTrack {
+		lines = append(lines, fmt.Sprintf("trk  %03d", a.Track))
+	}
+	if a.HasMagHeading {
+		lines = append(lines, fmt.Sprintf("hdg  %03dM", a.MagHeading))
+	}
+	if !a.HasTrack && !a.HasMagHeading {
+		lines = append(lines, "trk  -")
+	}
+	if a.HasSquawk {
+		lines = append(lines, fmt.Sprintf("sqwk %04d", a.Squawk))
+	}
+	if a.Emergency != "" {
+		lines = append(lines, "emrg "+a.Emergency)
+	}
+	if a.SPI {
+		lines = append(lines, "ss   ident")
+	} else if a.Alert {
+		lines = append(lines, "ss   alert")
+	}
+	if a.HasIAS {
+		lines = append(lines, "ias  "+r.formatSpeed(a.IAS))
+	}
+	if a.HasTAS {
+		lines = append(lines, "tas  "+r.formatSpeed(a.TAS))
+	}
+	if a.HasMach {
+		lines = append(lines, fmt.Sprintf("mach %.3f", a.Mach))
+	}
+	if a.HasBaroVertRate {
+		lines = append(lines, "bvs  "+FormatVertRate(a.BaroVertRate, r.metric))
+	}
+	if a.HasInertialVertRate {
+		lines = append(lines, "ivs  "+FormatVertRate(a.InertialVertRate, r.metric))
+	}
+	if a.HasRoll {
+		lines = append(lines, "roll "+formatTurn(a.Roll, ""))
+	}
+	if a.HasTrackRate {
+		lines = append(lines, "turn "+formatTurn(a.TrackRate, "/s"))
+	}
+	if a.HasSelectedAltitude {
+		lines = append(lines, "sel  "+FormatAltitude(a.SelectedAltitude, r.metric, r.config.TransitionAltitude))
+	}
+	if a.HasBaroSetting {
+		lines = append(lines, fmt.Sprintf("qnh  %.0fhPa", a.BaroSetting))
+	}
+	if a.HasWind {
+		lines = append(lines, fmt.Sprintf("wind %03.0f/%dkts", a.WindDirection, a.WindSpeed))
+	}
+	if a.HasTemperature {
+		lines = append(lines, fmt.Sprintf("sat  %.1fC", a.Temperature))
+	}
+
+	lines = append(lines, "src  "+a.AddrType.String(), "data "+seenTypesString(a.SeenTypes))
+	if a.Messages > 0 {
+		// Padded to leave room for the sparkline drawn after the value
+		lines = append(lines, fmt.Sprintf("rssi %-10s %8s", formatDBFS(a.SignalLevel[(a.Messages-1)%len(a.SignalLevel)]), ""))
+	}
+	return lines
+}
+
+// formatSpeed formats a speed in knots in the configured units
+func (r *Renderer) formatSpeed(kts int) string {
+	if r.metric {
+		return fmt.Sprintf("%dkm/h", int(float64(kts)*1.852))
+	}
+	return fmt.Sprintf("%dkts", kts)
+}
+
+// formatTurn formats a roll angle or turn rate in degrees with the side it
+// turns to, e.g. "2.1R"
+func formatTurn(deg float64, unit string) string {
+	side := "R"
+	if deg < 0 {
+		side = "L"
+	} else if deg == 0 {
+		side = ""
+	}
+	return fmt.Sprintf("%.1f%s%s", math.Abs(deg), side, unit)
+}
+
+// seenTypesString renders the message types seen from an aircraft as indicator
+// letters: Position, Velocity, Ident, Ground, with '-' for types not yet seen
+func seenTypesString(seen int) string {
+	indicators := []struct {
+		flag   int
+		letter byte
+	}{
+		{adsb.SeenPosition, 'P'},
+		{adsb.SeenVelocity, 'V'},
+		{adsb.SeenIdent, 'I'},
+		{adsb.SeenSurface, 'G'},
+	}
+
+	out := make([]byte, len(indicators))
+	for i, ind := range indicators {
+		out[i] = '-'
+		if seen&ind.flag != 0 {
+			out[i] = ind.letter
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,83 @@
+package viz
+
+import (
+	"math"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+// labelGrid buckets label centers into square cells at least as large as any
+// label's repulsion range, so only labels in neighboring cells can push each
+// other apart
+type labelGrid struct {
+	cell  float64
+	cells map[[2]int][]*adsb.Aircraft
+}
+
+// labelCenter returns the screen position of the middle of a label
+func labelCenter(a *adsb.Aircraft) (x, y float64) {
+	return a.LabelX + a.LabelW/2, a.LabelY + a.LabelH/2
+}
+
+// labelRepulsion returns the push on a1's label away from a2's when their
+// centers are closer than the average of their sizes
+func labelRepulsion(a1, a2 *adsb.Aircraft) (fx, fy float64) {
+	x1, y1 := labelCenter(a1)
+	x2, y2 := labelCenter(a2)
+	dx, dy := x1-x2, y1-y2
+	dist := math.Sqrt(dx*dx + dy*dy)
+	if dist < 0.001 {
+		return 0, 0 // Avoid division by zero
+	}
+
+	targetDist := (a1.LabelW + a2.LabelW + a1.LabelH + a2.LabelH) / 4
+	if dist >= targetDist {
+		return 0, 0
+	}
+	force := 0.001 * (targetDist - dist)
+	return force * dx / dist, force * dy / dist
+}
+
+// key returns the cell holding a screen position
+func (g *labelGrid) key(x, y float64) [2]int {
+	return [2]int{int(math.Floor(x / g.cell)), int(math.Floor(y / g.cell))}
+}
+
+// reset buckets labels, sizing cells to the largest label dimension. The
+// repulsion range of two labels is the average of their sizes, which is never
+// more than that, so every pair in range is in the same or adjacent cells.
+func (g *labelGrid) reset(labels []*adsb.Aircraft) {
+	g.cell = 1
+	for _, a := range labels {
+		g.cell = math.Max(g.cell, math.Max(a.LabelW, a.LabelH))
+	}
+
+	// Keep cell slices between frames unless labels have spread over many
+	// more cells than are in use
+	if g.cells == nil || len(g.cells) > 4*len(labels)+64 {
+		g.cells = make(map[[2]int][]*adsb.Aircraft, len(labels))
+	}
+	for k, v := range g.cells {
+		g.cells[k] = v[:0]
+	}
+
+	for _, a := range labels {
+		k := g.key(labelCenter(a))
+		g.cells[k] = append(g.cells[k], a)
+	}
+}
+
+// neighbors calls f for each label other than a in a's cell and the eight
+// around it
+func (g *labelGrid) neighbors(a *adsb.Aircraft, f func(*adsb.Aircraft)) {
+	k := g.key(labelCenter(a))
+	for cx := k[0] - 1; cx <= k[0]+1; cx++ {
+		for cy := k[1] - 1; cy <= k[1]+1; cy++ {
+			for _, other := range g.cells[[2]int{cx, cy}] {
+				if other != a {
+					f(other)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package viz
+
+import (
+	"testing"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+// sceneLabels returns the labels of labelScene(n) as a slice, with sizes
+// varied so cells are sized by the largest
+func sceneLabels(n int) []*adsb.Aircraft {
+	var labels []*adsb.Aircraft
+	for _, a := range labelScene(n) {
+		a.LabelW += float64(a.ICAO%5) * 15
+		labels = append(labels, a)
+	}
+	return labels
+}
+
+func TestLabelGridFindsEveryRepellingPair(t *testing.T) {
+	labels := sceneLabels(500)
+	var g labelGrid
+	g.reset(labels)
+
+	for _, a1 := range labels {
+		near := map[*adsb.Aircraft]bool{}
+		g.neighbors(a1, func(a2 *adsb.Aircraft) { near[a2] = true })
+
+		for _, a2 := range labels {
+			if a1 == a2 {
+				continue
+			}
+			if fx, fy := labelRepulsion(a1, a2); (fx != 0 || fy != 0) && !near[a2] {
+				t.Fatalf("labels %d and %d repel but aren't neighbors in the grid", a1.ICAO, a2.ICAO)
+			}
+		}
+	}
+}
+
+// BenchmarkLabelRepulsion compares a pass of label repulsion over all pairs
+// against only the pairs in neighboring grid cells
+func BenchmarkLabelRepulsion(b *testing.B) {
+	labels := sceneLabels(500)
+
+	b.Run("pairs=all", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, a1 := range labels {
+				for _, a2 := range labels {
+					if a1 != a2 {
+						fx, fy := labelRepulsion(a1, a2)
+						a1.LabelDX += fx
+						a1.LabelDY += fy
+					}
+				}
+			}
+		}
+	})
+
+	b.Run("pairs=grid", func(b *testing.B) {
+		var g labelGrid
+		for i := 0; i < b.N; i++ {
+			g.reset(labels)
+			for _, a1 := range labels {
+				g.neighbors(a1, func(a2 *adsb.Aircraft) {
+					fx, fy := labelRepulsion(a1, a2)
+					a1.LabelDX += fx
+					a1.LabelDY += fy
+				})
+			}
+		}
+	})
+}
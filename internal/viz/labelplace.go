@@ -0,0 +1,144 @@
+package viz
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+// labelDirection is a side of the symbol a new label is placed on
+type labelDirection int
+
+const (
+	labelBelow labelDirection = iota
+	labelRight
+	labelLeft
+	labelAbove
+)
+
+// labelAuto places new labels on the least crowded side of the symbol
+const labelAuto labelDirection = -1
+
+// parseLabelPlacement returns the direction named by a LabelPlacement config
+// value: "auto" (or empty), "below", "above", "left" or "right"
+func parseLabelPlacement(name string) (labelDirection, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "auto":
+		return labelAuto, nil
+	case "below":
+		return labelBelow, nil
+	case "right":
+		return labelRight, nil
+	case "left":
+		return labelLeft, nil
+	case "above":
+		return labelAbove, nil
+	}
+	return labelAuto, fmt.Errorf("unknown label placement %q", name)
+}
+
+// labelRect is a label box in screen pixels, x and y at its top left
+type labelRect struct {
+	x, y, w, h float64
+}
+
+// overlaps reports whether two boxes intersect
+func (r labelRect) overlaps(o labelRect) bool {
+	return r.x < o.x+o.w && o.x < r.x+r.w && r.y < o.y+o.h && o.y < r.y+r.h
+}
+
+// contains reports whether a point lies in the box
+func (r labelRect) contains(x, y float64) bool {
+	return x >= r.x && x < r.x+r.w && y >= r.y && y < r.y+r.h
+}
+
+// labelCandidate returns the box of a w by h label offset pixels to one side
+// of a symbol at (x, y)
+func labelCandidate(dir labelDirection, x, y, offset, w, h float64) labelRect {
+	switch dir {
+	case labelAbove:
+		return labelRect{x - w/2, y - offset - h, w, h}
+	case labelRight:
+		return labelRect{x + offset, y - h/2, w, h}
+	case labelLeft:
+		return labelRect{x - offset - w, y - h/2, w, h}
+	}
+	return labelRect{x - w/2, y + offset, w, h}
+}
+
+// initialLabelPosition picks where a new label starts, before the overlap
+// solver takes over. With a fixed direction it is always that side; with
+// labelAuto it is the side whose box overlaps the fewest other labels and
+// symbols and stays on screen, preferring below, right, left, then above.
+// New labels placed this frame aren't sized yet, so they are passed in
+// placed.
+func (ls *LabelSystem) initialLabelPosition(a *adsb.Aircraft, aircraft map[uint32]*adsb.Aircraft, placed []labelRect, w, h float64) (float64, float64) {
+	x, y := float64(a.X), float64(a.Y)
+	offset := float64(ls.labelOffset * ls.uiScale)
+
+	if ls.placement != labelAuto {
+		r := labelCandidate(ls.placement, x, y, offset, w, h)
+		return r.x, r.y
+	}
+
+	screen := labelRect{0, 0, float64(ls.width), float64(ls.height)}
+	best, bestScore := labelRect{}, -1
+	for _, dir := range []labelDirection{labelBelow, labelRight, labelLeft, labelAbove} {
+		r := labelCandidate(dir, x, y, offset, w, h)
+
+		score := 0
+		if r.x < screen.x || r.y < screen.y || r.x+r.w > screen.w || r.y+r.h > screen.h {
+			score++
+		}
+		for _, other := range aircraft {
+			if other == a || !other.HasPosition {
+				continue
+			}
+			if r.contains(float64(other.X), float64(other.Y)) {
+				score++
+			}
+			drawn := labelRect{other.LabelX, other.LabelY, other.LabelW, other.LabelH}
+			if drawn.w > 0 && !labelHidden(other) && r.overlaps(drawn) {
+				score++
+			}
+		}
+		for _, p := range placed {
+			if r.overlaps(p) {
+				score++
+			}
+		}
+
+		if bestScore < 0 || score < bestScore {
+			best, bestScore = r, score
+		}
+	}
+	return best.x, best.y
+}
+
+// placeNewLabels gives labels that haven't been placed yet a starting
+// position, sized like the labels already drawn
+func (ls *LabelSystem) placeNewLabels(aircraft map[uint32]*adsb.Aircraft) {
+	// Estimate the size of a label that hasn't been drawn yet
+	w, h := 50*float64(ls.uiScale), 30*float64(ls.uiScale)
+	var sumW, sumH float64
+	n := 0
+	for _, a := range aircraft {
+		if a.LabelW > 0 && a.LabelH > 0 {
+			sumW += a.LabelW
+			sumH += a.LabelH
+			n++
+		}
+	}
+	if n > 0 {
+		w, h = sumW/float64(n), sumH/float64(n)
+	}
+
+	var placed []labelRect
+	for _, a := range aircraft {
+		if a.HasPosition && a.LabelX == 0 && a.LabelY == 0 {
+			a.LabelX, a.LabelY = ls.initialLabelPosition(a, aircraft, placed, w, h)
+			placed = append(placed, labelRect{a.LabelX, a.LabelY, w, h})
+		}
+	}
+}
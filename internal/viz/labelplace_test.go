@@ -0,0 +1,123 @@
+package viz
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+func TestInitialLabelPositionAvoidsCrowding(t *testing.T) {
+	ls := NewLabelSystem(800, 600, 1, false)
+
+	a := &adsb.Aircraft{ICAO: 1, HasPosition: true, X: 400, Y: 300}
+	aircraft := map[uint32]*adsb.Aircraft{1: a}
+
+	// With nothing around, the label goes below
+	x, y := ls.initialLabelPosition(a, aircraft, nil, 50, 30)
+	if x != 375 || y != 320 {
+		t.Errorf("open sky label at %v,%v, want below at 375,320", x, y)
+	}
+
+	// A label already below and another symbol to the right push it left
+	aircraft[2] = &adsb.Aircraft{ICAO: 2, HasPosition: true, X: 380, Y: 280,
+		LabelX: 370, LabelY: 325, LabelW: 60, LabelH: 30}
+	aircraft[3] = &adsb.Aircraft{ICAO: 3, HasPosition: true, X: 440, Y: 300}
+	x, y = ls.initialLabelPosition(a, aircraft, nil, 50, 30)
+	if x != 330 || y != 285 {
+		t.Errorf("crowded label at %v,%v, want left at 330,285", x, y)
+	}
+
+	// Near the bottom edge the label is kept on screen
+	edge := &adsb.Aircraft{ICAO: 4, HasPosition: true, X: 400, Y: 590}
+	x, y = ls.initialLabelPosition(edge, map[uint32]*adsb.Aircraft{4: edge}, nil, 50, 30)
+	if y+30 > 600 {
+		t.Errorf("label at %v,%v runs off the bottom of the screen", x, y)
+	}
+}
+
+func TestNewLabelsAvoidEachOther(t *testing.T) {
+	ls := NewLabelSystem(800, 600, 1, false)
+
+	// Four aircraft appearing together at almost the same spot
+	aircraft := map[uint32]*adsb.Aircraft{}
+	for i, p := range [][2]int{{400, 300}, {402, 301}, {399, 302}, {401, 299}} {
+		icao := uint32(i + 1)
+		aircraft[icao] = &adsb.Aircraft{ICAO: icao, HasPosition: true, X: p[0], Y: p[1]}
+	}
+	ls.placeNewLabels(aircraft)
+
+	// Boxes of the estimated size placeNewLabels uses before any are drawn
+	var boxes []labelRect
+	for _, a := range aircraft {
+		boxes = append(boxes, labelRect{a.LabelX, a.LabelY, 50, 30})
+	}
+	for i := range boxes {
+		for j := i + 1; j < len(boxes); j++ {
+			if boxes[i].overlaps(boxes[j]) {
+				t.Errorf("new labels overlap: %+v and %+v", boxes[i], boxes[j])
+			}
+		}
+	}
+}
+
+func TestFixedLabelPlacement(t *testing.T) {
+	ls := NewLabelSystem(800, 600, 2, false)
+	if err := ls.SetPlacement("right", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &adsb.Aircraft{ICAO: 1, HasPosition: true, X: 400, Y: 300}
+	// A crowded right side doesn't matter when the side is fixed
+	aircraft := map[uint32]*adsb.Aircraft{1: a, 2: {ICAO: 2, HasPosition: true, X: 440, Y: 300}}
+	if x, y := ls.initialLabelPosition(a, aircraft, nil, 50, 30); x != 420 || y != 285 {
+		t.Errorf("label at %v,%v, want right at 420,285", x, y)
+	}
+
+	if err := ls.SetPlacement("sideways", 10); err == nil || ls.placement != labelAuto {
+		t.Errorf("unknown placement accepted, placement %v", ls.placement)
+	}
+}
+
+func TestLabelIterationsFallWithCount(t *testing.T) {
+	prev := labelIterations(0)
+	for _, n := range []int{10, 50, 200, 500, 2000} {
+		got := labelIterations(n)
+		if got > prev || got < 1 {
+			t.Errorf("labelIterations(%d) = %d after %d, want a non-increasing count of at least 1", n, got, prev)
+		}
+		prev = got
+	}
+	if labelIterations(10) <= labelIterations(500) {
+		t.Error("few labels get no more passes than many")
+	}
+}
+
+// labelScene scatters n labelled aircraft over an 1280x960 screen with a
+// fixed seed, so runs are comparable
+func labelScene(n int) map[uint32]*adsb.Aircraft {
+	rng := rand.New(rand.NewSource(1))
+	aircraft := make(map[uint32]*adsb.Aircraft, n)
+	for i := 0; i < n; i++ {
+		x, y := rng.Intn(1280), rng.Intn(960)
+		aircraft[uint32(i+1)] = &adsb.Aircraft{ICAO: uint32(i + 1), HasPosition: true, X: x, Y: y,
+			LabelX: float64(x - 40), LabelY: float64(y + 20), LabelW: 80, LabelH: 40}
+	}
+	return aircraft
+}
+
+// BenchmarkUpdateLabels measures a frame of label layout as traffic grows
+func BenchmarkUpdateLabels(b *testing.B) {
+	for _, n := range []int{50, 200, 500} {
+		b.Run(fmt.Sprintf("aircraft=%d", n), func(b *testing.B) {
+			ls := NewLabelSystem(1280, 960, 1, false)
+			aircraft := labelScene(n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ls.UpdateLabels(aircraft, 0, 50)
+			}
+			b.ReportMetric(float64(labelIterations(n)), "passes")
+		})
+	}
+}
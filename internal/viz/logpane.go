@@ -0,0 +1,45 @@
+package viz
+
+import (
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
+)
+
+// logPaneLines is the number of recent events shown in the log pane
+const logPaneLines = 8
+
+// drawLogPane shows the most recent events from the default log in the
+// bottom left corner, above the status bar
+func (r *Renderer) drawLogPane() {
+	entries := eventlog.Default.Recent(logPaneLines)
+	if len(entries) == 0 {
+		return
+	}
+
+	lineHeight := r.lineHeight()
+	maxChars := (r.width/2 - 2*PAD*r.uiScale) / max(r.charWidth(), 1)
+
+	lines := make([]string, len(entries))
+	longest := 0
+	for i, e := range entries {
+		line := e.Time.Format("15:04:05") + " " + e.Text
+		if len(line) > maxChars {
+			line = line[:max(maxChars-1, 0)] + "~"
+		}
+		lines[i] = line
+		longest = max(longest, len(line))
+	}
+
+	w := longest*r.charWidth() + 2*PAD*r.uiScale
+	h := len(lines)*lineHeight + 2*PAD*r.uiScale
+	x := PAD * r.uiScale
+	y := r.height - 40*r.uiScale - h
+
+	r.drawRect(int32(x), int32(y), int32(w), int32(h), ColorLabelBg)
+	r.drawRectOutline(int32(x), int32(y), int32(w), int32(h), ColorLabelLine)
+
+	textY := y + PAD*r.uiScale
+	for _, line := range lines {
+		r.drawText(line, x+PAD*r.uiScale, textY, r.regularFont, ColorText)
+		textY += lineHeight
+	}
+}
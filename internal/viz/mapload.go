@@ -0,0 +1,76 @@
+package viz
+
+import (
+	"github.com/OJPARKINSON/viz1090/internal/config"
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
+	"github.com/OJPARKINSON/viz1090/internal/map_system"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// mapData is a vector map loaded in the background with its layer colors
+type mapData struct {
+	m      *map_system.Map
+	colors []sdl.Color // Line color of each loaded layer
+}
+
+// startMapLoad loads the configured map in a goroutine so the window stays
+// responsive. Frames are drawn without a map until pollMapLoad swaps it in.
+func (r *Renderer) startMapLoad() {
+	done := make(chan mapData, 1)
+	r.mapLoad = done
+
+	layers := r.config.MapLayers
+	maxDepth, capacity := r.config.MapMaxDepth, r.config.MapNodeCapacity
+	go func() {
+		done <- loadMapData(layers, maxDepth, capacity)
+	}()
+}
+
+// pollMapLoad installs the map once the background load has finished
+func (r *Renderer) pollMapLoad() {
+	if r.mapLoad == nil {
+		return
+	}
+
+	select {
+	case data := <-r.mapLoad:
+		r.mapSystem, r.layerColors = data.m, data.colors
+		r.mapLoad = nil
+		r.mapDrawn = false
+	default:
+	}
+}
+
+// loadMapData loads map layers in drawing order, skipping any that fail to
+// load, followed by the place and airport labels
+func loadMapData(layers []config.MapLayer, maxDepth, capacity int) mapData {
+	data := mapData{m: map_system.NewMap()}
+	data.m.MaxDepth, data.m.NodeCapacity = maxDepth, capacity
+	for _, layer := range layers {
+		if err := data.m.LoadLayer(layer.Name, layer.File); err != nil {
+			eventlog.Printf("Warning: %v\n", err)
+			continue
+		}
+
+		color, err := parseHexColor(layer.Color)
+		if err != nil {
+			eventlog.Printf("Warning: Map layer %s: %v, using the default color\n", layer.Name, err)
+			color = ColorMap
+		}
+		data.colors = append(data.colors, color)
+	}
+
+	data.m.LoadLabels("mapnames", "airportnames")
+	return data
+}
+
+// drawLoading draws the banner shown while the map is loading
+func (r *Renderer) drawLoading() {
+	text := "Loading map..."
+	w := len(text) * r.charWidth()
+	h := r.lineHeight()
+	x, y := (r.width-w)/2, (r.height-h)/2
+	r.drawRect(int32(x-PAD*r.uiScale), int32(y-PAD*r.uiScale), int32(w+2*PAD*r.uiScale), int32(h+2*PAD*r.uiScale), ColorButtonBg)
+	r.drawRectOutline(int32(x-PAD*r.uiScale), int32(y-PAD*r.uiScale), int32(w+2*PAD*r.uiScale), int32(h+2*PAD*r.uiScale), ColorLabelLine)
+	r.drawText(text, x, y, r.boldFont, ColorLabel)
+}
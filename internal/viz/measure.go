@@ -0,0 +1,51 @@
+package viz
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+	"github.com/OJPARKINSON/viz1090/internal/map_system"
+)
+
+// SetMeasurement sets the points of the measuring tool, none to draw nothing
+func (r *Renderer) SetMeasurement(points []map_system.Point) {
+	r.measure = append(r.measure[:0], points...)
+}
+
+// measureText formats a great circle distance and initial bearing between
+// two points, in km when metric
+func measureText(from, to map_system.Point, metric bool) string {
+	dist := adsb.DistanceNM(from.Lat, from.Lon, to.Lat, to.Lon)
+	bearing := adsb.BearingDeg(from.Lat, from.Lon, to.Lat, to.Lon)
+	if metric {
+		return fmt.Sprintf("%.1fkm %03.0f°", dist*1.852, bearing)
+	}
+	return fmt.Sprintf("%.1fnm %03.0f°", dist, bearing)
+}
+
+// drawMeasurement draws the measuring tool's points, and once both are set
+// the line between them with its distance and bearing
+func (r *Renderer) drawMeasurement(centerLat, centerLon, maxDistance float64) {
+	if len(r.measure) == 0 {
+		return
+	}
+
+	var xs, ys [2]int
+	for i, p := range r.measure {
+		xs[i], ys[i] = r.latLonToScreen(p.Lat, p.Lon, centerLat, centerLon, maxDistance)
+		r.drawCircle(xs[i], ys[i], 3*r.uiScale, ColorSelected)
+	}
+	if len(r.measure) < 2 {
+		return
+	}
+
+	r.renderer.SetDrawColor(ColorSelected.R, ColorSelected.G, ColorSelected.B, ColorSelected.A)
+	r.renderer.DrawLine(int32(xs[0]), int32(ys[0]), int32(xs[1]), int32(ys[1]))
+
+	text := measureText(r.measure[0], r.measure[1], r.metric)
+	x := (xs[0]+xs[1])/2 + PAD*r.uiScale
+	y := (ys[0]+ys[1])/2 - r.lineHeight()
+	r.drawRect(int32(x), int32(y), int32((utf8.RuneCountInString(text)+1)*r.charWidth()), int32(r.lineHeight()), ColorLabelBg)
+	r.drawText(text, x+r.charWidth()/2, y, r.regularFont, ColorSelected)
+}
@@ -0,0 +1,19 @@
+package viz
+
+import (
+	"testing"
+
+	"github.com/OJPARKINSON/viz1090/internal/map_system"
+)
+
+func TestMeasureText(t *testing.T) {
+	from := map_system.Point{Lat: 51.0, Lon: 0.0}
+	to := map_system.Point{Lat: 52.0, Lon: 0.0}
+
+	if got := measureText(from, to, false); got != "60.0nm 000°" {
+		t.Errorf("measureText = %q, want 60.0nm 000°", got)
+	}
+	if got := measureText(to, from, true); got != "111.2km 180°" {
+		t.Errorf("metric measureText = %q, want 111.2km 180°", got)
+	}
+}
@@ -0,0 +1,33 @@
+package viz
+
+import (
+	"math"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Symbol brightness by message rate: aircraft at fullBrightnessRate messages
+// per second or more are drawn at full brightness, silent ones at
+// minRateBrightness
+const (
+	fullBrightnessRate = 4.0
+	minRateBrightness  = 0.4
+)
+
+// rateBrightness maps a message rate to a brightness factor. The scale is
+// logarithmic so the difference between 0.5 and 1 message a second shows as
+// much as between 2 and 4.
+func rateBrightness(rate float64) float64 {
+	t := math.Log2(1+math.Max(0, rate)) / math.Log2(1+fullBrightnessRate)
+	return minRateBrightness + (1-minRateBrightness)*math.Min(1, t)
+}
+
+// scaleBrightness scales a color's value by f, keeping its hue and alpha
+func scaleBrightness(c sdl.Color, f float64) sdl.Color {
+	return sdl.Color{
+		R: uint8(math.Round(float64(c.R) * f)),
+		G: uint8(math.Round(float64(c.G) * f)),
+		B: uint8(math.Round(float64(c.B) * f)),
+		A: c.A,
+	}
+}
@@ -0,0 +1,36 @@
+package viz
+
+import (
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func TestRateBrightness(t *testing.T) {
+	if got := rateBrightness(0); got != minRateBrightness {
+		t.Errorf("silent aircraft brightness %.2f, want %.2f", got, minRateBrightness)
+	}
+	if got := rateBrightness(fullBrightnessRate); got != 1 {
+		t.Errorf("brightness at %v msg/s = %.2f, want 1", fullBrightnessRate, got)
+	}
+	if got := rateBrightness(50); got != 1 {
+		t.Errorf("brightness at 50 msg/s = %.2f, want capped at 1", got)
+	}
+
+	prev := rateBrightness(0)
+	for _, rate := range []float64{0.2, 0.5, 1, 2, 3} {
+		got := rateBrightness(rate)
+		if got <= prev {
+			t.Errorf("brightness at %v msg/s = %.3f, not above %.3f", rate, got, prev)
+		}
+		prev = got
+	}
+}
+
+func TestScaleBrightnessKeepsHue(t *testing.T) {
+	c := sdl.Color{R: 200, G: 100, B: 50, A: 255}
+	got := scaleBrightness(c, 0.5)
+	if got != (sdl.Color{R: 100, G: 50, B: 25, A: 255}) {
+		t.Errorf("half brightness = %v, want 100,50,25 with alpha kept", got)
+	}
+}
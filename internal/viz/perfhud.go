@@ -0,0 +1,147 @@
+package viz
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Frame phases timed for the performance HUD
+const (
+	phaseDecode  = iota // Message decoding in the receiver since the last frame
+	phaseLabels         // Label placement
+	phaseMap            // Map redraw and copy
+	phaseDraw           // Everything else drawn over the map
+	phasePresent        // Presenting the frame, including any vsync wait
+	numPhases
+)
+
+// phaseNames label the phases in the HUD legend
+var phaseNames = [numPhases]string{"decode", "labels", "map", "draw", "present"}
+
+// phaseColors stack the phases in the HUD graph
+var phaseColors = [numPhases]sdl.Color{ColorEmergency, ColorPlane, ColorReceiver, ColorTrail, ColorSubLabel}
+
+// perfSamples is how many frames the HUD graph covers
+const perfSamples = 120
+
+// perfHistory is a ring of the per-phase times of recent frames
+type perfHistory struct {
+	frames [perfSamples][numPhases]time.Duration
+	next   int
+	count  int
+}
+
+// add records one frame
+func (p *perfHistory) add(phases [numPhases]time.Duration) {
+	p.frames[p.next] = phases
+	p.next = (p.next + 1) % perfSamples
+	if p.count < perfSamples {
+		p.count++
+	}
+}
+
+// recent returns the recorded frames oldest first
+func (p *perfHistory) recent() [][numPhases]time.Duration {
+	out := make([][numPhases]time.Duration, 0, p.count)
+	for i := 0; i < p.count; i++ {
+		out = append(out, p.frames[(p.next-p.count+i+perfSamples)%perfSamples])
+	}
+	return out
+}
+
+// average returns the mean time of each phase over the recorded frames
+func (p *perfHistory) average() [numPhases]time.Duration {
+	var sum [numPhases]time.Duration
+	if p.count == 0 {
+		return sum
+	}
+	for i := 0; i < p.count; i++ {
+		for phase, d := range p.frames[i] {
+			sum[phase] += d
+		}
+	}
+	for phase := range sum {
+		sum[phase] /= time.Duration(p.count)
+	}
+	return sum
+}
+
+// startPhases starts timing a frame
+func (r *Renderer) startPhases() {
+	r.phases = [numPhases]time.Duration{phaseDecode: r.stats.DecodeTime}
+	r.phaseStart = time.Now()
+}
+
+// endPhase adds the time since the last phase ended to a phase
+func (r *Renderer) endPhase(phase int) {
+	now := time.Now()
+	r.phases[phase] += now.Sub(r.phaseStart)
+	r.phaseStart = now
+}
+
+// drawPerfHUD draws a graph of recent frame times stacked by phase, with a
+// line per frame budget, and a legend of the average time of each phase
+func (r *Renderer) drawPerfHUD() {
+	frames := r.perf.recent()
+	if len(frames) == 0 {
+		return
+	}
+
+	// The graph spans two frame budgets, or two 60Hz frames uncapped
+	budget := time.Second / 60
+	if r.config.MaxFPS > 0 {
+		budget = time.Second / time.Duration(r.config.MaxFPS)
+	}
+	scale := float64(40*r.uiScale) / float64(2*budget)
+
+	barWidth := r.uiScale
+	graphH := 40 * r.uiScale
+	x := PAD * r.uiScale
+	y := 30 * r.uiScale
+	lineHeight := r.lineHeight()
+	w := perfSamples*barWidth + 2*PAD*r.uiScale
+	h := graphH + (numPhases+1)*lineHeight + 3*PAD*r.uiScale
+
+	r.drawRect(int32(x), int32(y), int32(w), int32(h), ColorLabelBg)
+	r.drawRectOutline(int32(x), int32(y), int32(w), int32(h), ColorScaleBar)
+
+	graphX, graphBottom := x+PAD*r.uiScale, y+PAD*r.uiScale+graphH
+	for i, phases := range frames {
+		bottom := graphBottom
+		for phase, d := range phases {
+			bh := int(float64(d) * scale)
+			if bottom-bh < graphBottom-graphH {
+				bh = bottom - (graphBottom - graphH) // Clip frames over two budgets
+			}
+			if bh <= 0 {
+				continue
+			}
+			r.drawRect(int32(graphX+i*barWidth), int32(bottom-bh), int32(barWidth), int32(bh), phaseColors[phase])
+			bottom -= bh
+		}
+	}
+
+	// Frame budget
+	budgetY := int32(graphBottom - int(float64(budget)*scale))
+	r.renderer.SetDrawColor(ColorScaleBar.R, ColorScaleBar.G, ColorScaleBar.B, ColorScaleBar.A)
+	r.renderer.DrawLine(int32(graphX), budgetY, int32(graphX+perfSamples*barWidth), budgetY)
+
+	// Legend of average phase times
+	avg := r.perf.average()
+	var total time.Duration
+	textY := graphBottom + PAD*r.uiScale
+	for phase, d := range avg {
+		total += d
+		r.drawRect(int32(graphX), int32(textY+lineHeight/4), int32(lineHeight/2), int32(lineHeight/2), phaseColors[phase])
+		r.drawText(fmt.Sprintf("%-8s%6.2fms", phaseNames[phase], msec(d)), graphX+lineHeight, textY, r.regularFont, ColorText)
+		textY += lineHeight
+	}
+	r.drawText(fmt.Sprintf("%-8s%6.2fms", "total", msec(total)), graphX+lineHeight, textY, r.regularFont, ColorLabel)
+}
+
+// msec returns a duration in fractional milliseconds
+func msec(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,30 @@
+package viz
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPerfHistory(t *testing.T) {
+	var p perfHistory
+	if got := p.average(); got != ([numPhases]time.Duration{}) {
+		t.Errorf("empty average = %v", got)
+	}
+
+	for i := 1; i <= perfSamples+2; i++ {
+		p.add([numPhases]time.Duration{phaseMap: time.Duration(i) * time.Millisecond})
+	}
+
+	frames := p.recent()
+	if len(frames) != perfSamples {
+		t.Fatalf("%d frames kept, want %d", len(frames), perfSamples)
+	}
+	if frames[0][phaseMap] != 3*time.Millisecond || frames[perfSamples-1][phaseMap] != (perfSamples+2)*time.Millisecond {
+		t.Errorf("frames run %v..%v, want the latest oldest first", frames[0][phaseMap], frames[perfSamples-1][phaseMap])
+	}
+
+	// Mean of 3..122 ms
+	if got := p.average()[phaseMap]; got != 62500*time.Microsecond {
+		t.Errorf("average map time = %v, want 62.5ms", got)
+	}
+}
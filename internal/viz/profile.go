@@ -0,0 +1,84 @@
+package viz
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+// drawAltitudeProfile plots the selected aircraft's trail altitude against time
+// over the last ProfileSeconds, in a panel in the bottom right corner
+func (r *Renderer) drawAltitudeProfile(a *adsb.Aircraft) {
+	if r.config.ProfileSeconds <= 0 {
+		return
+	}
+
+	w := 200 * r.uiScale
+	h := 100 * r.uiScale
+	x := r.width - w - PAD*r.uiScale
+	y := r.height - h - 30*r.uiScale
+
+	// Panel background and outline
+	r.drawRect(int32(x), int32(y), int32(w), int32(h), ColorLabelBg)
+	r.drawRectOutline(int32(x), int32(y), int32(w), int32(h), ColorLabelLine)
+
+	window := time.Duration(r.config.ProfileSeconds) * time.Second
+	start := r.clock.Add(-window)
+
+	// Collect the trail points inside the time window and find the altitude range
+	var points []adsb.Position
+	minAlt, maxAlt := 0, 0
+	for _, p := range a.Trail {
+		if p.Timestamp.Before(start) {
+			continue
+		}
+		if len(points) == 0 || p.Altitude < minAlt {
+			minAlt = p.Altitude
+		}
+		if len(points) == 0 || p.Altitude > maxAlt {
+			maxAlt = p.Altitude
+		}
+		points = append(points, p)
+	}
+
+	if len(points) < 2 {
+		r.drawText("no altitude data", x+PAD*r.uiScale, y+PAD*r.uiScale, r.regularFont, ColorSubLabel)
+		return
+	}
+
+	lo, hi := profileRange(minAlt, maxAlt)
+
+	// Scale labels
+	r.drawText(fmt.Sprintf("%d'", hi), x+PAD*r.uiScale, y+PAD*r.uiScale, r.regularFont, ColorSubLabel)
+	r.drawText(fmt.Sprintf("%d'", lo), x+PAD*r.uiScale, y+h-PAD*r.uiScale-r.lineHeight(), r.regularFont, ColorSubLabel)
+
+	// Plot the profile
+	toScreen := func(p adsb.Position) (int32, int32) {
+		px := x + int(float64(w)*p.Timestamp.Sub(start).Seconds()/window.Seconds())
+		py := y + h - int(float64(h)*float64(p.Altitude-lo)/float64(hi-lo))
+		return int32(px), int32(py)
+	}
+
+	r.renderer.SetDrawColor(ColorProfile.R, ColorProfile.G, ColorProfile.B, ColorProfile.A)
+	prevX, prevY := toScreen(points[0])
+	for _, p := range points[1:] {
+		px, py := toScreen(p)
+		r.renderer.DrawLine(prevX, prevY, px, py)
+		prevX, prevY = px, py
+	}
+}
+
+// profileRange widens an altitude range to whole 500 ft steps so the plot has
+// some headroom and a level aircraft is drawn mid-panel
+func profileRange(minAlt, maxAlt int) (int, int) {
+	const step = 500
+
+	lo := (minAlt/step - 1) * step
+	hi := (maxAlt/step + 1) * step
+	if minAlt < 0 && minAlt%step != 0 {
+		lo -= step
+	}
+
+	return lo, hi
+}
@@ -3,10 +3,16 @@ package viz
 import (
 	"fmt"
 	"math"
+	"os"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/OJPARKINSON/viz1090/internal/adsb"
+	"github.com/OJPARKINSON/viz1090/internal/config"
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
 	"github.com/OJPARKINSON/viz1090/internal/map_system"
+	"github.com/OJPARKINSON/viz1090/internal/watchlist"
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/veandco/go-sdl2/ttf"
 )
@@ -16,6 +22,19 @@ const (
 	LATLONMULT   = 111.195 // 6371.0 * math.Pi / 180.0 - conversion factor for lat/lon to km
 	PAD          = 5       // Padding for UI elements
 	ROUND_RADIUS = 3       // Radius of rounded corners
+
+	MinUIScale = 1 // Smallest runtime UI scale
+	MaxUIScale = 4 // Largest runtime UI scale
+
+	MinFontSize = 6  // Smallest accepted configured font size
+	MaxFontSize = 72 // Largest accepted configured font size
+
+	MinSymbolScale = 0.25 // Smallest aircraft symbol scale
+	MaxSymbolScale = 4.0  // Largest aircraft symbol scale
+	MaxTrailWidth  = 10   // Widest trail line in pixels
+
+	regularFontPath = "font/TerminusTTF-4.46.0.ttf"
+	boldFontPath    = "font/TerminusTTF-Bold-4.46.0.ttf"
 )
 
 // Color definitions
@@ -23,6 +42,8 @@ var (
 	ColorBackground = sdl.Color{R: 0, G: 0, B: 0, A: 255}
 	ColorPlane      = sdl.Color{R: 253, G: 250, B: 31, A: 255}
 	ColorPlaneGone  = sdl.Color{R: 127, G: 127, B: 127, A: 255}
+	ColorAnonymous  = sdl.Color{R: 120, G: 200, B: 255, A: 255}
+	ColorEmergency  = sdl.Color{R: 255, G: 0, B: 0, A: 255}
 	ColorSelected   = sdl.Color{R: 249, G: 38, B: 114, A: 255}
 	ColorTrail      = sdl.Color{R: 90, G: 133, B: 50, A: 255}
 	ColorLabel      = sdl.Color{R: 255, G: 255, B: 255, A: 255}
@@ -31,12 +52,31 @@ var (
 	ColorLabelLine  = sdl.Color{R: 64, G: 64, B: 64, A: 255}
 	ColorLabelBg    = sdl.Color{R: 0, G: 0, B: 0, A: 200}
 	ColorMap        = sdl.Color{R: 33, G: 0, B: 122, A: 255}
-	ColorAirport    = sdl.Color{R: 85, G: 0, B: 255, A: 255}
 	ColorText       = sdl.Color{R: 196, G: 196, B: 196, A: 255}
 	ColorButton     = sdl.Color{R: 196, G: 196, B: 196, A: 255}
 	ColorButtonBg   = sdl.Color{R: 0, G: 0, B: 0, A: 255}
+	ColorGraticule  = sdl.Color{R: 64, G: 64, B: 64, A: 255}
+	ColorReceiver   = sdl.Color{R: 0, G: 200, B: 255, A: 255}
+	ColorAccuracy   = sdl.Color{R: 90, G: 90, B: 40, A: 255}
+	ColorProfile    = sdl.Color{R: 90, G: 200, B: 255, A: 255}
+	ColorWind       = sdl.Color{R: 150, G: 150, B: 220, A: 255}
+	ColorConflict   = sdl.Color{R: 255, G: 60, B: 40, A: 255}
+	ColorWatch      = sdl.Color{R: 255, G: 0, B: 255, A: 255}
 )
 
+// Stats holds receiver statistics from the app shown in the status bar
+type Stats struct {
+	ShortFrames int           // Frames dropped for having the wrong length
+	FPS         float64       // Average frames per second
+	FrameTime   time.Duration // Average time spent producing a frame
+	Paused      bool          // Whether the display is frozen
+	Buffered    int           // Messages held while paused
+	NoData      bool          // No messages received within the data timeout
+	Measuring   bool          // Clicks place measuring tool points
+	ViewMode    string        // What the view is centered on
+	DecodeTime  time.Duration // Time spent decoding messages since the last frame
+}
+
 // LabelSystem manages aircraft labels and prevents overlaps
 type LabelSystem struct {
 	width     int
@@ -44,15 +84,26 @@ type LabelSystem struct {
 	uiScale   int
 	metric    bool
 	labelFont *ttf.Font
+	fullNM    float64 // Labels beyond this distance from center drop to callsign only, 0 to disable
+	hideNM    float64 // Labels beyond this distance from center are hidden, 0 to disable
+
+	placement   labelDirection // Side new labels start on, or labelAuto for the least crowded
+	labelOffset int            // Distance of new labels from the symbol in pixels at UI scale 1
+
+	// Overlap solver buffers reused between passes
+	visible []*adsb.Aircraft
+	grid    labelGrid
 }
 
 // NewLabelSystem creates a new label system
 func NewLabelSystem(width, height, uiScale int, metric bool) *LabelSystem {
 	return &LabelSystem{
-		width:   width,
-		height:  height,
-		uiScale: uiScale,
-		metric:  metric,
+		width:       width,
+		height:      height,
+		uiScale:     uiScale,
+		metric:      metric,
+		placement:   labelAuto,
+		labelOffset: 20,
 	}
 }
 
@@ -61,31 +112,112 @@ func (ls *LabelSystem) SetFont(font *ttf.Font) {
 	ls.labelFont = font
 }
 
+// SetPlacement sets where new labels start: the side of the symbol named by
+// placement, see parseLabelPlacement, at offset pixels at UI scale 1. An
+// unknown placement leaves automatic placement and returns an error.
+func (ls *LabelSystem) SetPlacement(placement string, offset int) error {
+	ls.labelOffset = offset
+	dir, err := parseLabelPlacement(placement)
+	ls.placement = dir
+	return err
+}
+
+// SetDetailRange sets the distances from the view center, in NM, beyond which
+// labels are reduced to the callsign and hidden altogether
+func (ls *LabelSystem) SetDetailRange(fullNM, hideNM float64) {
+	ls.fullNM = fullNM
+	ls.hideNM = hideNM
+}
+
 // UpdateLabels updates all aircraft labels to avoid overlaps
-func (ls *LabelSystem) UpdateLabels(aircraft map[uint32]*adsb.Aircraft) {
-	// Resolve label conflicts - simplified version for now
-	for i := 0; i < 4; i++ { // Iterate a few times for better results
+func (ls *LabelSystem) UpdateLabels(aircraft map[uint32]*adsb.Aircraft, selectedICAO uint32, maxDistance float64) {
+	ls.updateDetail(aircraft, selectedICAO, maxDistance)
+	ls.placeNewLabels(aircraft)
+
+	// Each pass pushes every label away from those in its own and
+	// neighboring grid cells, so the cost grows with crowding. Busy scenes
+	// get fewer passes per frame and settle over several frames instead
+	n := 0
+	for _, a := range aircraft {
+		if a.LabelW > 0 && a.LabelH > 0 && !labelHidden(a) {
+			n++
+		}
+	}
+	for i := labelIterations(n); i > 0; i-- {
 		ls.resolveOverlaps(aircraft)
 	}
 }
 
+// labelIterations returns how many overlap solver passes to run per frame
+// for n visible labels
+func labelIterations(n int) int {
+	switch {
+	case n <= 30:
+		return 6
+	case n <= 100:
+		return 4
+	case n <= 300:
+		return 2
+	}
+	return 1
+}
+
+// distanceLevel returns the label level for an aircraft distNM from the view
+// center: 0 for a full label, 1 for the callsign only and 2 for no label
+func (ls *LabelSystem) distanceLevel(distNM float64) float64 {
+	switch {
+	case ls.hideNM > 0 && distNM > ls.hideNM:
+		return 2
+	case ls.fullNM > 0 && distNM > ls.fullNM:
+		return 1
+	}
+	return 0
+}
+
+// updateDetail eases each label's level towards the one for its distance
+// from the view center. The selected aircraft always gets a full label.
+func (ls *LabelSystem) updateDetail(aircraft map[uint32]*adsb.Aircraft, selectedICAO uint32, maxDistance float64) {
+	nmPerPixel := maxDistance / float64(ls.height/2)
+	for icao, a := range aircraft {
+		dx := float64(a.X - ls.width/2)
+		dy := float64(a.Y - ls.height/2)
+		target := ls.distanceLevel(math.Sqrt(dx*dx+dy*dy) * nmPerPixel)
+		if icao == selectedICAO {
+			target = 0
+		}
+
+		// Ease the level so labels don't flicker at the thresholds
+		level := a.LabelLevel + 0.2*(target-a.LabelLevel)
+		if math.Abs(target-level) < 0.05 {
+			level = target
+		}
+		a.LabelLevel = level
+	}
+}
+
+// labelHidden reports whether an aircraft's label is not drawn
+func labelHidden(a *adsb.Aircraft) bool {
+	return a.LabelLevel >= 1.5 || a.Stacked
+}
+
 // resolveOverlaps detects and resolves label overlaps
 func (ls *LabelSystem) resolveOverlaps(aircraft map[uint32]*adsb.Aircraft) {
 	// Algorithm to prevent label overlaps
 	// This is a simplified implementation
 
-	// Clear forces
+	// Clear forces and collect the labels being drawn
+	ls.visible = ls.visible[:0]
 	for _, a := range aircraft {
 		a.LabelDX = 0
 		a.LabelDY = 0
+		if a.LabelW != 0 && a.LabelH != 0 && !labelHidden(a) {
+			ls.visible = append(ls.visible, a)
+		}
 	}
+	ls.grid.reset(ls.visible)
 
 	// Calculate forces based on overlaps
-	for _, a1 := range aircraft {
-		if a1.LabelW == 0 || a1.LabelH == 0 {
-			continue
-		}
-
+	for _, a1 := range ls.visible {
 		// Apply screen edge forces
 		edge := float64(15 * ls.uiScale)
 		if a1.LabelX < edge {
@@ -117,29 +249,12 @@ func (ls *LabelSystem) resolveOverlaps(aircraft map[uint32]*adsb.Aircraft) {
 			}
 		}
 
-		// Forces from other labels
-		for _, a2 := range aircraft {
-			if a1 == a2 || a2.LabelW == 0 || a2.LabelH == 0 {
-				continue
-			}
-
-			dx := a1.LabelX + a1.LabelW/2 - (a2.LabelX + a2.LabelW/2)
-			dy := a1.LabelY + a1.LabelH/2 - (a2.LabelY + a2.LabelH/2)
-			dist := math.Sqrt(dx*dx + dy*dy)
-
-			if dist < 0.001 {
-				continue // Avoid division by zero
-			}
-
-			// Calculate repulsion
-			targetDist := (a1.LabelW + a2.LabelW + a1.LabelH + a2.LabelH) / 4
-
-			if dist < targetDist {
-				force := 0.001 * (targetDist - dist)
-				a1.LabelDX += force * dx / dist
-				a1.LabelDY += force * dy / dist
-			}
-		}
+		// Forces from nearby labels
+		ls.grid.neighbors(a1, func(a2 *adsb.Aircraft) {
+			fx, fy := labelRepulsion(a1, a2)
+			a1.LabelDX += fx
+			a1.LabelDY += fy
+		})
 	}
 
 	// Apply forces with damping
@@ -169,20 +284,43 @@ func (ls *LabelSystem) resolveOverlaps(aircraft map[uint32]*adsb.Aircraft) {
 
 // Renderer handles drawing the radar display
 type Renderer struct {
-	window      *sdl.Window
-	renderer    *sdl.Renderer
-	regularFont *ttf.Font
-	boldFont    *ttf.Font
-	labelFont   *ttf.Font
-	mapTexture  *sdl.Texture
-	width       int
-	height      int
-	uiScale     int
-	metric      bool
-	lastRedraw  time.Time
-	mapDrawn    bool
-	mapSystem   *map_system.Map
-	labelSystem *LabelSystem
+	config         *config.Config
+	window         *sdl.Window
+	renderer       *sdl.Renderer
+	regularFont    *ttf.Font
+	boldFont       *ttf.Font
+	labelFont      *ttf.Font
+	mapTexture     *sdl.Texture
+	width          int
+	height         int
+	uiScale        int
+	metric         bool
+	fontPath       string
+	fontSize       int
+	stats          Stats
+	lastRedraw     time.Time
+	clock          time.Time // Scene time for age based effects, held while paused
+	lastAirportTag time.Time
+	flashStart     time.Time // When the last alert flash began
+	mapDrawn       bool
+	mapSystem      *map_system.Map // Nil until the background load completes
+	mapLoad        chan mapData    // Receives the map from the background load, nil once installed
+	labelSystem    *LabelSystem
+	baseImage      *baseImage // Raster map drawn beneath the vector map, nil if none
+	altitudeStops  []colorStop
+	watchlist      *watchlist.List[watchStyle]
+	measure        []map_system.Point // Measuring tool points, at most two
+
+	// Visible line buffers reused between map redraws
+	layerLineBuf [][]*map_system.Line
+	layerColors  []sdl.Color // Line color of each loaded map layer
+	projection   map_system.ProjectionKind
+	lines        lineBatch // Pending anti-aliased line pixels, reused between draws
+
+	// Performance HUD timing
+	perf       perfHistory
+	phases     [numPhases]time.Duration // Phase times of the frame being drawn
+	phaseStart time.Time
 
 	// Mouse and interaction
 	mouseMoved bool
@@ -194,9 +332,12 @@ type Renderer struct {
 }
 
 // NewRenderer creates a new visualization renderer
-func NewRenderer(width, height, uiScale int, metric bool) (*Renderer, error) {
+func NewRenderer(cfg *config.Config) (*Renderer, error) {
 	var err error
+	width, height := cfg.ScreenWidth, cfg.ScreenHeight
+	uiScale, metric := cfg.UIScale, cfg.Metric
 	r := &Renderer{
+		config:   cfg,
 		width:    width,
 		height:   height,
 		uiScale:  uiScale,
@@ -215,15 +356,19 @@ func NewRenderer(width, height, uiScale int, metric bool) (*Renderer, error) {
 		return nil, fmt.Errorf("failed to initialize TTF: %v", err)
 	}
 
-	// Get display size if needed
-	if width == 0 || height == 0 {
-		displayCount, err := sdl.GetNumVideoDisplays()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get display count: %v", err)
-		}
+	displayCount, err := sdl.GetNumVideoDisplays()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get display count: %v", err)
+	}
+	display := displayIndex(cfg.Display, displayCount)
+	if display != cfg.Display {
+		eventlog.Printf("Warning: display %d not found (%d connected), using display 0\n", cfg.Display, displayCount)
+	}
 
+	// Size to the chosen display when fullscreen or auto-detecting
+	if width == 0 || height == 0 || cfg.Fullscreen {
 		for i := 0; i < displayCount; i++ {
-			bounds, err := sdl.GetDisplayBounds(i)
+			bounds, err := sdl.GetDisplayBounds((display + i) % displayCount)
 			if err != nil {
 				continue
 			}
@@ -233,20 +378,36 @@ func NewRenderer(width, height, uiScale int, metric bool) (*Renderer, error) {
 		}
 	}
 
-	// Create window
-	r.window, err = sdl.CreateWindow("viz1090-go", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED,
-		int32(width), int32(height), sdl.WINDOW_SHOWN)
+	// Create window centered on the chosen display
+	var windowFlags uint32 = sdl.WINDOW_SHOWN
+	if cfg.Fullscreen {
+		windowFlags |= sdl.WINDOW_FULLSCREEN_DESKTOP
+	}
+	pos := int32(sdl.WINDOWPOS_CENTERED_MASK | display)
+	r.window, err = sdl.CreateWindow(cfg.Title, pos, pos,
+		int32(width), int32(height), windowFlags)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create window: %v", err)
 	}
 
 	// Create renderer
-	r.renderer, err = sdl.CreateRenderer(r.window, -1, sdl.RENDERER_ACCELERATED)
+	var rendererFlags uint32 = sdl.RENDERER_ACCELERATED
+	if cfg.MaxFPS <= 0 {
+		rendererFlags |= sdl.RENDERER_PRESENTVSYNC
+	}
+	r.renderer, err = sdl.CreateRenderer(r.window, -1, rendererFlags)
 	if err != nil {
 		r.window.Destroy()
 		return nil, fmt.Errorf("failed to create renderer: %v", err)
 	}
 
+	// Use the real output size, which differs from the request when the size
+	// was auto-detected or the window is fullscreen
+	if w, h, err := r.renderer.GetOutputSize(); err == nil {
+		width, height = int(w), int(h)
+	}
+	r.width, r.height = width, height
+
 	// Create map texture
 	r.mapTexture, err = r.renderer.CreateTexture(
 		sdl.PIXELFORMAT_RGBA8888,
@@ -258,51 +419,265 @@ func NewRenderer(width, height, uiScale int, metric bool) (*Renderer, error) {
 		return nil, fmt.Errorf("failed to create map texture: %v", err)
 	}
 
-	// Load fonts
-	r.regularFont, err = ttf.OpenFont("font/TerminusTTF-4.46.0.ttf", 12*uiScale)
+	// Initialize the label system
+	r.labelSystem = NewLabelSystem(width, height, uiScale, metric)
+	r.labelSystem.SetDetailRange(cfg.LabelFullNM, cfg.LabelHideNM)
+	if err := r.labelSystem.SetPlacement(cfg.LabelPlacement, cfg.LabelOffset); err != nil {
+		eventlog.Printf("Warning: %v, placing labels automatically\n", err)
+	}
+
+	// Altitude color gradient
+	r.altitudeStops = defaultAltitudeStops
+	if cfg.AltitudeColors != "" {
+		if stops, err := parseAltitudeStops(cfg.AltitudeColors); err != nil {
+			eventlog.Printf("Warning: %v, using the default altitude colors\n", err)
+		} else {
+			r.altitudeStops = stops
+		}
+	}
+
+	r.watchlist = newWatchlist(cfg.Watchlist)
+
+	r.projection, err = map_system.ParseProjection(cfg.Projection)
 	if err != nil {
+		eventlog.Printf("Warning: %v, using equirectangular\n", err)
+	}
+
+	// Load fonts
+	r.validateFontConfig()
+	r.validateFadeConfig()
+	if err = r.loadFonts(uiScale); err != nil {
 		r.mapTexture.Destroy()
 		r.renderer.Destroy()
 		r.window.Destroy()
-		return nil, fmt.Errorf("failed to load regular font: %v", err)
+		return nil, err
+	}
+
+	// Load the map without holding up the first frames
+	r.startMapLoad()
+
+	// Load the raster base map
+	if cfg.BaseImage != "" {
+		if r.baseImage, err = r.loadBaseImage(cfg.BaseImage); err != nil {
+			eventlog.Printf("Warning: Failed to load base image: %v\n", err)
+		}
+	}
+
+	return r, nil
+}
+
+// validateFadeConfig warns when the stale fade would outlast the removal TTL,
+// in which case it is shortened to fit
+func (r *Renderer) validateFadeConfig() {
+	fadeEnd := r.config.FadeStartSeconds + r.config.FadeDurationSeconds
+	if ttl := min(r.config.TTLFor(false), r.config.TTLFor(true)); ttl > 0 && fadeEnd > ttl {
+		eventlog.Printf("Warning: Fade ends at %ds but aircraft are removed after %ds, shortening the fade\n",
+			fadeEnd, ttl)
+	}
+}
+
+// validateFontConfig checks the configured font file and size, falling back
+// to the bundled Terminus font and UI-scaled size if either is unusable
+func (r *Renderer) validateFontConfig() {
+	if r.config.FontPath != "" {
+		if _, err := os.Stat(r.config.FontPath); err != nil {
+			eventlog.Printf("Warning: Font file not usable, using default font: %v\n", err)
+		} else {
+			r.fontPath = r.config.FontPath
+		}
+	}
+
+	if r.config.FontSize != 0 && (r.config.FontSize < MinFontSize || r.config.FontSize > MaxFontSize) {
+		eventlog.Printf("Warning: Font size %d outside %d-%d, scaling with UI instead\n",
+			r.config.FontSize, MinFontSize, MaxFontSize)
+	}
+}
+
+// fontSizeFor returns the font size to use at the given UI scale
+func (r *Renderer) fontSizeFor(uiScale int) int {
+	if r.config.FontSize >= MinFontSize && r.config.FontSize <= MaxFontSize {
+		return r.config.FontSize
 	}
+	return 12 * uiScale
+}
+
+// lineHeight returns the vertical spacing between lines of text
+func (r *Renderer) lineHeight() int {
+	return r.fontSize + r.fontSize/6
+}
+
+// charWidth returns the approximate width of one character of text
+func (r *Renderer) charWidth() int {
+	return r.fontSize / 2
+}
 
-	r.boldFont, err = ttf.OpenFont("font/TerminusTTF-Bold-4.46.0.ttf", 12*uiScale)
+// loadFonts opens the regular and bold fonts at the size for the given UI scale,
+// replacing any previously loaded fonts only once both have loaded successfully
+func (r *Renderer) loadFonts(uiScale int) error {
+	size := r.fontSizeFor(uiScale)
+	if size == r.fontSize && r.regularFont != nil {
+		return nil // Already loaded at this size
+	}
+
+	regularPath, boldPath := regularFontPath, boldFontPath
+	if r.fontPath != "" {
+		regularPath, boldPath = r.fontPath, r.fontPath
+	}
+
+	regularFont, err := ttf.OpenFont(regularPath, size)
 	if err != nil {
+		return fmt.Errorf("failed to load regular font: %v", err)
+	}
+
+	boldFont, err := ttf.OpenFont(boldPath, size)
+	if err != nil {
+		regularFont.Close()
+		return fmt.Errorf("failed to load bold font: %v", err)
+	}
+	if r.fontPath != "" {
+		boldFont.SetStyle(ttf.STYLE_BOLD)
+	}
+
+	if r.regularFont != nil {
 		r.regularFont.Close()
-		r.mapTexture.Destroy()
-		r.renderer.Destroy()
-		r.window.Destroy()
-		return nil, fmt.Errorf("failed to load bold font: %v", err)
+	}
+	if r.boldFont != nil {
+		r.boldFont.Close()
 	}
 
+	r.regularFont = regularFont
+	r.boldFont = boldFont
+	r.fontSize = size
 	r.labelFont = r.boldFont
-
-	// Initialize the label system
-	r.labelSystem = NewLabelSystem(width, height, uiScale, metric)
 	r.labelSystem.SetFont(r.labelFont)
 
-	// Initialize the map system
-	r.mapSystem = map_system.NewMap()
-	err = r.mapSystem.LoadMapData("mapdata.bin", "airportdata.bin", "mapnames", "airportnames")
+	return nil
+}
+
+// SetUIScale changes the UI scale at runtime, reloading fonts at the new size.
+// Fonts are only reloaded when the resulting font size actually changes.
+func (r *Renderer) SetUIScale(uiScale int) error {
+	if uiScale < MinUIScale {
+		uiScale = MinUIScale
+	} else if uiScale > MaxUIScale {
+		uiScale = MaxUIScale
+	}
+
+	if uiScale == r.uiScale {
+		return nil
+	}
+
+	if err := r.loadFonts(uiScale); err != nil {
+		return err
+	}
+
+	r.uiScale = uiScale
+	r.labelSystem.uiScale = uiScale
+	r.mapDrawn = false
+
+	return nil
+}
+
+// SetTitle changes the window title
+func (r *Renderer) SetTitle(title string) {
+	if title != r.window.GetTitle() {
+		r.window.SetTitle(title)
+	}
+}
+
+// displayIndex validates a configured display index against the number of
+// connected displays, falling back to the first display
+func displayIndex(display, count int) int {
+	if display < 0 || display >= count {
+		return 0
+	}
+	return display
+}
+
+// SetFullscreen switches between a window and borderless fullscreen at the
+// desktop resolution, resizing the map texture and label bounds to match
+func (r *Renderer) SetFullscreen(fullscreen bool) error {
+	var flags uint32
+	if fullscreen {
+		flags = sdl.WINDOW_FULLSCREEN_DESKTOP
+	}
+	if err := r.window.SetFullscreen(flags); err != nil {
+		return fmt.Errorf("failed to change fullscreen mode: %v", err)
+	}
+
+	return r.resize()
+}
+
+// resize updates the renderer to the window's current output size
+func (r *Renderer) resize() error {
+	w, h, err := r.renderer.GetOutputSize()
 	if err != nil {
-		fmt.Printf("Warning: Failed to load map data: %v\n", err)
+		return fmt.Errorf("failed to get output size: %v", err)
+	}
+	if int(w) == r.width && int(h) == r.height {
+		return nil
 	}
 
-	return r, nil
+	mapTexture, err := r.renderer.CreateTexture(
+		sdl.PIXELFORMAT_RGBA8888,
+		sdl.TEXTUREACCESS_TARGET,
+		w, h)
+	if err != nil {
+		return fmt.Errorf("failed to create map texture: %v", err)
+	}
+	r.mapTexture.Destroy()
+	r.mapTexture = mapTexture
+
+	r.width, r.height = int(w), int(h)
+	r.labelSystem.width, r.labelSystem.height = r.width, r.height
+	r.mapDrawn = false
+
+	return nil
+}
+
+// SetStats updates the receiver statistics shown in the status bar
+func (r *Renderer) SetStats(stats Stats) {
+	r.stats = stats
+}
+
+// GetUIScale returns the current UI scale
+func (r *Renderer) GetUIScale() int {
+	return r.uiScale
 }
 
 // RenderFrame draws a complete frame with all aircraft
 func (r *Renderer) RenderFrame(aircraft map[uint32]*adsb.Aircraft, centerLat, centerLon, maxDistance float64, selectedICAO uint32) {
+	r.startPhases()
+
+	if r.config.HideNoPosition {
+		aircraft = positionedAircraft(aircraft)
+	}
+
+	// Hold the scene clock while paused so fades and ghosts freeze too
+	if !r.stats.Paused || r.clock.IsZero() {
+		r.clock = time.Now()
+	}
+
+	r.pollMapLoad()
+
 	// Clear screen
 	r.renderer.SetDrawColor(ColorBackground.R, ColorBackground.G, ColorBackground.B, ColorBackground.A)
 	r.renderer.Clear()
 
 	// Calculate screen positions for all aircraft
 	r.calculateScreenPositions(aircraft, centerLat, centerLon, maxDistance)
+	markStacks(aircraft, selectedICAO, stackRadius*r.uiScale)
+
+	// Tag aircraft low and close to an airport
+	if time.Since(r.lastAirportTag) > time.Second {
+		r.updateAirportTags(aircraft)
+		r.lastAirportTag = time.Now()
+	}
 
 	// Update label positions to avoid overlaps
-	r.labelSystem.UpdateLabels(aircraft)
+	r.endPhase(phaseDraw)
+	r.labelSystem.UpdateLabels(aircraft, selectedICAO, maxDistance)
+	r.endPhase(phaseLabels)
 
 	// Draw map if needed
 	if !r.mapDrawn || time.Since(r.lastRedraw) > 2*time.Second {
@@ -311,27 +686,95 @@ func (r *Renderer) RenderFrame(aircraft map[uint32]*adsb.Aircraft, centerLat, ce
 
 	// Copy map from texture to screen
 	r.renderer.Copy(r.mapTexture, nil, nil)
+	r.endPhase(phaseMap)
+
+	// Draw lat/lon grid over the map
+	if r.config.ShowGraticule {
+		r.drawGraticule(centerLat, centerLon, maxDistance)
+	}
+
+	// Draw aircraft trails, or only the selected one's when trails are off
+	if r.config.ShowTrails {
+		r.drawTrails(aircraft, centerLat, centerLon, maxDistance)
+	} else if selected, ok := aircraft[selectedICAO]; ok && r.config.SelectedTrail {
+		r.drawTrails(map[uint32]*adsb.Aircraft{selectedICAO: selected}, centerLat, centerLon, maxDistance)
+	}
 
-	// Draw aircraft trails
-	r.drawTrails(aircraft, centerLat, centerLon, maxDistance)
+	// Draw receiver location
+	if r.config.ShowReceiver {
+		r.drawReceiver(centerLat, centerLon, maxDistance)
+	}
+
+	// Draw position accuracy of low quality targets
+	if r.config.ShowAccuracy {
+		r.drawAccuracy(aircraft, maxDistance)
+	}
+
+	// Draw wind reported by aircraft
+	if r.config.ShowWind {
+		r.drawWind(aircraft)
+	}
+
+	// Draw predicted conflicts between aircraft
+	if r.config.ShowConflicts {
+		r.drawConflicts(aircraft)
+	}
 
 	// Draw all aircraft
 	r.drawAircraft(aircraft, selectedICAO)
 
+	// Draw the measuring tool over the traffic
+	r.drawMeasurement(centerLat, centerLon, maxDistance)
+
+	// Draw details of the selected aircraft
+	if selected, ok := aircraft[selectedICAO]; ok {
+		r.drawInfoPanel(selected)
+
+		if r.config.ShowAltitudeProfile {
+			r.drawAltitudeProfile(selected)
+		}
+	}
+
+	// Draw recent events
+	if r.config.ShowLog {
+		r.drawLogPane()
+	}
+
+	// Show that the map is still on its way
+	if r.mapLoad != nil {
+		r.drawLoading()
+	}
+
+	// Grey out the scope while the feed is silent
+	if r.stats.NoData && r.config.DataTimeoutBlank {
+		r.drawNoData()
+	}
+
+	// Outline the display after an alert
+	r.drawFlash()
+
 	// Draw scale bar
 	r.drawScaleBars(maxDistance)
 
 	// Draw status information
 	r.drawStatus(countAircraft(aircraft), countVisibleAircraft(aircraft), centerLat, centerLon)
 
+	// Show where recent frames spent their time
+	if r.config.ShowPerfHUD {
+		r.drawPerfHUD()
+	}
+
 	// Present the renderer
+	r.endPhase(phaseDraw)
 	r.renderer.Present()
+	r.endPhase(phasePresent)
+	r.perf.add(r.phases)
 }
 
 // calculateScreenPositions calculates screen coordinates for all aircraft
 func (r *Renderer) calculateScreenPositions(aircraft map[uint32]*adsb.Aircraft, centerLat, centerLon, maxDistance float64) {
 	for _, a := range aircraft {
-		if a.Lat == 0 && a.Lon == 0 {
+		if !a.HasPosition {
 			continue // Skip aircraft without position
 		}
 
@@ -339,20 +782,13 @@ func (r *Renderer) calculateScreenPositions(aircraft map[uint32]*adsb.Aircraft,
 		x, y := r.latLonToScreen(a.Lat, a.Lon, centerLat, centerLon, maxDistance)
 		a.X = x
 		a.Y = y
-
-		// Initialize label position if needed
-		if a.LabelX == 0 && a.LabelY == 0 {
-			a.LabelX = float64(x)
-			a.LabelY = float64(y) + 20*float64(r.uiScale)
-		}
 	}
 }
 
 // latLonToScreen converts geographical coordinates to screen coordinates
 func (r *Renderer) latLonToScreen(lat, lon, centerLat, centerLon, maxDistance float64) (int, int) {
-	// Convert lat/lon to distance in NM
-	dx := (lon - centerLon) * math.Cos(((lat+centerLat)/2.0)*math.Pi/180.0) * 60
-	dy := (lat - centerLat) * 60
+	// Convert lat/lon to distance in NM from the center
+	dx, dy := map_system.NewProjection(r.projection, centerLat, centerLon).Forward(lat, lon)
 
 	// Scale to screen coordinates
 	scale := float64(r.height) / (maxDistance * 2)
@@ -375,40 +811,37 @@ func (r *Renderer) drawMap(centerLat, centerLon, maxDistance float64) {
 	r.renderer.SetDrawColor(ColorBackground.R, ColorBackground.G, ColorBackground.B, ColorBackground.A)
 	r.renderer.Clear()
 
+	// Draw the raster base map beneath the vector layer
+	if r.baseImage != nil {
+		r.drawBaseImage(centerLat, centerLon, maxDistance)
+	}
+
 	// Calculate visible area bounds
 	latMin, lonMin, latMax, lonMax := r.calculateVisibleBounds(centerLat, centerLon, maxDistance)
 
 	// Draw map elements if available
 	if r.mapSystem != nil {
-		// Get visible map features
-		mapLines, airportLines := r.mapSystem.GetVisibleLines(latMin, latMax, lonMin, lonMax)
-
-		// Draw map lines
-		r.renderer.SetDrawColor(ColorMap.R, ColorMap.G, ColorMap.B, ColorMap.A)
-		for _, line := range mapLines {
-			x1, y1 := r.latLonToScreen(line.Start.Lat, line.Start.Lon, centerLat, centerLon, maxDistance)
-			x2, y2 := r.latLonToScreen(line.End.Lat, line.End.Lon, centerLat, centerLon, maxDistance)
-
-			// Skip if outside viewport
-			if r.outOfBounds(x1, y1) && r.outOfBounds(x2, y2) {
-				continue
-			}
-
-			r.renderer.DrawLine(int32(x1), int32(y1), int32(x2), int32(y2))
+		// Get visible map features, reusing last redraw's buffers
+		for i := range r.layerLineBuf {
+			r.layerLineBuf[i] = r.layerLineBuf[i][:0]
 		}
+		r.layerLineBuf = r.mapSystem.AppendVisibleLines(r.layerLineBuf, latMin, latMax, lonMin, lonMax)
 
-		// Draw airport lines
-		r.renderer.SetDrawColor(ColorAirport.R, ColorAirport.G, ColorAirport.B, ColorAirport.A)
-		for _, line := range airportLines {
-			x1, y1 := r.latLonToScreen(line.Start.Lat, line.Start.Lon, centerLat, centerLon, maxDistance)
-			x2, y2 := r.latLonToScreen(line.End.Lat, line.End.Lon, centerLat, centerLon, maxDistance)
+		// Draw each layer's lines in its color
+		for i, lines := range r.layerLineBuf {
+			r.beginLines(r.layerColors[i])
+			for _, line := range lines {
+				x1, y1 := r.latLonToScreen(line.Start.Lat, line.Start.Lon, centerLat, centerLon, maxDistance)
+				x2, y2 := r.latLonToScreen(line.End.Lat, line.End.Lon, centerLat, centerLon, maxDistance)
 
-			// Skip if outside viewport
-			if r.outOfBounds(x1, y1) && r.outOfBounds(x2, y2) {
-				continue
-			}
+				// Skip if outside viewport
+				if r.outOfBounds(x1, y1) && r.outOfBounds(x2, y2) {
+					continue
+				}
 
-			r.renderer.DrawLine(int32(x1), int32(y1), int32(x2), int32(y2))
+				r.line(x1, y1, x2, y2)
+			}
+			r.endLines()
 		}
 
 		// Draw place labels
@@ -429,7 +862,7 @@ func (r *Renderer) drawMap(centerLat, centerLon, maxDistance float64) {
 			}
 			r.drawText(label.Text, x, y, r.boldFont, ColorText)
 		}
-	} else {
+	} else if r.mapLoad == nil {
 		// Draw a fallback grid if no map data is loaded
 		r.renderer.SetDrawColor(ColorMap.R, ColorMap.G, ColorMap.B, ColorMap.A)
 		for i := 0; i < r.width; i += 50 {
@@ -449,6 +882,10 @@ func (r *Renderer) drawMap(centerLat, centerLon, maxDistance float64) {
 
 // calculateVisibleBounds calculates the lat/lon bounds of the visible area
 func (r *Renderer) calculateVisibleBounds(centerLat, centerLon, maxDistance float64) (latMin, lonMin, latMax, lonMax float64) {
+	if r.projection == map_system.AzimuthalEquidistant {
+		return r.azimuthalBounds(centerLat, centerLon, maxDistance)
+	}
+
 	// Calculate how much lat/lon changes per pixel
 	latPerPixel := (maxDistance * 2) / float64(r.height) / 60.0
 	lonPerPixel := latPerPixel / math.Cos(centerLat*math.Pi/180.0)
@@ -459,12 +896,66 @@ func (r *Renderer) calculateVisibleBounds(centerLat, centerLon, maxDistance floa
 
 	latMin = centerLat - halfHeight*latPerPixel
 	latMax = centerLat + halfHeight*latPerPixel
-	lonMin = centerLon - halfWidth*lonPerPixel
-	lonMax = centerLon + halfWidth*lonPerPixel
+
+	// Wrap longitudes into -180..180; a view straddling the anti-meridian
+	// ends up with lonMin > lonMax, which the map queries understand
+	if halfWidth*lonPerPixel >= 180 {
+		lonMin, lonMax = -180, 180
+	} else {
+		lonMin = map_system.NormalizeLon(centerLon - halfWidth*lonPerPixel)
+		lonMax = map_system.NormalizeLon(centerLon + halfWidth*lonPerPixel)
+	}
 
 	return
 }
 
+// azimuthalBounds calculates the lat/lon bounds of the visible area under the
+// azimuthal projection, where the screen edges are curves on the globe. The
+// bounds are taken from points along the edges, widened to a pole in view.
+func (r *Renderer) azimuthalBounds(centerLat, centerLon, maxDistance float64) (latMin, lonMin, latMax, lonMax float64) {
+	p := map_system.NewProjection(map_system.AzimuthalEquidistant, centerLat, centerLon)
+	nmPerPixel := (maxDistance * 2) / float64(r.height)
+	halfWidth := float64(r.width) / 2 * nmPerPixel
+	halfHeight := maxDistance
+
+	latMin, latMax = centerLat, centerLat
+	dLonMin, dLonMax := 0.0, 0.0
+
+	const steps = 8
+	for i := 0; i <= steps; i++ {
+		t := 2*float64(i)/steps - 1
+		edges := [][2]float64{
+			{t * halfWidth, halfHeight}, {t * halfWidth, -halfHeight},
+			{halfWidth, t * halfHeight}, {-halfWidth, t * halfHeight},
+		}
+		for _, e := range edges {
+			lat, lon := p.Inverse(e[0], e[1])
+			latMin, latMax = math.Min(latMin, lat), math.Max(latMax, lat)
+			dLon := map_system.NormalizeLon(lon - centerLon)
+			dLonMin, dLonMax = math.Min(dLonMin, dLon), math.Max(dLonMax, dLon)
+		}
+	}
+
+	// A pole on screen takes in every longitude
+	poleInView := false
+	for _, pole := range []float64{90, -90} {
+		east, north := p.Forward(pole, centerLon)
+		if math.Abs(east) <= halfWidth && math.Abs(north) <= halfHeight {
+			poleInView = true
+			if pole > 0 {
+				latMax = 90
+			} else {
+				latMin = -90
+			}
+		}
+	}
+
+	if poleInView || dLonMax-dLonMin >= 360-1e-9 || math.Hypot(halfWidth, halfHeight) >= 180*60 {
+		return latMin, -180, latMax, 180
+	}
+	return latMin, map_system.NormalizeLon(centerLon + dLonMin), latMax, map_system.NormalizeLon(centerLon + dLonMax)
+}
+
 // drawTrails renders the trail of an aircraft's past positions
 func (r *Renderer) drawTrails(aircraft map[uint32]*adsb.Aircraft, centerLat, centerLon, maxDistance float64) {
 	for _, a := range aircraft {
@@ -483,35 +974,221 @@ func (r *Renderer) drawTrails(aircraft map[uint32]*adsb.Aircraft, centerLat, cen
 			x2, y2 := r.latLonToScreen(a.Trail[i+1].Lat, a.Trail[i+1].Lon, centerLat, centerLon, maxDistance)
 
 			// Draw trail segment
-			r.renderer.SetDrawColor(ColorTrail.R, ColorTrail.G, ColorTrail.B, alpha)
-			r.renderer.DrawLine(int32(x1), int32(y1), int32(x2), int32(y2))
+			color := ColorTrail
+			color.A = alpha
+			r.drawThickLine(x1, y1, x2, y2, r.trailWidth(), color)
+		}
+	}
+}
+
+// drawReceiver draws an antenna marker at the receiver location when it is on screen
+func (r *Renderer) drawReceiver(centerLat, centerLon, maxDistance float64) {
+	x, y := r.latLonToScreen(r.config.InitialLat, r.config.InitialLon, centerLat, centerLon, maxDistance)
+	if r.outOfBounds(x, y) {
+		return
+	}
+
+	// Concentric rings around a center dot
+	r.drawCircle(x, y, 3*r.uiScale, ColorReceiver)
+	r.drawCircle(x, y, 6*r.uiScale, ColorReceiver)
+	r.drawRect(int32(x-r.uiScale), int32(y-r.uiScale), int32(2*r.uiScale), int32(2*r.uiScale), ColorReceiver)
+}
+
+// drawAccuracy draws a faint circle showing the position uncertainty of
+// aircraft whose reported accuracy is worse than half a nautical mile
+func (r *Renderer) drawAccuracy(aircraft map[uint32]*adsb.Aircraft, maxDistance float64) {
+	const lowQuality = 926.0 // meters
+
+	pixelsPerNM := float64(r.height) / (maxDistance * 2)
+	for _, a := range aircraft {
+		if !a.HasPosition {
+			continue // Skip aircraft without position
+		}
+		if a.Accuracy < lowQuality {
+			continue
+		}
+
+		radius := int(a.Accuracy / 1852.0 * pixelsPerNM)
+		if radius < 3 {
+			continue
 		}
+		r.drawCircle(a.X, a.Y, radius, ColorAccuracy)
 	}
 }
 
 // drawAircraft renders all aircraft symbols and labels
 func (r *Renderer) drawAircraft(aircraft map[uint32]*adsb.Aircraft, selectedICAO uint32) {
 	for icao, a := range aircraft {
-		if a.X == 0 && a.Y == 0 {
-			continue // Skip aircraft without position
+		if !a.HasPosition || a.Stacked {
+			continue // Skip aircraft without position or hidden in a stack
 		}
 
-		// Determine color based on selection and age
+		// Determine color based on selection, address type and age
 		color := ColorPlane
+		if r.config.ColorByAltitude && a.HasAltitude {
+			color = altitudeColor(r.altitudeStops, a.Altitude)
+		}
+		if a.AddrType.Anonymous() {
+			color = ColorAnonymous
+		}
+		if style, ok := r.watchlist.Match(icao, a.Flight); ok {
+			color = style.color
+		}
+		if r.config.BrightnessByRate {
+			color = scaleBrightness(color, rateBrightness(a.MsgRate))
+		}
+		if a.Emergency != "" || r.squawkAlertOf(a) != "" {
+			color = ColorEmergency
+		}
 		if icao == selectedICAO {
 			color = ColorSelected
-		} else if time.Since(a.Seen).Seconds() > 15 {
+		} else if fade := r.fadeAmount(a); fade > 0 {
 			// Fade color the longer we haven't seen the aircraft
-			fade := math.Min(1.0, (time.Since(a.Seen).Seconds()-15.0)/15.0)
-			color = lerpColor(ColorPlane, ColorPlaneGone, fade)
+			color = lerpColor(color, ColorPlaneGone, fade)
+		}
+
+		// Draw aircraft symbol, or a ghost at the last known spot once the
+		// position has gone stale
+		if r.isGhost(a) {
+			r.drawGhostSymbol(a.X, a.Y, color)
+		} else if a.OnGround {
+			r.drawGroundSymbol(a.X, a.Y, symbolHeading(a), color)
+		} else {
+			r.drawAircraftSymbol(a.X, a.Y, symbolHeading(a), color)
+		}
+		if a.StackCount > 0 {
+			r.drawStackCount(a, color)
+		}
+		if r.config.ShowIdent && identActive(a, r.clock) {
+			r.drawIdent(a)
+		}
+
+		// Draw label unless it is too far from the center
+		if !labelHidden(a) {
+			r.drawAircraftLabel(a, color)
+		}
+	}
+}
+
+// updateAirportTags sets NearAirport for aircraft below the approach altitude
+// within range of an airport, and clears it once they climb or move away
+func (r *Renderer) updateAirportTags(aircraft map[uint32]*adsb.Aircraft) {
+	if r.mapSystem == nil || r.config.ApproachNM <= 0 {
+		return
+	}
+
+	for _, a := range aircraft {
+		a.NearAirport = ""
+		if !a.HasPosition || (!a.OnGround && (!a.HasAltitude || a.Altitude > r.config.ApproachFeet)) {
+			continue
+		}
+
+		if airport, _ := r.mapSystem.NearestAirport(a.Lat, a.Lon, r.config.ApproachNM); airport != nil {
+			a.NearAirport = airport.Text
 		}
+	}
+}
+
+// fadeAmount returns how far an aircraft has faded towards ColorPlaneGone,
+// from 0 to 1. The fade is shortened when needed so it completes by the time
+// the aircraft is removed.
+func (r *Renderer) fadeAmount(a *adsb.Aircraft) float64 {
+	ttl := r.config.TTLFor(a.OnGround)
+	if a.AddrType.Anonymous() && r.config.AnonymousTTL > 0 {
+		ttl = min(ttl, r.config.AnonymousTTL)
+	}
+	start, duration := fadeWindow(float64(r.config.FadeStartSeconds), float64(r.config.FadeDurationSeconds), float64(ttl))
+
+	age := r.clock.Sub(a.Seen).Seconds()
+	if age <= start {
+		return 0
+	}
+	if duration <= 0 {
+		return 1
+	}
+	return math.Min(1.0, (age-start)/duration)
+}
+
+// fadeWindow fits a fade starting at start seconds and lasting duration
+// seconds inside a removal TTL, so it always completes before removal
+func fadeWindow(start, duration, ttl float64) (float64, float64) {
+	if ttl <= 0 || start+duration <= ttl {
+		return start, duration
+	}
+	if start >= ttl {
+		start = ttl / 2
+	}
+	return start, ttl - start
+}
+
+// isGhost reports whether an aircraft's position is too old to be trusted
+func (r *Renderer) isGhost(a *adsb.Aircraft) bool {
+	if r.config.GhostSeconds <= 0 {
+		return false
+	}
+	return r.clock.Sub(a.SeenLatLon) > time.Duration(r.config.GhostSeconds)*time.Second
+}
+
+// drawGhostSymbol draws a dashed ring with a question mark for an aircraft
+// whose position has gone stale
+func (r *Renderer) drawGhostSymbol(x, y int, color sdl.Color) {
+	r.renderer.SetDrawColor(color.R, color.G, color.B, color.A)
+
+	radius := 7 * float64(r.uiScale) * r.symbolScale()
+	const segments = 16
+	for i := 0; i < segments; i += 2 {
+		a1 := 2 * math.Pi * float64(i) / segments
+		a2 := 2 * math.Pi * float64(i+1) / segments
+		r.renderer.DrawLine(
+			int32(float64(x)+radius*math.Cos(a1)), int32(float64(y)+radius*math.Sin(a1)),
+			int32(float64(x)+radius*math.Cos(a2)), int32(float64(y)+radius*math.Sin(a2)))
+	}
 
-		// Draw aircraft symbol
-		r.drawAircraftSymbol(a.X, a.Y, a.Heading, color)
+	r.drawText("?", x-r.charWidth()/2, y-r.fontSize/2, r.regularFont, color)
+}
 
-		// Draw label
-		r.drawAircraftLabel(a, color)
+// symbolHeading returns the direction to draw an aircraft's symbol in:
+// the surface ground track on the ground, upright when there is none, and
+// the airborne track or heading otherwise
+func symbolHeading(a *adsb.Aircraft) int {
+	if !a.OnGround {
+		return a.Heading
+	}
+	if !a.HasGroundTrack {
+		return 0
 	}
+	return a.GroundTrack
+}
+
+// drawGroundSymbol draws an aircraft on the ground as a narrow box pointing
+// along its ground track, with a nose tick to show the direction
+func (r *Renderer) drawGroundSymbol(x, y, heading int, color sdl.Color) {
+	headingRad := float64(heading) * math.Pi / 180.0
+
+	scale := float64(r.uiScale) * r.symbolScale()
+	halfLen := 5 * scale
+	halfWidth := 2 * scale
+	noseLen := 3 * scale
+
+	dirX, dirY := math.Sin(headingRad), -math.Cos(headingRad)
+	perpX, perpY := -dirY, dirX
+
+	corner := func(along, across float64) (int32, int32) {
+		return int32(float64(x) + dirX*along + perpX*across), int32(float64(y) + dirY*along + perpY*across)
+	}
+	x1, y1 := corner(halfLen, halfWidth)
+	x2, y2 := corner(halfLen, -halfWidth)
+	x3, y3 := corner(-halfLen, -halfWidth)
+	x4, y4 := corner(-halfLen, halfWidth)
+	noseX, noseY := corner(halfLen+noseLen, 0)
+	frontX, frontY := corner(halfLen, 0)
+
+	r.renderer.SetDrawColor(color.R, color.G, color.B, color.A)
+	r.renderer.DrawLine(x1, y1, x2, y2)
+	r.renderer.DrawLine(x2, y2, x3, y3)
+	r.renderer.DrawLine(x3, y3, x4, y4)
+	r.renderer.DrawLine(x4, y4, x1, y1)
+	r.renderer.DrawLine(frontX, frontY, noseX, noseY)
 }
 
 // drawAircraftSymbol draws an aircraft symbol at the specified position
@@ -520,9 +1197,10 @@ func (r *Renderer) drawAircraftSymbol(x, y, heading int, color sdl.Color) {
 	headingRad := float64(heading) * math.Pi / 180.0
 
 	// Scale factors for plane size
-	bodyLen := float64(8 * r.uiScale)
-	wingLen := float64(6 * r.uiScale)
-	tailLen := float64(3 * r.uiScale)
+	scale := float64(r.uiScale) * r.symbolScale()
+	bodyLen := 8 * scale
+	wingLen := 6 * scale
+	tailLen := 3 * scale
 
 	// Calculate direction vectors
 	dirX := math.Sin(headingRad)
@@ -564,14 +1242,25 @@ func (r *Renderer) drawAircraftSymbol(x, y, heading int, color sdl.Color) {
 
 // drawAircraftLabel draws a label for the specified aircraft
 func (r *Renderer) drawAircraftLabel(a *adsb.Aircraft, color sdl.Color) {
-	// If this is the first time seeing this aircraft, initialize label size
+	// If this is the first time seeing this aircraft, initialize label state
 	if a.LabelW == 0 || a.LabelH == 0 {
-		a.LabelW = 100
-		a.LabelH = 45
 		a.LabelOpacity = 0
 		a.LabelLevel = 0
 	}
 
+	if r.config.CompactLabels {
+		r.drawCompactTag(a, color)
+		return
+	}
+
+	// Size the label for the current font
+	lineHeight := r.lineHeight()
+	a.LabelW = float64(r.fontSize * 25 / 3)
+	a.LabelH = float64(3*lineHeight + r.fontSize/4)
+	if a.LabelLevel >= 1 {
+		a.LabelH = float64(lineHeight + r.fontSize/4)
+	}
+
 	// Fade in opacity
 	if a.LabelOpacity < 1.0 {
 		a.LabelOpacity += 0.05
@@ -597,17 +1286,30 @@ func (r *Renderer) drawAircraftLabel(a *adsb.Aircraft, color sdl.Color) {
 	r.drawRectOutline(int32(a.LabelX), int32(a.LabelY), int32(a.LabelW), int32(a.LabelH), lineColor)
 
 	// Draw label content based on label level
-	textY := int(a.LabelY) + 5
+	textY := int(a.LabelY) + 5*r.uiScale
 
 	// Always show callsign
 	textColor := ColorLabel
 	textColor.A = alpha
 	flight := a.Flight
 	if flight == "" {
-		flight = fmt.Sprintf("%06X", a.ICAO)
+		flight = adsb.FormatAddress(a.ICAO)
 	}
-	r.drawText(flight, int(a.LabelX)+5, textY, r.labelFont, textColor)
-	textY += 14
+	if r.config.LabelShowType && a.TypeCode != "" {
+		flight += " " + a.TypeCode
+	}
+	if style, ok := r.watchlist.Match(a.ICAO, a.Flight); ok && style.label != "" {
+		flight += " " + style.label
+	}
+	if a.Emergency != "" {
+		flight += " " + strings.ToUpper(a.Emergency)
+	} else if alert := r.squawkAlertOf(a); alert != "" {
+		flight += " " + alert
+	} else if a.NearAirport != "" {
+		flight += " \u2192" + a.NearAirport
+	}
+	r.drawText(flight, int(a.LabelX)+5*r.uiScale, textY, r.labelFont, textColor)
+	textY += lineHeight
 
 	// Show altitude and speed if level allows
 	if a.LabelLevel < 1 {
@@ -615,14 +1317,9 @@ func (r *Renderer) drawAircraftLabel(a *adsb.Aircraft, color sdl.Color) {
 		subTextColor.A = alpha
 
 		// Altitude
-		altText := ""
-		if r.metric {
-			altText = fmt.Sprintf(" %dm", int(float64(a.Altitude)/3.2828))
-		} else {
-			altText = fmt.Sprintf(" %d'", a.Altitude)
-		}
-		r.drawText(altText, int(a.LabelX)+5, textY, r.regularFont, subTextColor)
-		textY += 14
+		altText := " " + r.altitudeText(a)
+		r.drawText(altText, int(a.LabelX)+5*r.uiScale, textY, r.regularFont, subTextColor)
+		textY += lineHeight
 
 		// Speed
 		speedText := ""
@@ -631,7 +1328,10 @@ func (r *Renderer) drawAircraftLabel(a *adsb.Aircraft, color sdl.Color) {
 		} else {
 			speedText = fmt.Sprintf(" %dkts", a.Speed)
 		}
-		r.drawText(speedText, int(a.LabelX)+5, textY, r.regularFont, subTextColor)
+		if tag := r.squawkTag(a); tag != "" {
+			speedText += " " + tag
+		}
+		r.drawText(speedText, int(a.LabelX)+5*r.uiScale, textY, r.regularFont, subTextColor)
 	}
 
 	// Draw connecting line from aircraft to label
@@ -647,23 +1347,62 @@ func (r *Renderer) drawAircraftLabel(a *adsb.Aircraft, color sdl.Color) {
 	r.renderer.DrawLine(int32(a.X), int32(a.Y), anchorX, anchorY)
 }
 
-// drawScaleBars draws distance scale indicators
-func (r *Renderer) drawScaleBars(maxDistance float64) {
-	// Find appropriate scale - powers of 10
-	scalePower := 0
-	scaleBarDist := 0
+// drawCompactTag draws a single line "flight altitude" tag in the symbol's
+// color instead of the boxed label, placed by the label system like a label
+func (r *Renderer) drawCompactTag(a *adsb.Aircraft, color sdl.Color) {
+	text := a.Flight
+	if text == "" {
+		text = adsb.FormatAddress(a.ICAO)
+	}
+	if a.LabelLevel < 1 {
+		text += " " + r.altitudeText(a)
+	}
+	if tag := r.squawkTag(a); tag != "" {
+		text += " " + tag
+	}
 
-	for {
-		dist := math.Pow10(scalePower)
-		screenDist := float64(r.height/2) * (dist / maxDistance)
+	a.LabelW = float64(utf8.RuneCountInString(text) * r.charWidth())
+	a.LabelH = float64(r.lineHeight())
+	a.LabelOpacity = 1
 
-		if screenDist > 100 {
-			scaleBarDist = int(screenDist)
-			break
-		}
-		scalePower++
+	r.drawText(text, int(a.LabelX), int(a.LabelY), r.labelFont, color)
+
+	// Leader line to the nearest end of the tag
+	anchorX := int32(a.LabelX)
+	if a.LabelX+a.LabelW/2 < float64(a.X) {
+		anchorX = int32(a.LabelX + a.LabelW)
+	}
+	r.renderer.SetDrawColor(ColorLabelLine.R, ColorLabelLine.G, ColorLabelLine.B, ColorLabelLine.A)
+	r.renderer.DrawLine(int32(a.X), int32(a.Y), anchorX, int32(a.LabelY+a.LabelH/2))
+}
+
+// altitudeText formats an aircraft's altitude in the display units, "-"
+// until one is decoded, followed by an arrow while climbing or descending
+func (r *Renderer) altitudeText(a *adsb.Aircraft) string {
+	if !a.HasAltitude {
+		return "-"
+	}
+	alt := FormatAltitude(a.Altitude, r.metric, r.config.TransitionAltitude)
+	if a.SeenTypes&adsb.SeenVelocity != 0 {
+		alt += vertRateArrow(a.VertRate, r.config.VertRateThreshold)
+	}
+	return alt
+}
+
+// drawScaleBars draws distance scale indicators
+func (r *Renderer) drawScaleBars(maxDistance float64) {
+	// Work out how many pixels one display unit (nm or km) covers
+	pixelsPerUnit := float64(r.height/2) / maxDistance
+	unit := "nm"
+	if r.metric {
+		pixelsPerUnit /= 1.852 // 1 nm = 1.852 km
+		unit = "km"
 	}
 
+	// Find appropriate scale from the 1-2-5 sequence
+	dist := scaleBarDistance(pixelsPerUnit, 100)
+	scaleBarDist := int(dist * pixelsPerUnit)
+
 	// Draw horizontal scale bar
 	r.renderer.SetDrawColor(ColorScaleBar.R, ColorScaleBar.G, ColorScaleBar.B, ColorScaleBar.A)
 	r.renderer.DrawLine(10, 10, 10+int32(scaleBarDist), 10)
@@ -671,15 +1410,25 @@ func (r *Renderer) drawScaleBars(maxDistance float64) {
 	r.renderer.DrawLine(10+int32(scaleBarDist), 10, 10+int32(scaleBarDist), 15)
 
 	// Draw scale label
-	scaleLabel := ""
-	if r.metric {
-		scaleLabel = fmt.Sprintf("%dkm", int(math.Pow10(scalePower)))
-	} else {
-		scaleLabel = fmt.Sprintf("%dnm", int(math.Pow10(scalePower)))
-	}
+	scaleLabel := fmt.Sprintf("%g%s", dist, unit)
 	r.drawText(scaleLabel, 15+scaleBarDist, 15, r.regularFont, ColorScaleBar)
 }
 
+// scaleBarDistance returns the smallest distance from the 1-2-5 sequence
+// (0.1, 0.2, 0.5, 1, 2, 5, 10, ...) that spans at least minPixels on screen
+func scaleBarDistance(pixelsPerUnit, minPixels float64) float64 {
+	dist := 0.0
+	for exp := -1; exp <= 6; exp++ {
+		for _, mult := range []float64{1, 2, 5} {
+			dist = mult * math.Pow10(exp)
+			if dist*pixelsPerUnit >= minPixels {
+				return dist
+			}
+		}
+	}
+	return dist
+}
+
 // drawStatus draws status information at the bottom of the screen
 func (r *Renderer) drawStatus(aircraftCount, visibleCount int, centerLat, centerLon float64) {
 	// Format location text
@@ -697,14 +1446,52 @@ func (r *Renderer) drawStatus(aircraftCount, visibleCount int, centerLat, center
 	// Draw the status boxes
 	r.drawStatusBox(&x, &y, "loc", locText, ColorScaleBar)
 	r.drawStatusBox(&x, &y, "disp", dispText, ColorScaleBar)
+	if r.stats.ShortFrames > 0 {
+		r.drawStatusBox(&x, &y, "drop", fmt.Sprintf("%d", r.stats.ShortFrames), ColorScaleBar)
+	}
+	if r.stats.NoData {
+		r.drawStatusBox(&x, &y, "NO DATA", "", ColorEmergency)
+	}
+	if r.stats.ViewMode != "" {
+		r.drawStatusBox(&x, &y, "view", r.stats.ViewMode, ColorScaleBar)
+	}
+	if r.stats.Measuring {
+		r.drawStatusBox(&x, &y, "MEASURE", "", ColorSelected)
+	}
+	if r.stats.Paused {
+		r.drawStatusBox(&x, &y, "PAUSED", fmt.Sprintf("%d", r.stats.Buffered), ColorSelected)
+	}
+	if !r.config.ShowTrails {
+		r.drawStatusBox(&x, &y, "trl", "off", ColorScaleBar)
+	}
+	if r.config.ShowFPS {
+		r.drawStatusBox(&x, &y, "fps", fmt.Sprintf("%.1f %.1fms", r.stats.FPS,
+			float64(r.stats.FrameTime.Microseconds())/1000.0), ColorScaleBar)
+	}
+}
+
+// drawNoData dims the display and draws a NO DATA banner across the middle
+func (r *Renderer) drawNoData() {
+	r.renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	r.renderer.SetDrawColor(ColorBackground.R, ColorBackground.G, ColorBackground.B, 180)
+	r.renderer.FillRect(&sdl.Rect{X: 0, Y: 0, W: int32(r.width), H: int32(r.height)})
+	r.renderer.SetDrawBlendMode(sdl.BLENDMODE_NONE)
+
+	text := "NO DATA"
+	w := len(text) * r.charWidth()
+	h := r.lineHeight()
+	x, y := (r.width-w)/2, (r.height-h)/2
+	r.drawRect(int32(x-PAD*r.uiScale), int32(y-PAD*r.uiScale), int32(w+2*PAD*r.uiScale), int32(h+2*PAD*r.uiScale), ColorButtonBg)
+	r.drawRectOutline(int32(x-PAD*r.uiScale), int32(y-PAD*r.uiScale), int32(w+2*PAD*r.uiScale), int32(h+2*PAD*r.uiScale), ColorEmergency)
+	r.drawText(text, x, y, r.boldFont, ColorEmergency)
 }
 
 // drawStatusBox draws a status box with label and value
 func (r *Renderer) drawStatusBox(x *int, y *int, label, value string, color sdl.Color) {
 	// Calculate dimensions
-	labelFontWidth := 6 * r.uiScale
-	messageFontWidth := 6 * r.uiScale
-	messageFontHeight := 12 * r.uiScale
+	labelFontWidth := r.charWidth()
+	messageFontWidth := r.charWidth()
+	messageFontHeight := r.fontSize
 
 	labelWidth := (len(label) + 1) * labelFontWidth
 	messageWidth := (len(value) + 1) * messageFontWidth
@@ -793,6 +1580,64 @@ func (r *Renderer) drawRectOutline(x, y, w, h int32, color sdl.Color) {
 	r.renderer.DrawRect(&sdl.Rect{X: x, Y: y, W: w, H: h})
 }
 
+// drawCircle draws a circle outline as a polygon of short segments
+func (r *Renderer) drawCircle(x, y, radius int, color sdl.Color) {
+	r.beginLines(color)
+	defer r.endLines()
+
+	segments := 8 + radius
+	prevX, prevY := x+radius, y
+	for i := 1; i <= segments; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(segments)
+		px := x + int(math.Round(float64(radius)*math.Cos(angle)))
+		py := y + int(math.Round(float64(radius)*math.Sin(angle)))
+		r.line(prevX, prevY, px, py)
+		prevX, prevY = px, py
+	}
+}
+
+// drawThickLine draws a line of the given width in pixels, using a quad for
+// widths above one since SDL's DrawLine is always a single pixel wide
+func (r *Renderer) drawThickLine(x1, y1, x2, y2, width int, color sdl.Color) {
+	if width <= 1 {
+		r.beginLines(color)
+		r.line(x1, y1, x2, y2)
+		r.endLines()
+		return
+	}
+
+	dx, dy := float64(x2-x1), float64(y2-y1)
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+
+	// Offset each end by half the width along the line's normal
+	nx := float32(-dy / length * float64(width) / 2)
+	ny := float32(dx / length * float64(width) / 2)
+
+	vertex := func(x, y int, sx, sy float32) sdl.Vertex {
+		return sdl.Vertex{Position: sdl.FPoint{X: float32(x) + sx, Y: float32(y) + sy}, Color: color}
+	}
+	vertices := []sdl.Vertex{
+		vertex(x1, y1, nx, ny),
+		vertex(x1, y1, -nx, -ny),
+		vertex(x2, y2, nx, ny),
+		vertex(x2, y2, -nx, -ny),
+	}
+	r.renderer.RenderGeometry(nil, vertices, []int32{0, 1, 2, 2, 1, 3})
+}
+
+// symbolScale returns the configured aircraft symbol scale clamped to a sane range
+func (r *Renderer) symbolScale() float64 {
+	return math.Max(MinSymbolScale, math.Min(MaxSymbolScale, r.config.SymbolScale))
+}
+
+// trailWidth returns the configured trail width clamped to a sane range
+func (r *Renderer) trailWidth() int {
+	return max(1, min(MaxTrailWidth, r.config.TrailWidth))
+}
+
 // outOfBounds checks if a point is off the screen
 func (r *Renderer) outOfBounds(x, y int) bool {
 	return x < 0 || x >= r.width || y < 0 || y >= r.height
@@ -803,10 +1648,10 @@ func lerpColor(a, b sdl.Color, t float64) sdl.Color {
 	t = math.Max(0, math.Min(1, t)) // Clamp t to 0-1
 
 	return sdl.Color{
-		R: uint8(float64(a.R) + t*float64(b.R-a.R)),
-		G: uint8(float64(a.G) + t*float64(b.G-a.G)),
-		B: uint8(float64(a.B) + t*float64(b.B-a.B)),
-		A: uint8(float64(a.A) + t*float64(b.A-a.A)),
+		R: uint8(math.Round(float64(a.R) + t*(float64(b.R)-float64(a.R)))),
+		G: uint8(math.Round(float64(a.G) + t*(float64(b.G)-float64(a.G)))),
+		B: uint8(math.Round(float64(a.B) + t*(float64(b.B)-float64(a.B)))),
+		A: uint8(math.Round(float64(a.A) + t*(float64(b.A)-float64(a.A)))),
 	}
 }
 
@@ -819,13 +1664,24 @@ func countAircraft(aircraft map[uint32]*adsb.Aircraft) int {
 func countVisibleAircraft(aircraft map[uint32]*adsb.Aircraft) int {
 	count := 0
 	for _, a := range aircraft {
-		if a.Lat != 0 && a.Lon != 0 {
+		if a.HasPosition {
 			count++
 		}
 	}
 	return count
 }
 
+// positionedAircraft returns only the aircraft with a decoded position
+func positionedAircraft(aircraft map[uint32]*adsb.Aircraft) map[uint32]*adsb.Aircraft {
+	positioned := make(map[uint32]*adsb.Aircraft, len(aircraft))
+	for icao, a := range aircraft {
+		if a.HasPosition {
+			positioned[icao] = a
+		}
+	}
+	return positioned
+}
+
 // Cleanup releases all resources
 func (r *Renderer) Cleanup() {
 	if r.labelFont != nil && r.labelFont != r.regularFont {
@@ -844,6 +1700,10 @@ func (r *Renderer) Cleanup() {
 		r.mapTexture.Destroy()
 	}
 
+	if r.baseImage != nil {
+		r.baseImage.texture.Destroy()
+	}
+
 	if r.renderer != nil {
 		r.renderer.Destroy()
 	}
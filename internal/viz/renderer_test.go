@@ -0,0 +1,220 @@
+package viz
+
+import (
+	"testing"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+func TestFadeWindowEndsBeforeRemoval(t *testing.T) {
+	tests := []struct {
+		start, duration, ttl float64
+		wantStart, wantDur   float64
+	}{
+		{15, 15, 30, 15, 15}, // Fits exactly
+		{15, 15, 60, 15, 15}, // Fits with room to spare
+		{15, 15, 20, 15, 5},  // Shortened to end at the TTL
+		{40, 15, 30, 15, 15}, // Starts after removal, so moved to half the TTL
+		{15, 15, 0, 15, 15},  // No TTL
+	}
+
+	for _, tt := range tests {
+		start, dur := fadeWindow(tt.start, tt.duration, tt.ttl)
+		if start != tt.wantStart || dur != tt.wantDur {
+			t.Errorf("fadeWindow(%v, %v, %v) = %v, %v, want %v, %v",
+				tt.start, tt.duration, tt.ttl, start, dur, tt.wantStart, tt.wantDur)
+		}
+	}
+}
+
+func TestVisibleBoundsAcrossAntiMeridian(t *testing.T) {
+	r := &Renderer{width: 800, height: 600}
+
+	_, lonMin, _, lonMax := r.calculateVisibleBounds(0, 179.5, 60)
+	if lonMin <= lonMax {
+		t.Fatalf("expected wrapped longitude range, got %v..%v", lonMin, lonMax)
+	}
+	if lonMin < 178 || lonMax > -178 {
+		t.Errorf("unexpected bounds %v..%v", lonMin, lonMax)
+	}
+}
+
+func TestProjectionNearAntiMeridian(t *testing.T) {
+	r := &Renderer{width: 800, height: 600}
+
+	// An aircraft just across the dateline should be drawn just right of center
+	x, _ := r.latLonToScreen(0, -179.9, 0, 179.5, 60)
+	if x <= r.width/2 || x >= r.width {
+		t.Errorf("expected x just right of center, got %d", x)
+	}
+
+	x, _ = r.latLonToScreen(0, 179.0, 0, 179.5, 60)
+	if x >= r.width/2 || x < 0 {
+		t.Errorf("expected x just left of center, got %d", x)
+	}
+}
+
+func TestScaleBarDistance(t *testing.T) {
+	const height = 600
+
+	tests := []struct {
+		maxDistance float64
+		want        float64
+	}{
+		{0.5, 0.2},    // 600 px/nm
+		{5, 2},        // 60 px/nm
+		{10, 5},       // 30 px/nm
+		{25, 10},      // 12 px/nm
+		{50, 20},      // 6 px/nm
+		{150, 50},     // 2 px/nm
+		{300, 100},    // 1 px/nm
+		{1000, 500},   // 0.3 px/nm
+		{5000, 2000},  // 0.06 px/nm
+		{10000, 5000}, // 0.03 px/nm
+	}
+
+	for _, tt := range tests {
+		pixelsPerUnit := float64(height/2) / tt.maxDistance
+		got := scaleBarDistance(pixelsPerUnit, 100)
+		if got != tt.want {
+			t.Errorf("maxDistance %v: got %v, want %v", tt.maxDistance, got, tt.want)
+		}
+		if px := got * pixelsPerUnit; px < 100 || px > 250+1e-9 {
+			t.Errorf("maxDistance %v: bar is %v px, want 100-250", tt.maxDistance, px)
+		}
+	}
+}
+
+func TestAircraftAtNullIslandIsPositioned(t *testing.T) {
+	r := &Renderer{width: 800, height: 600, uiScale: 1}
+
+	a := &adsb.Aircraft{ICAO: 0xABCDEF, Lat: 0, Lon: 0, HasPosition: true}
+	aircraft := map[uint32]*adsb.Aircraft{a.ICAO: a}
+
+	r.calculateScreenPositions(aircraft, 0, 0, 50)
+	if a.X != r.width/2 || a.Y != r.height/2 {
+		t.Errorf("expected aircraft at screen center, got %d,%d", a.X, a.Y)
+	}
+	if got := countVisibleAircraft(aircraft); got != 1 {
+		t.Errorf("expected 1 visible aircraft, got %d", got)
+	}
+	if got := positionedAircraft(aircraft); len(got) != 1 {
+		t.Errorf("expected aircraft at 0,0 to be kept, got %d", len(got))
+	}
+
+	// Without a decoded position the aircraft is left out
+	a.HasPosition = false
+	if got := countVisibleAircraft(aircraft); got != 0 {
+		t.Errorf("expected 0 visible aircraft, got %d", got)
+	}
+}
+
+func TestSymbolHeading(t *testing.T) {
+	tests := []struct {
+		name string
+		a    *adsb.Aircraft
+		want int
+	}{
+		{"airborne", &adsb.Aircraft{Heading: 270, GroundTrack: 90, HasGroundTrack: true}, 270},
+		{"taxiing", &adsb.Aircraft{OnGround: true, Heading: 270, GroundTrack: 90, HasGroundTrack: true}, 90},
+		{"stationary", &adsb.Aircraft{OnGround: true, Heading: 270, GroundTrack: 90}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := symbolHeading(tt.a); got != tt.want {
+			t.Errorf("%s: symbolHeading = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayIndexFallsBackToFirst(t *testing.T) {
+	tests := []struct {
+		display, count, want int
+	}{
+		{0, 1, 0},
+		{1, 2, 1},
+		{2, 2, 0},
+		{-1, 3, 0},
+		{1, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := displayIndex(tt.display, tt.count); got != tt.want {
+			t.Errorf("displayIndex(%d, %d) = %d, want %d", tt.display, tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestDistanceLevel(t *testing.T) {
+	tests := []struct {
+		fullNM, hideNM, dist float64
+		want                 float64
+	}{
+		{40, 80, 0, 0},
+		{40, 80, 40, 0}, // Thresholds are exclusive
+		{40, 80, 40.1, 1},
+		{40, 80, 80, 1},
+		{40, 80, 80.1, 2},
+		{0, 80, 60, 0}, // Full labels out to the hide range
+		{0, 80, 90, 2},
+		{40, 0, 500, 1}, // Never hidden
+		{0, 0, 500, 0},
+	}
+
+	for _, tt := range tests {
+		ls := NewLabelSystem(800, 600, 1, false)
+		ls.SetDetailRange(tt.fullNM, tt.hideNM)
+		if got := ls.distanceLevel(tt.dist); got != tt.want {
+			t.Errorf("distanceLevel(%v) with full %v hide %v = %v, want %v",
+				tt.dist, tt.fullNM, tt.hideNM, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateDetailEasesToDistanceLevel(t *testing.T) {
+	ls := NewLabelSystem(800, 600, 1, false)
+	ls.SetDetailRange(40, 80)
+
+	// 100 NM to the top of the screen, so 3 pixels to the NM
+	tests := []struct {
+		icao uint32
+		x    int
+		want float64
+	}{
+		{0x000001, 400 + 30, 0},  // 10 NM
+		{0x000002, 400 + 150, 1}, // 50 NM
+		{0x000003, 400 + 300, 2}, // 100 NM
+		{0x000004, 400 + 330, 0}, // 110 NM but selected
+	}
+	aircraft := map[uint32]*adsb.Aircraft{}
+	for _, tt := range tests {
+		aircraft[tt.icao] = &adsb.Aircraft{ICAO: tt.icao, X: tt.x, Y: 300}
+	}
+	const selected = 0x000004
+
+	// Levels ease rather than jump
+	ls.updateDetail(aircraft, selected, 100)
+	if got := aircraft[0x000003].LabelLevel; got <= 0 || got >= 2 {
+		t.Errorf("level after one frame = %v, want between 0 and 2", got)
+	}
+
+	for i := 0; i < 50; i++ {
+		ls.updateDetail(aircraft, selected, 100)
+	}
+	for _, tt := range tests {
+		if got := aircraft[tt.icao].LabelLevel; got != tt.want {
+			t.Errorf("%06X at x=%d: level %v, want %v", tt.icao, tt.x, got, tt.want)
+		}
+	}
+
+	// A hidden label comes back in full once selected
+	for i := 0; i < 50; i++ {
+		ls.updateDetail(aircraft, 0x000003, 100)
+	}
+	if got := aircraft[0x000003].LabelLevel; got != 0 {
+		t.Errorf("selected far aircraft level %v, want 0", got)
+	}
+	if got := aircraft[0x000004].LabelLevel; got != 2 {
+		t.Errorf("deselected far aircraft level %v, want 2", got)
+	}
+}
@@ -0,0 +1,69 @@
+package viz
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+// signalFloorDBFS is the weakest signal level shown in the sparkline
+const signalFloorDBFS = -48.0
+
+// signalDBFS converts a Beast signal level byte, the RSSI amplitude scaled
+// to 255, to dBFS
+func signalDBFS(level byte) float64 {
+	if level == 0 {
+		return math.Inf(-1)
+	}
+	return 20 * math.Log10(float64(level)/255)
+}
+
+// formatDBFS formats a signal level for display, e.g. "-12.3dBFS"
+func formatDBFS(level byte) string {
+	if level == 0 {
+		return "-inf dBFS"
+	}
+	return fmt.Sprintf("%.1fdBFS", signalDBFS(level))
+}
+
+// signalHistory returns an aircraft's recent signal levels oldest first.
+// SignalLevel is a ring indexed by message count.
+func signalHistory(a *adsb.Aircraft) []byte {
+	n := len(a.SignalLevel)
+	if a.Messages < n {
+		return append([]byte(nil), a.SignalLevel[:a.Messages]...)
+	}
+
+	history := make([]byte, 0, n)
+	for i := 0; i < n; i++ {
+		history = append(history, a.SignalLevel[(a.Messages+i)%n])
+	}
+	return history
+}
+
+// drawSignalGraph draws a bar per recent signal level, newest rightmost,
+// ending at right and filling the text line at y
+func (r *Renderer) drawSignalGraph(a *adsb.Aircraft, right, y int) {
+	history := signalHistory(a)
+	barWidth := r.charWidth()
+	maxHeight := r.lineHeight() - 2*r.uiScale
+
+	x := right - len(history)*barWidth
+	for i, level := range history {
+		h := 1
+		if db := signalDBFS(level); db > signalFloorDBFS {
+			h = int(float64(maxHeight) * (1 - db/signalFloorDBFS))
+		}
+		if h < 1 {
+			h = 1
+		}
+
+		color := ColorText
+		if i == len(history)-1 {
+			color = ColorSelected
+		}
+		bottom := y + r.lineHeight() - r.uiScale
+		r.drawRect(int32(x+i*barWidth), int32(bottom-h), int32(barWidth-r.uiScale), int32(h), color)
+	}
+}
@@ -0,0 +1,40 @@
+package viz
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+func TestSignalDBFS(t *testing.T) {
+	if got := signalDBFS(255); got != 0 {
+		t.Errorf("full scale = %v dBFS, want 0", got)
+	}
+	if got := signalDBFS(128); math.Abs(got-(-5.98)) > 0.01 {
+		t.Errorf("half amplitude = %.2f dBFS, want -5.98", got)
+	}
+	if got := formatDBFS(0); got != "-inf dBFS" {
+		t.Errorf("formatDBFS(0) = %q", got)
+	}
+}
+
+func TestSignalHistoryOldestFirst(t *testing.T) {
+	a := &adsb.Aircraft{}
+	for level := byte(1); level <= 3; level++ {
+		a.SignalLevel[a.Messages%8] = level
+		a.Messages++
+	}
+	if got := signalHistory(a); !bytes.Equal(got, []byte{1, 2, 3}) {
+		t.Errorf("partial history = %v, want [1 2 3]", got)
+	}
+
+	for level := byte(4); level <= 11; level++ {
+		a.SignalLevel[a.Messages%8] = level
+		a.Messages++
+	}
+	if got := signalHistory(a); !bytes.Equal(got, []byte{4, 5, 6, 7, 8, 9, 10, 11}) {
+		t.Errorf("wrapped history = %v, want 4..11", got)
+	}
+}
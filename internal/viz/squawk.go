@@ -0,0 +1,39 @@
+package viz
+
+import (
+	"fmt"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+// squawkAlert returns the short name of an emergency squawk, empty for
+// any other code
+func squawkAlert(code int) string {
+	switch code {
+	case 7500:
+		return "HIJACK"
+	case 7600:
+		return "NORDO"
+	case 7700:
+		return "EMERG"
+	}
+	return ""
+}
+
+// squawkAlertOf returns the emergency squawk an aircraft should be
+// highlighted for, empty when highlighting is off or there is none
+func (r *Renderer) squawkAlertOf(a *adsb.Aircraft) string {
+	if !r.config.HighlightEmergencySquawk || !a.HasSquawk {
+		return ""
+	}
+	return squawkAlert(a.Squawk)
+}
+
+// squawkTag returns the squawk to show next to an aircraft's label, empty
+// when squawks are hidden or unknown
+func (r *Renderer) squawkTag(a *adsb.Aircraft) string {
+	if !r.config.ShowSquawk || !a.HasSquawk {
+		return ""
+	}
+	return fmt.Sprintf("%04d", a.Squawk)
+}
@@ -0,0 +1,65 @@
+package viz
+
+import (
+	"testing"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+	"github.com/OJPARKINSON/viz1090/internal/config"
+)
+
+func TestSquawkAlert(t *testing.T) {
+	tests := []struct {
+		code int
+		want string
+	}{
+		{7500, "HIJACK"},
+		{7600, "NORDO"},
+		{7700, "EMERG"},
+		{7000, ""},
+		{1200, ""},
+		{2341, ""},
+	}
+
+	for _, tt := range tests {
+		if got := squawkAlert(tt.code); got != tt.want {
+			t.Errorf("squawkAlert(%04d) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestSquawkTagAndAlert(t *testing.T) {
+	r := &Renderer{config: &config.Config{ShowSquawk: true, HighlightEmergencySquawk: true}}
+
+	tests := []struct {
+		squawk    int
+		hasSquawk bool
+		tag       string
+		alert     string
+	}{
+		{7000, true, "7000", ""},
+		{1200, true, "1200", ""},
+		{356, true, "0356", ""},
+		{7700, true, "7700", "EMERG"},
+		{7700, false, "", ""},
+	}
+
+	for _, tt := range tests {
+		a := &adsb.Aircraft{Squawk: tt.squawk, HasSquawk: tt.hasSquawk}
+		if got := r.squawkTag(a); got != tt.tag {
+			t.Errorf("squawkTag(%04d) = %q, want %q", tt.squawk, got, tt.tag)
+		}
+		if got := r.squawkAlertOf(a); got != tt.alert {
+			t.Errorf("squawkAlertOf(%04d) = %q, want %q", tt.squawk, got, tt.alert)
+		}
+	}
+
+	r.config.HighlightEmergencySquawk = false
+	if got := r.squawkAlertOf(&adsb.Aircraft{Squawk: 7600, HasSquawk: true}); got != "" {
+		t.Errorf("squawkAlertOf with highlighting off = %q, want empty", got)
+	}
+
+	r.config.ShowSquawk = false
+	if got := r.squawkTag(&adsb.Aircraft{Squawk: 7600, HasSquawk: true}); got != "" {
+		t.Errorf("squawkTag with squawks hidden = %q, want empty", got)
+	}
+}
@@ -0,0 +1,61 @@
+package viz
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// stackRadius is the distance in pixels, at UI scale 1, within which aircraft
+// symbols are drawn as a single stack
+const stackRadius = 4
+
+// markStacks groups aircraft whose symbols lie within radius pixels of each
+// other. Each group is drawn as its lead, the selected aircraft if it is in
+// the group or else the lowest address, with StackCount set to the number of
+// others; those are marked Stacked and not drawn.
+func markStacks(aircraft map[uint32]*adsb.Aircraft, selectedICAO uint32, radius int) {
+	order := make([]uint32, 0, len(aircraft))
+	for icao, a := range aircraft {
+		a.Stacked = false
+		a.StackCount = 0
+		if a.HasPosition {
+			order = append(order, icao)
+		}
+	}
+
+	// Visit the selected aircraft first so it always leads its stack
+	sort.Slice(order, func(i, j int) bool {
+		if (order[i] == selectedICAO) != (order[j] == selectedICAO) {
+			return order[i] == selectedICAO
+		}
+		return order[i] < order[j]
+	})
+
+	r2 := radius * radius
+	for i, icao := range order {
+		lead := aircraft[icao]
+		if lead.Stacked {
+			continue
+		}
+		for _, other := range order[i+1:] {
+			a := aircraft[other]
+			if a.Stacked {
+				continue
+			}
+			dx, dy := a.X-lead.X, a.Y-lead.Y
+			if dx*dx+dy*dy <= r2 {
+				a.Stacked = true
+				lead.StackCount++
+			}
+		}
+	}
+}
+
+// drawStackCount draws the "+N" count of aircraft hidden under a stack lead
+func (r *Renderer) drawStackCount(a *adsb.Aircraft, color sdl.Color) {
+	offset := int(10 * float64(r.uiScale) * r.symbolScale())
+	r.drawText(fmt.Sprintf("+%d", a.StackCount), a.X+offset, a.Y-offset-r.lineHeight()/2, r.regularFont, color)
+}
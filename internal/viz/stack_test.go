@@ -0,0 +1,38 @@
+package viz
+
+import (
+	"testing"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+func TestMarkStacks(t *testing.T) {
+	aircraft := map[uint32]*adsb.Aircraft{
+		0x000003: {HasPosition: true, X: 100, Y: 100},
+		0x000001: {HasPosition: true, X: 102, Y: 101},
+		0x000002: {HasPosition: true, X: 99, Y: 103},
+		0x000004: {HasPosition: true, X: 200, Y: 100}, // Alone
+		0x000005: {X: 100, Y: 100},                    // No position
+	}
+
+	markStacks(aircraft, 0, 4)
+	if aircraft[0x000001].StackCount != 2 || aircraft[0x000001].Stacked {
+		t.Errorf("lowest address should lead with +2, got %+d stacked=%v",
+			aircraft[0x000001].StackCount, aircraft[0x000001].Stacked)
+	}
+	if !aircraft[0x000002].Stacked || !aircraft[0x000003].Stacked {
+		t.Error("other stack members should be hidden")
+	}
+	if a := aircraft[0x000004]; a.Stacked || a.StackCount != 0 {
+		t.Error("lone aircraft should not be stacked")
+	}
+	if aircraft[0x000005].Stacked {
+		t.Error("aircraft without a position should not be stacked")
+	}
+
+	// The selected aircraft takes over as lead
+	markStacks(aircraft, 0x000003, 4)
+	if aircraft[0x000003].StackCount != 2 || aircraft[0x000003].Stacked || !aircraft[0x000001].Stacked {
+		t.Errorf("selected aircraft should lead its stack")
+	}
+}
@@ -0,0 +1,32 @@
+package viz
+
+import (
+	"github.com/OJPARKINSON/viz1090/internal/config"
+	"github.com/OJPARKINSON/viz1090/internal/eventlog"
+	"github.com/OJPARKINSON/viz1090/internal/watchlist"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// watchStyle is the parsed highlight for a watchlist entry
+type watchStyle struct {
+	color sdl.Color
+	label string
+}
+
+// newWatchlist parses the configured watchlist colors, falling back to
+// ColorWatch for entries without a usable color
+func newWatchlist(entries map[string]config.WatchEntry) *watchlist.List[watchStyle] {
+	styles := make(map[string]watchStyle, len(entries))
+	for key, entry := range entries {
+		style := watchStyle{color: ColorWatch, label: entry.Label}
+		if entry.Color != "" {
+			if color, err := parseHexColor(entry.Color); err != nil {
+				eventlog.Printf("Warning: Watchlist entry %s: %v\n", key, err)
+			} else {
+				style.color = color
+			}
+		}
+		styles[key] = style
+	}
+	return watchlist.New(styles)
+}
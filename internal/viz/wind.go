@@ -0,0 +1,61 @@
+package viz
+
+import (
+	"math"
+
+	"github.com/OJPARKINSON/viz1090/internal/adsb"
+)
+
+// windBarbs splits a wind speed in knots into the pennants (50kt), full
+// barbs (10kt) and half barbs (5kt) drawn on a wind barb
+func windBarbs(speed int) (pennants, full, half int) {
+	speed = (speed + 2) / 5 * 5 // Round to the nearest 5kt
+	pennants = speed / 50
+	full = speed % 50 / 10
+	half = speed % 10 / 5
+	return pennants, full, half
+}
+
+// drawWind draws a wind barb on each aircraft reporting wind, with the staff
+// pointing into the wind and the barbs at its far end
+func (r *Renderer) drawWind(aircraft map[uint32]*adsb.Aircraft) {
+	r.renderer.SetDrawColor(ColorWind.R, ColorWind.G, ColorWind.B, ColorWind.A)
+
+	staff := float64(20 * r.uiScale)
+	barb := float64(7 * r.uiScale)
+	spacing := float64(3 * r.uiScale)
+
+	for _, a := range aircraft {
+		if !a.HasPosition || !a.HasWind || r.outOfBounds(a.X, a.Y) {
+			continue
+		}
+
+		// Unit vectors along the staff (towards the wind) and across it
+		dir := a.WindDirection * math.Pi / 180.0
+		sx, sy := math.Sin(dir), -math.Cos(dir)
+		bx, by := math.Sin(dir+math.Pi/3), -math.Cos(dir+math.Pi/3)
+
+		x0, y0 := float64(a.X), float64(a.Y)
+		r.renderer.DrawLine(int32(x0), int32(y0), int32(x0+sx*staff), int32(y0+sy*staff))
+
+		// Barbs start at the tip and work back towards the aircraft
+		pos := staff
+		pennants, full, half := windBarbs(a.WindSpeed)
+		for i := 0; i < pennants; i++ {
+			tx, ty := x0+sx*pos, y0+sy*pos
+			ex, ey := tx+bx*barb, ty+by*barb
+			r.renderer.DrawLine(int32(tx), int32(ty), int32(ex), int32(ey))
+			r.renderer.DrawLine(int32(ex), int32(ey), int32(x0+sx*(pos-2*spacing)), int32(y0+sy*(pos-2*spacing)))
+			pos -= 2 * spacing
+		}
+		for i := 0; i < full; i++ {
+			tx, ty := x0+sx*pos, y0+sy*pos
+			r.renderer.DrawLine(int32(tx), int32(ty), int32(tx+bx*barb), int32(ty+by*barb))
+			pos -= spacing
+		}
+		if half > 0 {
+			tx, ty := x0+sx*pos, y0+sy*pos
+			r.renderer.DrawLine(int32(tx), int32(ty), int32(tx+bx*barb/2), int32(ty+by*barb/2))
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package watchlist
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/OJPARKINSON/viz1090/internal/config"
+)
+
+// List matches aircraft against watched ICAO addresses and callsign prefixes
+type List[T any] struct {
+	icao     map[uint32]T
+	prefixes []prefix[T] // Longest first so the most specific prefix wins
+}
+
+type prefix[T any] struct {
+	text  string
+	value T
+}
+
+// New builds a list from keys that are ICAO hex addresses or callsign
+// prefixes. Keys that are six hex digits match both ways.
+func New[T any](entries map[string]T) *List[T] {
+	l := &List[T]{icao: make(map[uint32]T)}
+	for key, value := range entries {
+		key = strings.ToUpper(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+		if len(key) == 6 {
+			if icao, err := strconv.ParseUint(key, 16, 32); err == nil {
+				l.icao[uint32(icao)] = value
+			}
+		}
+		l.prefixes = append(l.prefixes, prefix[T]{key, value})
+	}
+
+	sort.Slice(l.prefixes, func(i, j int) bool {
+		if len(l.prefixes[i].text) != len(l.prefixes[j].text) {
+			return len(l.prefixes[i].text) > len(l.prefixes[j].text)
+		}
+		return l.prefixes[i].text < l.prefixes[j].text
+	})
+	return l
+}
+
+// Len returns the number of watched keys
+func (l *List[T]) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.prefixes)
+}
+
+// Match returns the entry for an aircraft, preferring an ICAO address match
+// over a callsign prefix. A nil list matches nothing.
+func (l *List[T]) Match(icao uint32, flight string) (T, bool) {
+	var zero T
+	if l == nil {
+		return zero, false
+	}
+	if value, ok := l.icao[icao]; ok {
+		return value, true
+	}
+	if flight != "" {
+		flight = strings.ToUpper(flight)
+		for _, p := range l.prefixes {
+			if strings.HasPrefix(flight, p.text) {
+				return p.value, true
+			}
+		}
+	}
+	return zero, false
+}
+
+// Load reads watchlist entries from a file with one "KEY [#rrggbb] [label]"
+// entry per line, where KEY is an ICAO hex address or callsign prefix. Blank
+// lines and lines starting with # are ignored.
+func Load(path string) (map[string]config.WatchEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open watchlist: %v", err)
+	}
+	defer file.Close()
+
+	entries := make(map[string]config.WatchEntry)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		var entry config.WatchEntry
+		rest := fields[1:]
+		if len(rest) > 0 && strings.HasPrefix(rest[0], "#") {
+			entry.Color = rest[0]
+			rest = rest[1:]
+		}
+		entry.Label = strings.Join(rest, " ")
+		entries[strings.ToUpper(fields[0])] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read watchlist: %v", err)
+	}
+	return entries, nil
+}
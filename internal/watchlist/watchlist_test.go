@@ -0,0 +1,70 @@
+package watchlist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OJPARKINSON/viz1090/internal/config"
+)
+
+func TestMatch(t *testing.T) {
+	l := New(map[string]string{
+		"4CA87C": "icao",
+		"BAW":    "airline",
+		"BAW1":   "flight",
+		"ABC":    "short hex", // Not six digits, callsign prefix only
+	})
+
+	tests := []struct {
+		icao   uint32
+		flight string
+		want   string
+		ok     bool
+	}{
+		{0x4CA87C, "", "icao", true},
+		{0x4CA87C, "BAW123", "icao", true}, // Address beats callsign
+		{0x400000, "BAW123", "flight", true},
+		{0x400000, "baw9", "airline", true},
+		{0x000ABC, "", "", false},
+		{0x400000, "EZY12", "", false},
+		{0x400000, "", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := l.Match(tt.icao, tt.flight)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Match(%06X, %q) = %q, %v; want %q, %v", tt.icao, tt.flight, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.txt")
+	text := "# spotting list\n\n4ca87c #ff00ff Aer Lingus special\nBAW\nN12345 Bizjet\n"
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]config.WatchEntry{
+		"4CA87C": {Color: "#ff00ff", Label: "Aer Lingus special"},
+		"BAW":    {},
+		"N12345": {Label: "Bizjet"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("loaded %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for key, entry := range want {
+		if entries[key] != entry {
+			t.Errorf("%s = %+v, want %+v", key, entries[key], entry)
+		}
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("missing file loaded")
+	}
+}